@@ -1,32 +1,149 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
 	"github.com/genryusaishigikuni/spy_cats/config"
 	"github.com/genryusaishigikuni/spy_cats/pkg/database"
+	"github.com/genryusaishigikuni/spy_cats/pkg/logging"
 	"github.com/genryusaishigikuni/spy_cats/pkg/router"
-	"log"
+	"github.com/genryusaishigikuni/spy_cats/pkg/scheduler"
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
 )
 
 func main() {
-	// Load configuration
-	cfg := config.Load()
+	// Load configuration: defaults, config file, environment, then flags.
+	// Its errors are meant for a person starting the service, so they stay plain text.
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cfg.PrintConfig {
+		fmt.Print(cfg.Redacted())
+		return
+	}
+	if cfg.Log.Level != "debug" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	logging.Setup(cfg.Log)
+
+	// Tracing: spans are exported as configured and flushed on shutdown
+	shutdownTracing, err := tracing.Setup(cfg.Tracing)
+	if err != nil {
+		fatal("tracing setup failed", err)
+	}
 
 	// Connect to database
-	db, err := database.Connect(cfg.DB)
+	db, err := database.Connect(cfg.DB, cfg.Log)
 	if err != nil {
-		log.Fatalf("Cannot connect DB: %v", err)
+		fatal("cannot connect to the database", err)
 	}
 
 	// Run database migrations
 	if err := database.RunMigrations(db); err != nil {
-		log.Fatalf("Migration failed: %v", err)
+		fatal("migration failed", err)
+	}
+
+	// Setup router; it also registers the background jobs
+	sched := scheduler.New(db, cfg.Scheduler.Interval)
+	r, err := router.SetupRouter(db, cfg, sched)
+	if err != nil {
+		fatal("router setup failed", err)
 	}
 
-	// Setup router
-	r := router.SetupRouter(db)
+	// SIGINT and SIGTERM start a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Start background scheduler
+	schedulerDone := make(chan struct{})
+	go func() {
+		defer close(schedulerDone)
+		sched.Run(ctx)
+	}()
 
 	// Start server
-	if err := r.Run(cfg.ServerPort); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
+	srv := &http.Server{
+		Addr:              cfg.ServerPort,
+		Handler:           r,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("listening", "address", cfg.ServerPort)
+		if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	exitCode := 0
+	select {
+	case <-ctx.Done():
+		slog.Info("shutting down, draining requests", "timeout", cfg.HTTP.ShutdownTimeout)
+	case err := <-serverErr:
+		slog.Error("server failed", "error", err)
+		exitCode = 1
+	}
+	stop()
+
+	if err := shutdown(srv, schedulerDone, db, shutdownTracing, cfg.HTTP.ShutdownTimeout); err != nil {
+		slog.Error("shutdown incomplete", "error", err)
+		exitCode = 1
+	}
+	os.Exit(exitCode)
+}
+
+// fatal logs err and exits.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// shutdown stops accepting requests and waits for in-flight ones, then for the
+// scheduler's running job, closes the database and finally flushes buffered
+// spans. The whole drain is bounded by timeout; connections still open after it
+// are cut.
+func shutdown(srv *http.Server, schedulerDone <-chan struct{}, db *gorm.DB, shutdownTracing func(context.Context) error, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errs []error
+	if err := srv.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("http server: %w", err))
+		_ = srv.Close()
+	}
+
+	select {
+	case <-schedulerDone:
+	case <-ctx.Done():
+		errs = append(errs, errors.New("scheduler: a job was still running"))
+	}
+
+	if err := database.Close(db); err != nil {
+		errs = append(errs, fmt.Errorf("database: %w", err))
+	}
+
+	// Spans of the drained requests are still buffered; give them their own
+	// deadline in case draining used up the timeout.
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFlush()
+	if err := shutdownTracing(flushCtx); err != nil {
+		errs = append(errs, fmt.Errorf("tracing: %w", err))
 	}
+	return errors.Join(errs...)
 }
@@ -1,12 +1,156 @@
 package config
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"os"
+	"sort"
+	"strings"
+	"time"
 )
 
 type Config struct {
 	DB         DBConfig
 	ServerPort string
+	HTTP       HTTPConfig
+	// AuthTokens is a comma-separated list of "token:name:role" entries.
+	AuthTokens  string
+	Scheduler   SchedulerConfig
+	CatAPI      CatAPIConfig
+	Payroll     PayrollConfig
+	Idempotency IdempotencyConfig
+	Rules       RulesConfig
+	Log         LogConfig
+	Health      HealthConfig
+	Tracing     TracingConfig
+	API         APIConfig
+	RateLimit   RateLimitConfig
+	Notes       NotesConfig
+	GraphQL     GraphQLConfig
+
+	// PrintConfig is set by --print-config: print the effective configuration and exit.
+	PrintConfig bool
+
+	// sources records where each setting's value came from, by key.
+	sources map[string]string
+}
+
+type HTTPConfig struct {
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	// ShutdownTimeout bounds how long in-flight requests may drain on shutdown.
+	ShutdownTimeout time.Duration
+	// MaxBodyBytes caps request bodies, except bulk imports, capped at MaxImportBytes.
+	MaxBodyBytes   int
+	MaxImportBytes int
+}
+
+type APIConfig struct {
+	// UnversionedRoutes is "alias" (served as /v1 with deprecation headers),
+	// "redirect" (308 to /v1) or "off".
+	UnversionedRoutes string
+	// UnversionedSunset is when unversioned paths will stop being served.
+	UnversionedSunset time.Time
+}
+
+type RateLimitConfig struct {
+	// Store is "memory", "postgres" (shared by replicas) or "none".
+	Store string
+	// PerMinute and Burst are the budget of each client across the routes
+	// without a budget of their own.
+	PerMinute int
+	Burst     int
+	// Routes is a comma-separated list of "METHOD /route=per_minute:burst" entries.
+	Routes string
+}
+
+type NotesConfig struct {
+	// MaxLength is the maximum length of a note, in characters.
+	MaxLength int
+}
+
+type GraphQLConfig struct {
+	// MaxComplexity bounds the estimated cost of a query: a field costs 1, and
+	// the fields below a list count ten times over.
+	MaxComplexity int
+	// MaxDepth bounds how deeply selections may nest.
+	MaxDepth int
+}
+
+type TracingConfig struct {
+	// Exporter is "none", "otlp", "stdout" or "file".
+	Exporter string
+	// OTLPEndpoint is the full OTLP/HTTP traces URL; empty uses the OTEL_EXPORTER_OTLP_* variables.
+	OTLPEndpoint string
+	// File receives one JSON span per line with the "file" exporter.
+	File string
+	// SampleRatio is the fraction of new traces that are recorded.
+	SampleRatio float64
+}
+
+type HealthConfig struct {
+	// CheckTimeout bounds each database readiness check; TheCatAPI checks use CatAPI.Timeout.
+	CheckTimeout time.Duration
+}
+
+type LogConfig struct {
+	// Level is "debug", "info", "warn" or "error".
+	Level string
+	// Format is "json" or "text".
+	Format string
+	// SQLSampleRatio is the fraction of successful SQL statements logged;
+	// failed and slow ones are always logged.
+	SQLSampleRatio float64
+	// SQLSlowThreshold is the duration above which a statement counts as slow.
+	SQLSlowThreshold time.Duration
+}
+
+type RulesConfig struct {
+	// File is a YAML or JSON rules file; empty uses the built-in rules.
+	File string
+	// Agency selects the file's per-agency overrides.
+	Agency string
+}
+
+type IdempotencyConfig struct {
+	// TTL is how long responses are kept for replay to retried POST requests.
+	TTL time.Duration
+}
+
+type PayrollConfig struct {
+	// MissionBonus is paid per completed mission, multiplied by its difficulty.
+	MissionBonus float64
+}
+
+type CatAPIConfig struct {
+	BaseURL string
+	// BreedsURL defaults to BaseURL + "/breeds".
+	BreedsURL string
+	APIKey    string
+	// Timeout bounds each attempt; failed calls are retried MaxRetries times,
+	// waiting up to RetryBackoff, doubled per retry, in between.
+	Timeout      time.Duration
+	MaxRetries   int
+	RetryBackoff time.Duration
+	// The circuit opens after BreakerThreshold consecutive failed calls and
+	// stays open for BreakerCooldown.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	// BreedCacheTTL controls how long the breed catalog is cached.
+	BreedCacheTTL time.Duration
+	// StaleBreedsMaxAge is how long past its TTL the last known catalog is still
+	// used while TheCatAPI is unavailable; 0 disables this degraded mode. The
+	// catalog is held in memory, so a restart loses it.
+	StaleBreedsMaxAge time.Duration
+}
+
+type SchedulerConfig struct {
+	Interval time.Duration
+	// OverdueEscalation is "notify" or "suspend".
+	OverdueEscalation string
 }
 
 type DBConfig struct {
@@ -15,48 +159,120 @@ type DBConfig struct {
 	User     string
 	Password string
 	Name     string
+
+	// Connection pool; zero MaxOpenConns means unlimited. One is rejected: the
+	// scheduler keeps a connection for its lock while its jobs use others.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
 }
 
-func Load() *Config {
-	// Gathers environment variables
-	dbHost := os.Getenv("DB_HOST")
-	if dbHost == "" {
-		dbHost = "localhost"
+// Load builds the configuration from, in increasing order of precedence:
+// built-in defaults, a YAML/TOML/JSON file (--config or CONFIG_FILE),
+// environment variables and command-line flags. Any variable can instead be read
+// from a file named by its _FILE variant (e.g. DB_PASSWORD_FILE), as Docker
+// secrets are. All invalid settings are reported together.
+func Load(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("spy_cats", flag.ContinueOnError)
+	configFile := fs.String("config", "", "configuration file (.yaml, .yml, .toml or .json)")
+	printConfig := fs.Bool("print-config", false, "print the effective configuration, secrets redacted, and exit")
+	flagValues := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		flagValues[s.key] = fs.String(s.flag(), "", s.usage+" ("+s.env+")")
 	}
-
-	dbPort := os.Getenv("DB_PORT")
-	if dbPort == "" {
-		dbPort = "5431"
+	if err := fs.Parse(args); err != nil {
+		return nil, err
 	}
+	flagsSet := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { flagsSet[f.Name] = true })
+
+	var errs []error
 
-	dbUser := os.Getenv("DB_USER")
-	if dbUser == "" {
-		dbUser = "postgres"
+	path := *configFile
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	fileValues := map[string]string{}
+	if path != "" {
+		var err error
+		if fileValues, err = readFile(path); err != nil {
+			return nil, err
+		}
 	}
 
-	dbPassword := os.Getenv("DB_PASSWORD")
-	if dbPassword == "" {
-		dbPassword = "dbpassword"
+	cfg := &Config{PrintConfig: *printConfig, sources: make(map[string]string, len(settings))}
+	unparsed := map[string]bool{}
+	for _, s := range settings {
+		value, source := s.def, "default"
+		if v, ok := fileValues[s.key]; ok {
+			value, source = v, "file"
+			delete(fileValues, s.key)
+		}
+		if v, ok, err := lookupEnv(s.env); err != nil {
+			errs = append(errs, err)
+		} else if ok {
+			value, source = v, "env"
+		}
+		if flagsSet[s.flag()] {
+			value, source = *flagValues[s.key], "flag"
+		}
+
+		if err := s.set(cfg, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s (from %s): %w", s.key, source, err))
+			unparsed[s.key] = true
+		}
+		cfg.sources[s.key] = source
+	}
+	for key := range fileValues {
+		errs = append(errs, fmt.Errorf("%s: unknown setting in %s", key, path))
 	}
 
-	dbName := os.Getenv("DB_NAME")
-	if dbName == "" {
-		dbName = "spy_cats_db"
+	if cfg.CatAPI.BreedsURL == "" {
+		cfg.CatAPI.BreedsURL = strings.TrimRight(cfg.CatAPI.BaseURL, "/") + "/breeds"
 	}
+	// A setting that could not be parsed holds its zero value; do not report that too.
+	for _, err := range cfg.validate() {
+		key, _, _ := strings.Cut(err.Error(), ":")
+		if !unparsed[key] {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return cfg, nil
+}
 
-	serverPort := os.Getenv("SERVER_PORT")
-	if serverPort == "" {
-		serverPort = ":8080"
+// lookupEnv reads an environment variable or the file named by its _FILE
+// variant. Empty variables count as unset.
+func lookupEnv(name string) (string, bool, error) {
+	value := os.Getenv(name)
+	file := os.Getenv(name + "_FILE")
+	switch {
+	case value != "" && file != "":
+		return "", false, fmt.Errorf("%s and %s_FILE are both set", name, name)
+	case file != "":
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", false, fmt.Errorf("%s_FILE: %w", name, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), true, nil
 	}
+	return value, value != "", nil
+}
 
-	return &Config{
-		DB: DBConfig{
-			Host:     dbHost,
-			Port:     dbPort,
-			User:     dbUser,
-			Password: dbPassword,
-			Name:     dbName,
-		},
-		ServerPort: serverPort,
+// Redacted renders the effective configuration one setting per line, with the
+// source of each value. Secrets are masked.
+func (c *Config) Redacted() string {
+	var b strings.Builder
+	for _, s := range settings {
+		value := s.get(c)
+		if s.secret && value != "" {
+			value = "[redacted]"
+		}
+		fmt.Fprintf(&b, "%s = %s (%s)\n", s.key, value, c.sources[s.key])
 	}
+	return b.String()
 }
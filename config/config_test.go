@@ -0,0 +1,160 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// cleanEnv unsets every setting's variables, so that the tests see only what
+// they set, and gives the required database password.
+func cleanEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("CONFIG_FILE", "")
+	for _, s := range settings {
+		t.Setenv(s.env, "")
+		t.Setenv(s.env+"_FILE", "")
+	}
+	t.Setenv("DB_PASSWORD", "secret")
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cleanEnv(t)
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.DB.Host != "localhost" || cfg.DB.MaxOpenConns != 25 || cfg.ServerPort != ":8080" {
+		t.Errorf("db.host, db.max_open_conns, server.port = %q, %d, %q, want the defaults",
+			cfg.DB.Host, cfg.DB.MaxOpenConns, cfg.ServerPort)
+	}
+	if !strings.Contains(cfg.Redacted(), "db.host = localhost (default)\n") {
+		t.Errorf("Redacted does not report db.host as a default:\n%s", cfg.Redacted())
+	}
+}
+
+func TestLoadLayering(t *testing.T) {
+	cleanEnv(t)
+	path := writeFile(t, "config.yaml", `
+db:
+  host: file-host
+  port: 6000
+  name: file-db
+  user: file-user
+`)
+	t.Setenv("DB_PORT", "7000")
+	t.Setenv("DB_NAME", "env-db")
+
+	cfg, err := Load([]string{"--config", path, "--db-name", "flag-db"})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	for _, tc := range []struct {
+		key, got, want, source string
+	}{
+		{"db.host", cfg.DB.Host, "file-host", "file"},
+		{"db.user", cfg.DB.User, "file-user", "file"},
+		{"db.port", cfg.DB.Port, "7000", "env"},
+		{"db.name", cfg.DB.Name, "flag-db", "flag"},
+		{"server.port", cfg.ServerPort, ":8080", "default"},
+	} {
+		if tc.got != tc.want || cfg.sources[tc.key] != tc.source {
+			t.Errorf("%s = %q from %s, want %q from %s", tc.key, tc.got, cfg.sources[tc.key], tc.want, tc.source)
+		}
+	}
+}
+
+func TestLoadConfigFileVariable(t *testing.T) {
+	cleanEnv(t)
+	t.Setenv("CONFIG_FILE", writeFile(t, "config.toml", "[db]\nhost = \"env-file-host\"\n"))
+
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.DB.Host != "env-file-host" {
+		t.Errorf("db.host = %q, want env-file-host", cfg.DB.Host)
+	}
+
+	cfg, err = Load([]string{"--config", writeFile(t, "config.json", `{"db": {"host": "flag-file-host"}}`)})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.DB.Host != "flag-file-host" {
+		t.Errorf("db.host = %q, want the --config file's flag-file-host", cfg.DB.Host)
+	}
+}
+
+func TestLoadFileVariants(t *testing.T) {
+	cleanEnv(t)
+	t.Setenv("DB_PASSWORD", "")
+	t.Setenv("DB_PASSWORD_FILE", writeFile(t, "password", "from-secret\n"))
+	path := writeFile(t, "config.yaml", "db:\n  password: from-config\n")
+
+	cfg, err := Load([]string{"--config", path})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.DB.Password != "from-secret" || cfg.sources["db.password"] != "env" {
+		t.Errorf("db.password = %q from %s, want from-secret from env", cfg.DB.Password, cfg.sources["db.password"])
+	}
+	if strings.Contains(cfg.Redacted(), "from-secret") {
+		t.Error("Redacted shows the password")
+	}
+
+	cfg, err = Load([]string{"--config", path, "--db-password", "from-flag"})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.DB.Password != "from-flag" {
+		t.Errorf("db.password = %q, want the flag's from-flag", cfg.DB.Password)
+	}
+}
+
+func TestLoadRejectsVariableAndFileVariant(t *testing.T) {
+	cleanEnv(t)
+	t.Setenv("DB_PASSWORD_FILE", writeFile(t, "password", "from-secret"))
+
+	_, err := Load(nil)
+	if err == nil || !strings.Contains(err.Error(), "DB_PASSWORD and DB_PASSWORD_FILE are both set") {
+		t.Errorf("Load error = %v, want both variables reported", err)
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	cleanEnv(t)
+	t.Setenv("DB_PORT", "not-a-port")
+	t.Setenv("DB_MAX_OPEN_CONNS", "1")
+	t.Setenv("LOG_LEVEL", "loud")
+	t.Setenv("SCHEDULER_INTERVAL", "soon")
+	path := writeFile(t, "config.yaml", "db:\n  hostname: typo\n")
+
+	_, err := Load([]string{"--config", path})
+	if err == nil {
+		t.Fatal("Load succeeded")
+	}
+	for _, want := range []string{
+		`db.port: "not-a-port" is not a port number`,
+		"db.max_open_conns: must be at least 2",
+		"log.level: must be",
+		"scheduler.interval (from env)",
+		"db.hostname: unknown setting",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Load error does not mention %q:\n%v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "scheduler.interval: must be positive") {
+		t.Errorf("Load error reports the unparsed scheduler.interval twice:\n%v", err)
+	}
+}
@@ -0,0 +1,200 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting binds one configuration value to its key in configuration files, its
+// environment variable and its command-line flag.
+type setting struct {
+	key    string // dotted path in files; the flag is the same with dashes
+	env    string
+	def    string
+	secret bool
+	usage  string
+	field  func(c *Config) interface{} // pointer to the Config field
+}
+
+var settings = []setting{
+	{key: "server.port", env: "SERVER_PORT", def: ":8080", usage: "address the API listens on",
+		field: func(c *Config) interface{} { return &c.ServerPort }},
+	{key: "server.read_timeout", env: "SERVER_READ_TIMEOUT", def: "15s", usage: "maximum time to read a request",
+		field: func(c *Config) interface{} { return &c.HTTP.ReadTimeout }},
+	{key: "server.read_header_timeout", env: "SERVER_READ_HEADER_TIMEOUT", def: "5s", usage: "maximum time to read request headers",
+		field: func(c *Config) interface{} { return &c.HTTP.ReadHeaderTimeout }},
+	{key: "server.write_timeout", env: "SERVER_WRITE_TIMEOUT", def: "30s", usage: "maximum time to write a response",
+		field: func(c *Config) interface{} { return &c.HTTP.WriteTimeout }},
+	{key: "server.idle_timeout", env: "SERVER_IDLE_TIMEOUT", def: "60s", usage: "how long idle keep-alive connections stay open",
+		field: func(c *Config) interface{} { return &c.HTTP.IdleTimeout }},
+	{key: "server.shutdown_timeout", env: "SERVER_SHUTDOWN_TIMEOUT", def: "30s", usage: "how long in-flight requests may drain on shutdown",
+		field: func(c *Config) interface{} { return &c.HTTP.ShutdownTimeout }},
+	{key: "server.max_body_bytes", env: "SERVER_MAX_BODY_BYTES", def: "1048576", usage: "maximum size of a request body",
+		field: func(c *Config) interface{} { return &c.HTTP.MaxBodyBytes }},
+	{key: "server.max_import_bytes", env: "SERVER_MAX_IMPORT_BYTES", def: "33554432", usage: "maximum size of a bulk import body",
+		field: func(c *Config) interface{} { return &c.HTTP.MaxImportBytes }},
+
+	{key: "db.host", env: "DB_HOST", def: "localhost", usage: "database host",
+		field: func(c *Config) interface{} { return &c.DB.Host }},
+	{key: "db.port", env: "DB_PORT", def: "5431", usage: "database port",
+		field: func(c *Config) interface{} { return &c.DB.Port }},
+	{key: "db.user", env: "DB_USER", def: "postgres", usage: "database user",
+		field: func(c *Config) interface{} { return &c.DB.User }},
+	{key: "db.password", env: "DB_PASSWORD", secret: true, usage: "database password",
+		field: func(c *Config) interface{} { return &c.DB.Password }},
+	{key: "db.name", env: "DB_NAME", def: "spy_cats_db", usage: "database name",
+		field: func(c *Config) interface{} { return &c.DB.Name }},
+	{key: "db.max_open_conns", env: "DB_MAX_OPEN_CONNS", def: "25", usage: "maximum open connections, at least 2, 0 for unlimited",
+		field: func(c *Config) interface{} { return &c.DB.MaxOpenConns }},
+	{key: "db.max_idle_conns", env: "DB_MAX_IDLE_CONNS", def: "5", usage: "maximum idle connections",
+		field: func(c *Config) interface{} { return &c.DB.MaxIdleConns }},
+	{key: "db.conn_max_lifetime", env: "DB_CONN_MAX_LIFETIME", def: "30m", usage: "maximum age of a connection, 0 for no limit",
+		field: func(c *Config) interface{} { return &c.DB.ConnMaxLifetime }},
+	{key: "db.conn_max_idle_time", env: "DB_CONN_MAX_IDLE_TIME", def: "5m", usage: "maximum idle time of a connection, 0 for no limit",
+		field: func(c *Config) interface{} { return &c.DB.ConnMaxIdleTime }},
+
+	{key: "auth.tokens", env: "AUTH_TOKENS", secret: true, usage: "comma-separated token:name:role entries",
+		field: func(c *Config) interface{} { return &c.AuthTokens }},
+
+	{key: "scheduler.interval", env: "SCHEDULER_INTERVAL", def: "1m", usage: "how often background jobs run",
+		field: func(c *Config) interface{} { return &c.Scheduler.Interval }},
+	{key: "scheduler.overdue_escalation", env: "OVERDUE_ESCALATION", def: "notify", usage: `"notify" or "suspend" overdue missions`,
+		field: func(c *Config) interface{} { return &c.Scheduler.OverdueEscalation }},
+
+	{key: "catapi.base_url", env: "THECATAPI_URL", def: "https://api.thecatapi.com/v1", usage: "TheCatAPI base URL",
+		field: func(c *Config) interface{} { return &c.CatAPI.BaseURL }},
+	{key: "catapi.breeds_url", env: "THECATAPI_BREEDS_URL", usage: "breed catalog endpoint, defaults to the base URL's /breeds",
+		field: func(c *Config) interface{} { return &c.CatAPI.BreedsURL }},
+	{key: "catapi.key", env: "THECATAPI_KEY", secret: true, usage: "TheCatAPI key",
+		field: func(c *Config) interface{} { return &c.CatAPI.APIKey }},
+	{key: "catapi.timeout", env: "THECATAPI_TIMEOUT", def: "5s", usage: "timeout of each call to TheCatAPI",
+		field: func(c *Config) interface{} { return &c.CatAPI.Timeout }},
+	{key: "catapi.max_retries", env: "THECATAPI_MAX_RETRIES", def: "2", usage: "retries of calls to TheCatAPI that failed transiently",
+		field: func(c *Config) interface{} { return &c.CatAPI.MaxRetries }},
+	{key: "catapi.retry_backoff", env: "THECATAPI_RETRY_BACKOFF", def: "200ms", usage: "maximum wait before the first retry, doubled for each next one",
+		field: func(c *Config) interface{} { return &c.CatAPI.RetryBackoff }},
+	{key: "catapi.breaker_threshold", env: "THECATAPI_BREAKER_THRESHOLD", def: "5", usage: "consecutive failed calls that open the circuit",
+		field: func(c *Config) interface{} { return &c.CatAPI.BreakerThreshold }},
+	{key: "catapi.breaker_cooldown", env: "THECATAPI_BREAKER_COOLDOWN", def: "30s", usage: "how long the circuit stays open",
+		field: func(c *Config) interface{} { return &c.CatAPI.BreakerCooldown }},
+	{key: "catapi.breed_cache_ttl", env: "BREED_CACHE_TTL", def: "1h", usage: "how long the breed catalog is cached",
+		field: func(c *Config) interface{} { return &c.CatAPI.BreedCacheTTL }},
+	{key: "catapi.stale_breeds_max_age", env: "STALE_BREEDS_MAX_AGE", def: "24h", usage: "how long an expired breed catalog is used while TheCatAPI is down, 0 to disable; it is kept in memory only, so not across restarts",
+		field: func(c *Config) interface{} { return &c.CatAPI.StaleBreedsMaxAge }},
+
+	{key: "api.unversioned_routes", env: "API_UNVERSIONED_ROUTES", def: "alias", usage: "alias, redirect or off: how paths without /v1 are served",
+		field: func(c *Config) interface{} { return &c.API.UnversionedRoutes }},
+	{key: "api.unversioned_sunset", env: "API_UNVERSIONED_SUNSET", def: "2027-04-30", usage: "date announced in the Sunset header of unversioned paths",
+		field: func(c *Config) interface{} { return &c.API.UnversionedSunset }},
+
+	{key: "ratelimit.store", env: "RATE_LIMIT_STORE", def: "memory", usage: "memory, postgres (shared by replicas) or none",
+		field: func(c *Config) interface{} { return &c.RateLimit.Store }},
+	{key: "ratelimit.per_minute", env: "RATE_LIMIT_PER_MINUTE", def: "300", usage: "requests a minute per client on routes without their own budget",
+		field: func(c *Config) interface{} { return &c.RateLimit.PerMinute }},
+	{key: "ratelimit.burst", env: "RATE_LIMIT_BURST", def: "60", usage: "requests a client may make at once on routes without their own budget",
+		field: func(c *Config) interface{} { return &c.RateLimit.Burst }},
+	{key: "ratelimit.routes", env: "RATE_LIMIT_ROUTES", def: "POST /missions=30:10,POST /targets/:id/notes=60:20,POST /import/cats=6:2",
+		usage: "comma-separated METHOD /route=per_minute:burst budgets",
+		field: func(c *Config) interface{} { return &c.RateLimit.Routes }},
+	{key: "notes.max_length", env: "NOTE_MAX_LENGTH", def: "10000", usage: "maximum length of a note, in characters",
+		field: func(c *Config) interface{} { return &c.Notes.MaxLength }},
+	{key: "graphql.max_complexity", env: "GRAPHQL_MAX_COMPLEXITY", def: "1000", usage: "maximum estimated cost of a GraphQL query",
+		field: func(c *Config) interface{} { return &c.GraphQL.MaxComplexity }},
+	{key: "graphql.max_depth", env: "GRAPHQL_MAX_DEPTH", def: "8", usage: "maximum nesting depth of a GraphQL query",
+		field: func(c *Config) interface{} { return &c.GraphQL.MaxDepth }},
+
+	{key: "payroll.mission_bonus", env: "PAYROLL_MISSION_BONUS", def: "500", usage: "bonus per completed mission, times its difficulty",
+		field: func(c *Config) interface{} { return &c.Payroll.MissionBonus }},
+	{key: "idempotency.ttl", env: "IDEMPOTENCY_TTL", def: "24h", usage: "how long POST responses are replayed",
+		field: func(c *Config) interface{} { return &c.Idempotency.TTL }},
+	{key: "rules.file", env: "RULES_FILE", usage: "business rules file",
+		field: func(c *Config) interface{} { return &c.Rules.File }},
+	{key: "rules.agency", env: "AGENCY", usage: "agency whose rule overrides apply",
+		field: func(c *Config) interface{} { return &c.Rules.Agency }},
+	{key: "health.check_timeout", env: "HEALTH_CHECK_TIMEOUT", def: "2s", usage: "timeout of each database readiness check",
+		field: func(c *Config) interface{} { return &c.Health.CheckTimeout }},
+	{key: "tracing.exporter", env: "TRACING_EXPORTER", def: "none", usage: "none, otlp, stdout or file",
+		field: func(c *Config) interface{} { return &c.Tracing.Exporter }},
+	{key: "tracing.otlp_endpoint", env: "TRACING_OTLP_ENDPOINT", usage: "OTLP/HTTP traces URL, such as http://collector:4318/v1/traces",
+		field: func(c *Config) interface{} { return &c.Tracing.OTLPEndpoint }},
+	{key: "tracing.file", env: "TRACING_FILE", def: "traces.jsonl", usage: "file the file exporter appends spans to",
+		field: func(c *Config) interface{} { return &c.Tracing.File }},
+	{key: "tracing.sample_ratio", env: "TRACING_SAMPLE_RATIO", def: "1", usage: "fraction of traces recorded, from 0 to 1",
+		field: func(c *Config) interface{} { return &c.Tracing.SampleRatio }},
+	{key: "log.level", env: "LOG_LEVEL", def: "info", usage: "debug, info, warn or error",
+		field: func(c *Config) interface{} { return &c.Log.Level }},
+	{key: "log.format", env: "LOG_FORMAT", def: "json", usage: "json or text",
+		field: func(c *Config) interface{} { return &c.Log.Format }},
+	{key: "log.sql_sample_ratio", env: "LOG_SQL_SAMPLE_RATIO", def: "0", usage: "fraction of successful SQL statements logged, from 0 to 1",
+		field: func(c *Config) interface{} { return &c.Log.SQLSampleRatio }},
+	{key: "log.sql_slow_threshold", env: "LOG_SQL_SLOW_THRESHOLD", def: "200ms", usage: "SQL statements slower than this are always logged, 0 to disable",
+		field: func(c *Config) interface{} { return &c.Log.SQLSlowThreshold }},
+}
+
+// flag returns the command-line flag name of the setting.
+func (s setting) flag() string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(s.key)
+}
+
+// set parses value into the setting's field.
+func (s setting) set(c *Config, value string) error {
+	value = strings.TrimSpace(value)
+	switch p := s.field(c).(type) {
+	case *string:
+		*p = value
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", value)
+		}
+		*p = n
+	case *float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		*p = f
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration such as 30s or 5m", value)
+		}
+		*p = d
+	case *time.Time:
+		if value == "" {
+			*p = time.Time{}
+			return nil
+		}
+		t, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return fmt.Errorf("%q is not a date such as 2027-04-30", value)
+		}
+		*p = t
+	default:
+		panic("config: unsupported setting type for " + s.key)
+	}
+	return nil
+}
+
+// get formats the setting's current value.
+func (s setting) get(c *Config) string {
+	switch p := s.field(c).(type) {
+	case *string:
+		return *p
+	case *int:
+		return strconv.Itoa(*p)
+	case *float64:
+		return strconv.FormatFloat(*p, 'f', -1, 64)
+	case *time.Duration:
+		return p.String()
+	case *time.Time:
+		if p.IsZero() {
+			return ""
+		}
+		return p.Format(time.DateOnly)
+	default:
+		panic("config: unsupported setting type for " + s.key)
+	}
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/auth"
+)
+
+// validate checks the parsed settings and returns every problem found.
+func (c *Config) validate() []error {
+	var errs []error
+	fail := func(key, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: "+format, append([]interface{}{key}, args...)...))
+	}
+
+	if c.DB.Password == "" {
+		fail("db.password", "is required, set DB_PASSWORD or DB_PASSWORD_FILE")
+	}
+	for key, value := range map[string]string{"db.host": c.DB.Host, "db.user": c.DB.User, "db.name": c.DB.Name} {
+		if value == "" {
+			fail(key, "cannot be empty")
+		}
+	}
+	if port, err := strconv.Atoi(c.DB.Port); err != nil || port < 1 || port > 65535 {
+		fail("db.port", "%q is not a port number", c.DB.Port)
+	}
+	if c.DB.MaxOpenConns < 0 {
+		fail("db.max_open_conns", "cannot be negative")
+	} else if c.DB.MaxOpenConns == 1 {
+		// The scheduler holds a connection for its lock while a job runs on others.
+		fail("db.max_open_conns", "must be at least 2, or 0 for unlimited")
+	}
+	if c.DB.MaxIdleConns < 0 {
+		fail("db.max_idle_conns", "cannot be negative")
+	} else if c.DB.MaxOpenConns > 0 && c.DB.MaxIdleConns > c.DB.MaxOpenConns {
+		fail("db.max_idle_conns", "cannot exceed db.max_open_conns (%d)", c.DB.MaxOpenConns)
+	}
+
+	if _, _, err := net.SplitHostPort(c.ServerPort); err != nil {
+		fail("server.port", "%q is not a listen address such as :8080", c.ServerPort)
+	}
+
+	positive := map[string]time.Duration{
+		"server.read_timeout":        c.HTTP.ReadTimeout,
+		"server.read_header_timeout": c.HTTP.ReadHeaderTimeout,
+		"server.write_timeout":       c.HTTP.WriteTimeout,
+		"server.idle_timeout":        c.HTTP.IdleTimeout,
+		"server.shutdown_timeout":    c.HTTP.ShutdownTimeout,
+		"scheduler.interval":         c.Scheduler.Interval,
+		"catapi.timeout":             c.CatAPI.Timeout,
+		"catapi.retry_backoff":       c.CatAPI.RetryBackoff,
+		"catapi.breaker_cooldown":    c.CatAPI.BreakerCooldown,
+		"catapi.breed_cache_ttl":     c.CatAPI.BreedCacheTTL,
+		"idempotency.ttl":            c.Idempotency.TTL,
+		"health.check_timeout":       c.Health.CheckTimeout,
+	}
+	for key, d := range positive {
+		if d <= 0 {
+			fail(key, "must be positive")
+		}
+	}
+	for key, d := range map[string]time.Duration{
+		"db.conn_max_lifetime":        c.DB.ConnMaxLifetime,
+		"db.conn_max_idle_time":       c.DB.ConnMaxIdleTime,
+		"catapi.stale_breeds_max_age": c.CatAPI.StaleBreedsMaxAge,
+	} {
+		if d < 0 {
+			fail(key, "cannot be negative")
+		}
+	}
+
+	if c.Scheduler.OverdueEscalation != "notify" && c.Scheduler.OverdueEscalation != "suspend" {
+		fail("scheduler.overdue_escalation", "must be notify or suspend")
+	}
+	for key, raw := range map[string]string{"catapi.base_url": c.CatAPI.BaseURL, "catapi.breeds_url": c.CatAPI.BreedsURL} {
+		if u, err := url.Parse(raw); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail(key, "%q is not an http(s) URL", raw)
+		}
+	}
+	if c.CatAPI.MaxRetries < 0 {
+		fail("catapi.max_retries", "cannot be negative")
+	}
+	if c.CatAPI.BreakerThreshold < 1 {
+		fail("catapi.breaker_threshold", "must be at least 1")
+	}
+	for key, n := range map[string]int{
+		"server.max_body_bytes":   c.HTTP.MaxBodyBytes,
+		"server.max_import_bytes": c.HTTP.MaxImportBytes,
+		"ratelimit.per_minute":    c.RateLimit.PerMinute,
+		"ratelimit.burst":         c.RateLimit.Burst,
+		"notes.max_length":        c.Notes.MaxLength,
+		"graphql.max_complexity":  c.GraphQL.MaxComplexity,
+		"graphql.max_depth":       c.GraphQL.MaxDepth,
+	} {
+		if n < 1 {
+			fail(key, "must be at least 1")
+		}
+	}
+	switch c.API.UnversionedRoutes {
+	case "alias", "redirect", "off":
+	default:
+		fail("api.unversioned_routes", "must be alias, redirect or off")
+	}
+	switch c.RateLimit.Store {
+	case "memory", "postgres", "none":
+	default:
+		fail("ratelimit.store", "must be memory, postgres or none")
+	}
+	if c.Payroll.MissionBonus < 0 {
+		fail("payroll.mission_bonus", "cannot be negative")
+	}
+	switch c.Tracing.Exporter {
+	case "none", "otlp", "stdout":
+	case "file":
+		if c.Tracing.File == "" {
+			fail("tracing.file", "cannot be empty with the file exporter")
+		}
+	default:
+		fail("tracing.exporter", "must be none, otlp, stdout or file")
+	}
+	if c.Tracing.OTLPEndpoint != "" {
+		if u, err := url.Parse(c.Tracing.OTLPEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			fail("tracing.otlp_endpoint", "%q is not an http(s) URL", c.Tracing.OTLPEndpoint)
+		}
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		fail("tracing.sample_ratio", "must be between 0 and 1")
+	}
+	switch c.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		fail("log.level", "must be debug, info, warn or error")
+	}
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		fail("log.format", "must be json or text")
+	}
+	if c.Log.SQLSampleRatio < 0 || c.Log.SQLSampleRatio > 1 {
+		fail("log.sql_sample_ratio", "must be between 0 and 1")
+	}
+	if c.Log.SQLSlowThreshold < 0 {
+		fail("log.sql_slow_threshold", "cannot be negative")
+	}
+
+	// Token errors can echo the token itself, so only say which entry is wrong.
+	if _, err := auth.ParseTokens(c.AuthTokens); err != nil {
+		errs = append(errs, errors.New("auth.tokens: invalid entry, expected token:name:role with role handler or supervisor"))
+	}
+	return errs
+}
@@ -0,0 +1,59 @@
+package budget
+
+import "time"
+
+// Categories an expense can be filed under.
+var Categories = map[string]bool{
+	"TRAVEL":     true,
+	"LODGING":    true,
+	"EQUIPMENT":  true,
+	"INFORMANTS": true,
+	"OTHER":      true,
+}
+
+// Budget is the funding allocated to a mission.
+type Budget struct {
+	ID        uint `gorm:"primaryKey"`
+	MissionID uint `gorm:"uniqueIndex"`
+	Amount    float64
+	Currency  string // ISO 4217 code, e.g. "USD"
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Version   uint `gorm:"not null;default:1"`
+}
+
+// Expense is an entry in a mission's expense ledger.
+type Expense struct {
+	ID          uint  `gorm:"primaryKey"`
+	MissionID   uint  `gorm:"index"`
+	CatID       uint  `gorm:"index"` // the cat who submitted the expense
+	TargetID    *uint // optional target the expense was incurred for
+	Category    string
+	Amount      float64
+	Currency    string
+	ReceiptNote string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Summary is a mission's budget with what has been spent against it.
+type Summary struct {
+	MissionID  uint
+	Budget     *Budget // nil if no budget has been set
+	Spent      float64
+	Remaining  float64
+	ByCategory map[string]float64
+}
+
+// Total is an aggregated amount in one currency.
+type Total struct {
+	Key      string // cat ID or country
+	Currency string
+	Amount   float64
+}
+
+// Report aggregates spending across all missions.
+type Report struct {
+	ByCat     []Total
+	ByCountry []Total
+}
@@ -0,0 +1,148 @@
+package budget
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/etag"
+)
+
+// Handler handles HTTP requests for mission budgets and expenses.
+type Handler struct {
+	service Service
+}
+
+// NewHandler creates a new budget Handler.
+func NewHandler(s Service) *Handler {
+	return &Handler{service: s}
+}
+
+// RegisterRoutes sets up the budget, expense and spending report endpoints.
+func (h *Handler) RegisterRoutes(r gin.IRouter) {
+	// Setting the first budget of a mission needs no If-Match
+	r.PUT("/missions/:id/budget", etag.IfMatch(h.budgetVersion), h.setBudget)
+	r.GET("/missions/:id/budget", h.getBudget)
+	r.POST("/missions/:id/expenses", h.submitExpense)
+	r.GET("/missions/:id/expenses", h.listExpenses)
+
+	r.GET("/reports/expenses", h.spendingReport)
+}
+
+// budgetVersion looks up the version of the budget of the mission addressed by :id.
+func (h *Handler) budgetVersion(c *gin.Context) (interface{}, uint, bool) {
+	missionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, 0, false
+	}
+	summary, err := h.service.GetSummary(c.Request.Context(), uint(missionID))
+	if err != nil || summary.Budget == nil {
+		return nil, 0, false
+	}
+	return summary.Budget, summary.Budget.Version, true
+}
+
+// setBudget handles PUT /missions/:id/budget
+func (h *Handler) setBudget(c *gin.Context) {
+	missionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mission ID"})
+		return
+	}
+
+	var req struct {
+		Amount   float64 `json:"amount"`
+		Currency string  `json:"currency"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	b, err := h.service.SetBudget(c.Request.Context(), uint(missionID), req.Amount, req.Currency)
+	if err != nil {
+		if etag.Conflict(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	etag.Set(c, b.Version)
+	c.JSON(http.StatusOK, b)
+}
+
+// getBudget handles GET /missions/:id/budget
+func (h *Handler) getBudget(c *gin.Context) {
+	missionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mission ID"})
+		return
+	}
+
+	summary, err := h.service.GetSummary(c.Request.Context(), uint(missionID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	// The summary includes spending, so its tag changes with every expense too.
+	if summary.Budget != nil {
+		etag.SetDerived(c, summary.Budget.Version, summary)
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// submitExpense handles POST /missions/:id/expenses
+func (h *Handler) submitExpense(c *gin.Context) {
+	missionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mission ID"})
+		return
+	}
+
+	var req struct {
+		CatID       uint    `json:"cat_id"`
+		TargetID    *uint   `json:"target_id"`
+		Category    string  `json:"category"`
+		Amount      float64 `json:"amount"`
+		Currency    string  `json:"currency"`
+		ReceiptNote string  `json:"receipt_note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	e, err := h.service.SubmitExpense(c.Request.Context(), uint(missionID), req.CatID, req.TargetID, req.Category, req.Amount, req.Currency, req.ReceiptNote)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, e)
+}
+
+// listExpenses handles GET /missions/:id/expenses
+func (h *Handler) listExpenses(c *gin.Context) {
+	missionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mission ID"})
+		return
+	}
+
+	expenses, err := h.service.ListExpenses(c.Request.Context(), uint(missionID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, expenses)
+}
+
+// spendingReport handles GET /reports/expenses
+func (h *Handler) spendingReport(c *gin.Context) {
+	report, err := h.service.SpendingReport(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
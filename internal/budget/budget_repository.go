@@ -0,0 +1,129 @@
+package budget
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/optimistic"
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
+)
+
+type Repository interface {
+	SaveBudget(ctx context.Context, b *Budget) error
+	FindBudgetByMissionID(ctx context.Context, missionID uint) (*Budget, error)
+
+	CreateExpense(ctx context.Context, e *Expense) error
+	ListExpensesByMissionID(ctx context.Context, missionID uint) ([]Expense, error)
+	// SpendingTotals totals the expenses of live missions per submitting cat
+	// and per target country, each in its own currency.
+	SpendingTotals(ctx context.Context) (byCat, byCountry []Total, err error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new budget repository with the given GORM DB instance.
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// SaveBudget creates or updates a mission's Budget. Updates fail with
+// optimistic.ErrConflict if the budget was modified since it was read.
+func (r *repository) SaveBudget(ctx context.Context, b *Budget) error {
+	ctx, span := tracing.Start(ctx, "budget.Repository.SaveBudget")
+	defer span.End()
+
+	if b.ID == 0 {
+		return r.db.WithContext(ctx).Create(b).Error
+	}
+	return optimistic.Update(r.db.WithContext(ctx), b, &b.Version)
+}
+
+// FindBudgetByMissionID retrieves the Budget of a mission.
+func (r *repository) FindBudgetByMissionID(ctx context.Context, missionID uint) (*Budget, error) {
+	ctx, span := tracing.Start(ctx, "budget.Repository.FindBudgetByMissionID")
+	defer span.End()
+
+	var b Budget
+	if err := r.db.WithContext(ctx).Where("mission_id = ?", missionID).First(&b).Error; err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// CreateExpense inserts a new Expense into the ledger.
+func (r *repository) CreateExpense(ctx context.Context, e *Expense) error {
+	ctx, span := tracing.Start(ctx, "budget.Repository.CreateExpense")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Create(e).Error
+}
+
+// ListExpensesByMissionID returns a mission's ledger, oldest first.
+func (r *repository) ListExpensesByMissionID(ctx context.Context, missionID uint) ([]Expense, error) {
+	ctx, span := tracing.Start(ctx, "budget.Repository.ListExpensesByMissionID")
+	defer span.End()
+
+	var expenses []Expense
+	if err := r.db.WithContext(ctx).Where("mission_id = ?", missionID).Order("created_at").Find(&expenses).Error; err != nil {
+		return nil, err
+	}
+	return expenses, nil
+}
+
+// spendingSQL groups the whole ledger in one query, leaving out the expenses of
+// soft-deleted missions. Expenses tied to a target count toward its country;
+// others are split evenly across the distinct countries of their mission's
+// targets, or count as UNKNOWN if it has none.
+const spendingSQL = `
+WITH live_expenses AS (
+	SELECT e.*
+	FROM expenses e
+	JOIN missions m ON m.id = e.mission_id AND m.deleted_at IS NULL
+), mission_countries AS (
+	SELECT DISTINCT mission_id, COALESCE(NULLIF(TRIM(country), ''), 'UNKNOWN') AS country
+	FROM targets
+	WHERE deleted_at IS NULL
+), shares AS (
+	SELECT COALESCE(NULLIF(TRIM(t.country), ''), 'UNKNOWN') AS country, e.currency, e.amount
+	FROM live_expenses e
+	JOIN targets t ON t.id = e.target_id AND t.deleted_at IS NULL
+	UNION ALL
+	SELECT COALESCE(mc.country, 'UNKNOWN'), e.currency, e.amount / COUNT(*) OVER (PARTITION BY e.id)
+	FROM live_expenses e
+	LEFT JOIN targets t ON t.id = e.target_id AND t.deleted_at IS NULL
+	LEFT JOIN mission_countries mc ON mc.mission_id = e.mission_id
+	WHERE t.id IS NULL
+)
+SELECT 'cat' AS dimension, CAST(cat_id AS text) AS key, currency, SUM(amount) AS amount
+FROM live_expenses
+GROUP BY cat_id, currency
+UNION ALL
+SELECT 'country', country, currency, SUM(amount)
+FROM shares
+GROUP BY country, currency`
+
+// SpendingTotals totals the ledger per cat and per country.
+func (r *repository) SpendingTotals(ctx context.Context) ([]Total, []Total, error) {
+	ctx, span := tracing.Start(ctx, "budget.Repository.SpendingTotals")
+	defer span.End()
+
+	var rows []struct {
+		Dimension string
+		Total
+	}
+	if err := r.db.WithContext(ctx).Raw(spendingSQL).Scan(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+	var byCat, byCountry []Total
+	for _, row := range rows {
+		if row.Dimension == "cat" {
+			byCat = append(byCat, row.Total)
+		} else {
+			byCountry = append(byCountry, row.Total)
+		}
+	}
+	return byCat, byCountry, nil
+}
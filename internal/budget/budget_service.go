@@ -0,0 +1,277 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/genryusaishigikuni/spy_cats/internal/cat"
+	"github.com/genryusaishigikuni/spy_cats/internal/mission"
+	"github.com/genryusaishigikuni/spy_cats/internal/target"
+	"github.com/genryusaishigikuni/spy_cats/pkg/errcode"
+	"github.com/genryusaishigikuni/spy_cats/pkg/rules"
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
+	"gorm.io/gorm"
+)
+
+// Errors returned by the budget service.
+var (
+	ErrMissionCompleted = errcode.New("mission_completed", "cannot change the budget of a completed mission")
+	ErrExpenseCompleted = errcode.New("mission_completed", "cannot add expense to a completed mission")
+	ErrNegativeBudget   = errcode.New("invalid_budget", "budget cannot be negative")
+	ErrCurrencyLocked   = errcode.New("budget_currency_locked", "cannot change the currency of a budget with expenses")
+	ErrNotAssigned      = errcode.New("cat_not_assigned", "only the cat assigned to the mission can submit expenses")
+	ErrForeignTarget    = errcode.New("target_not_in_mission", "target not found in this mission")
+	ErrInvalidCategory  = errcode.New("invalid_expense", "category must be one of TRAVEL, LODGING, EQUIPMENT, INFORMANTS or OTHER")
+	ErrInvalidAmount    = errcode.New("invalid_expense", "expense amount must be positive")
+	// ErrCurrencyMismatch names the budget's currency as details.
+	ErrCurrencyMismatch = errcode.New("currency_mismatch", "expense currency must match the mission budget currency")
+	ErrInvalidCurrency  = errcode.New("invalid_currency", "currency must be a three-letter ISO 4217 code")
+)
+
+// Service defines business operations for mission budgets and expenses.
+type Service interface {
+	SetBudget(ctx context.Context, missionID uint, amount float64, currency string) (*Budget, error)
+	GetSummary(ctx context.Context, missionID uint) (*Summary, error)
+
+	SubmitExpense(ctx context.Context, missionID, catID uint, targetID *uint, category string, amount float64, currency, receiptNote string) (*Expense, error)
+	ListExpenses(ctx context.Context, missionID uint) ([]Expense, error)
+
+	// SpendingReport aggregates spending per cat and per target country.
+	SpendingReport(ctx context.Context) (*Report, error)
+}
+
+type service struct {
+	repo        Repository
+	missionRepo mission.Repository
+	targetRepo  target.Repository
+	catRepo     cat.Repository
+	rules       rules.Rules
+}
+
+// NewService creates a new budget service with the given repositories and the
+// business rules deciding whether ledgers freeze.
+func NewService(
+	r Repository,
+	mRepo mission.Repository,
+	tRepo target.Repository,
+	cRepo cat.Repository,
+	rules rules.Rules,
+) Service {
+	return &service{
+		repo:        r,
+		missionRepo: mRepo,
+		targetRepo:  tRepo,
+		catRepo:     cRepo,
+		rules:       rules,
+	}
+}
+
+// SetBudget creates or replaces the budget of a mission that is not completed.
+func (s *service) SetBudget(ctx context.Context, missionID uint, amount float64, currency string) (*Budget, error) {
+	ctx, span := tracing.Start(ctx, "budget.Service.SetBudget")
+	defer span.End()
+
+	m, err := s.missionRepo.FindByID(ctx, missionID)
+	if err != nil {
+		return nil, mission.ErrNotFound
+	}
+	if m.Status == "COMPLETED" {
+		return nil, ErrMissionCompleted
+	}
+	if amount < 0 {
+		return nil, ErrNegativeBudget
+	}
+	currency, err = normalizeCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := s.repo.FindBudgetByMissionID(ctx, missionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		b = &Budget{MissionID: missionID}
+	} else if err != nil {
+		return nil, err
+	} else if b.Currency != currency {
+		expenses, err := s.repo.ListExpensesByMissionID(ctx, missionID)
+		if err != nil {
+			return nil, err
+		}
+		if len(expenses) > 0 {
+			return nil, ErrCurrencyLocked
+		}
+	}
+
+	b.Amount = amount
+	b.Currency = currency
+	if err := s.repo.SaveBudget(ctx, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// GetSummary returns a mission's budget, what has been spent and what remains.
+func (s *service) GetSummary(ctx context.Context, missionID uint) (*Summary, error) {
+	ctx, span := tracing.Start(ctx, "budget.Service.GetSummary")
+	defer span.End()
+
+	if _, err := s.missionRepo.FindByID(ctx, missionID); err != nil {
+		return nil, mission.ErrNotFound
+	}
+
+	summary := &Summary{MissionID: missionID, ByCategory: make(map[string]float64)}
+
+	b, err := s.repo.FindBudgetByMissionID(ctx, missionID)
+	if err == nil {
+		summary.Budget = b
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	expenses, err := s.repo.ListExpensesByMissionID(ctx, missionID)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range expenses {
+		summary.Spent += e.Amount
+		summary.ByCategory[e.Category] = roundCents(summary.ByCategory[e.Category] + e.Amount)
+	}
+	summary.Spent = roundCents(summary.Spent)
+	if summary.Budget != nil {
+		summary.Remaining = roundCents(summary.Budget.Amount - summary.Spent)
+	}
+	return summary, nil
+}
+
+// SubmitExpense files an expense against a mission. Like notes, the ledger is
+// frozen once the mission is completed, unless the rules say otherwise.
+func (s *service) SubmitExpense(ctx context.Context, missionID, catID uint, targetID *uint, category string, amount float64, currency, receiptNote string) (*Expense, error) {
+	ctx, span := tracing.Start(ctx, "budget.Service.SubmitExpense")
+	defer span.End()
+
+	m, err := s.missionRepo.FindByID(ctx, missionID)
+	if err != nil {
+		return nil, mission.ErrNotFound
+	}
+	if s.rules.FreezeExpensesOnCompletion && m.Status == "COMPLETED" {
+		return nil, ErrExpenseCompleted
+	}
+
+	if _, err := s.catRepo.FindByID(ctx, catID); err != nil {
+		return nil, cat.ErrNotFound
+	}
+	if m.AssignedCatID() != catID {
+		return nil, ErrNotAssigned
+	}
+
+	if targetID != nil {
+		t, err := s.targetRepo.FindByID(ctx, *targetID)
+		if err != nil || t.MissionID != missionID {
+			return nil, ErrForeignTarget
+		}
+	}
+
+	category = strings.ToUpper(strings.TrimSpace(category))
+	if !Categories[category] {
+		return nil, ErrInvalidCategory
+	}
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	b, err := s.repo.FindBudgetByMissionID(ctx, missionID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if b != nil && currency == "" {
+		currency = b.Currency
+	}
+	currency, err = normalizeCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+	if b != nil && b.Currency != currency {
+		return nil, fmt.Errorf("%w: %s", ErrCurrencyMismatch, b.Currency)
+	}
+
+	e := &Expense{
+		MissionID:   missionID,
+		CatID:       catID,
+		TargetID:    targetID,
+		Category:    category,
+		Amount:      amount,
+		Currency:    currency,
+		ReceiptNote: receiptNote,
+	}
+	if err := s.repo.CreateExpense(ctx, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// ListExpenses returns a mission's expense ledger.
+func (s *service) ListExpenses(ctx context.Context, missionID uint) ([]Expense, error) {
+	ctx, span := tracing.Start(ctx, "budget.Service.ListExpenses")
+	defer span.End()
+
+	if _, err := s.missionRepo.FindByID(ctx, missionID); err != nil {
+		return nil, mission.ErrNotFound
+	}
+	return s.repo.ListExpensesByMissionID(ctx, missionID)
+}
+
+// SpendingReport aggregates spending per submitting cat and per target country.
+// Expenses tied to a target count toward its country; others are split evenly
+// across the countries of the mission's targets.
+func (s *service) SpendingReport(ctx context.Context) (*Report, error) {
+	ctx, span := tracing.Start(ctx, "budget.Service.SpendingReport")
+	defer span.End()
+
+	byCat, byCountry, err := s.repo.SpendingTotals(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Report{
+		ByCat:     sortTotals(byCat),
+		ByCountry: sortTotals(byCountry),
+	}, nil
+}
+
+// sortTotals rounds totals to cents and sorts them by key and currency.
+func sortTotals(totals []Total) []Total {
+	if totals == nil {
+		totals = []Total{}
+	}
+	for i := range totals {
+		totals[i].Amount = roundCents(totals[i].Amount)
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].Key != totals[j].Key {
+			return totals[i].Key < totals[j].Key
+		}
+		return totals[i].Currency < totals[j].Currency
+	})
+	return totals
+}
+
+// normalizeCurrency validates a three-letter currency code.
+func normalizeCurrency(currency string) (string, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if len(currency) != 3 {
+		return "", ErrInvalidCurrency
+	}
+	for _, r := range currency {
+		if r < 'A' || r > 'Z' {
+			return "", ErrInvalidCurrency
+		}
+	}
+	return currency, nil
+}
+
+// roundCents rounds an amount to two decimals.
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
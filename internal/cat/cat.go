@@ -2,12 +2,36 @@ package cat
 
 import "time"
 
+// Cat statuses. Only ACTIVE cats can take missions; RETIRED and KIA are final.
+const (
+	StatusActive  = "ACTIVE"
+	StatusOnLeave = "ON_LEAVE"
+	StatusInjured = "INJURED"
+	StatusRetired = "RETIRED"
+	StatusKIA     = "KIA"
+)
+
 type Cat struct {
 	ID                uint `gorm:"primaryKey"`
 	Name              string
 	Breed             string
 	YearsOfExperience int
 	Salary            float64
+	Status            string     `gorm:"default:ACTIVE"`
+	RetiredAt         *time.Time // set when the cat is retired or killed in action
 	CreatedAt         time.Time
 	UpdatedAt         time.Time
+	Version           uint `gorm:"not null;default:1"` // bumped on every update and served as the ETag
+}
+
+// Leave is a period during which a cat cannot take missions.
+type Leave struct {
+	ID        uint `gorm:"primaryKey"`
+	CatID     uint `gorm:"index"`
+	StartsAt  time.Time
+	EndsAt    time.Time // exclusive
+	Reason    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Version   uint `gorm:"not null;default:1"`
 }
@@ -0,0 +1,31 @@
+package cat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/errcode"
+)
+
+// ErrUnavailable is returned by CheckAvailable, with the reason as details.
+var ErrUnavailable = errcode.New("cat_unavailable", "cat is not available")
+
+// CheckAvailable returns an error explaining why the cat cannot take a mission at
+// the given time: its status is not ACTIVE or a leave period covers that time.
+func CheckAvailable(ctx context.Context, r Repository, c *Cat, at time.Time) error {
+	if c.Status != "" && c.Status != StatusActive {
+		return fmt.Errorf("%w: status %s", ErrUnavailable, c.Status)
+	}
+
+	l, err := r.FindLeaveAt(ctx, c.ID, at)
+	if err == nil {
+		return fmt.Errorf("%w: on leave until %s", ErrUnavailable, l.EndsAt.Format(time.RFC3339))
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package cat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/clock"
+	"github.com/genryusaishigikuni/spy_cats/pkg/errcode"
+	"github.com/genryusaishigikuni/spy_cats/pkg/logging"
+	"github.com/genryusaishigikuni/spy_cats/pkg/thecatapi"
+)
+
+// Breed describes a breed from TheCatAPI catalog, including the trait scores
+// (1 to 5) used to rank cats for missions.
+type Breed = thecatapi.Breed
+
+// ErrInvalidBreed is returned by BreedCatalog.Find for breeds the catalog does not know.
+var ErrInvalidBreed = errcode.New("invalid_breed", "invalid cat breed")
+
+// BreedCatalog looks up breeds known to TheCatAPI.
+type BreedCatalog interface {
+	// Find returns the breed with the given name (case-insensitive).
+	Find(ctx context.Context, name string) (*Breed, error)
+	// Preload fetches the catalog unless a copy, even a stale one, is cached.
+	Preload(ctx context.Context) error
+	// Ping checks that TheCatAPI answers, without downloading the catalog.
+	Ping(ctx context.Context) error
+}
+
+// breedCatalog fetches the breed list from TheCatAPI and caches it for ttl.
+// While TheCatAPI is unavailable, an expired list is still used for up to
+// staleMaxAge past its ttl. The list is kept in memory only, so a process
+// started during an outage has no catalog to fall back on.
+type breedCatalog struct {
+	client      *thecatapi.Client
+	clock       clock.Clock
+	ttl         time.Duration
+	staleMaxAge time.Duration
+
+	mu        sync.Mutex
+	breeds    []Breed
+	fetchedAt time.Time
+	// fetching is closed when the fetch in flight ends, and nil when there is
+	// none; fetchErr is that fetch's outcome.
+	fetching chan struct{}
+	fetchErr error
+}
+
+// NewBreedCatalog creates a BreedCatalog backed by client.
+func NewBreedCatalog(client *thecatapi.Client, clk clock.Clock, ttl, staleMaxAge time.Duration) BreedCatalog {
+	return &breedCatalog{client: client, clock: clk, ttl: ttl, staleMaxAge: staleMaxAge}
+}
+
+// Find returns the named breed, refreshing the cached catalog when it is stale.
+func (b *breedCatalog) Find(ctx context.Context, name string) (*Breed, error) {
+	breeds, err := b.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, breed := range breeds {
+		if strings.EqualFold(breed.Name, name) {
+			return &breed, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrInvalidBreed, name)
+}
+
+// list returns the cached breeds, fetching them first if needed.
+func (b *breedCatalog) list(ctx context.Context) ([]Breed, error) {
+	breeds, fetchedAt := b.cached()
+	if breeds != nil && b.clock.Now().Sub(fetchedAt) < b.ttl {
+		return breeds, nil
+	}
+
+	err := b.refresh(ctx)
+	breeds, fetchedAt = b.cached()
+	if err == nil {
+		return breeds, nil
+	}
+	if breeds != nil && errors.Is(err, thecatapi.ErrUnavailable) && b.clock.Now().Sub(fetchedAt) < b.ttl+b.staleMaxAge {
+		logging.FromContext(ctx).WarnContext(ctx, "using the last known breed catalog",
+			"fetched_at", fetchedAt, "error", err)
+		return breeds, nil
+	}
+	return nil, err
+}
+
+// Preload fills the cache if it is empty.
+func (b *breedCatalog) Preload(ctx context.Context) error {
+	if breeds, _ := b.cached(); breeds != nil {
+		return nil
+	}
+	return b.refresh(ctx)
+}
+
+// cached returns the cached breeds and when they were fetched.
+func (b *breedCatalog) cached() ([]Breed, time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.breeds, b.fetchedAt
+}
+
+// refresh fetches the catalog into the cache. Concurrent callers share one
+// fetch, and the lock is not held while TheCatAPI is called, so lookups served
+// from the cache never wait for it.
+func (b *breedCatalog) refresh(ctx context.Context) error {
+	b.mu.Lock()
+	if wait := b.fetching; wait != nil {
+		b.mu.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		return b.fetchErr
+	}
+	done := make(chan struct{})
+	b.fetching = done
+	b.mu.Unlock()
+
+	breeds, err := b.client.Breeds(ctx)
+
+	b.mu.Lock()
+	if err == nil {
+		b.breeds = breeds
+		b.fetchedAt = b.clock.Now()
+	}
+	b.fetchErr = err
+	b.fetching = nil
+	b.mu.Unlock()
+	close(done)
+	return err
+}
+
+// Ping checks that TheCatAPI answers.
+func (b *breedCatalog) Ping(ctx context.Context) error {
+	return b.client.Ping(ctx)
+}
@@ -1,10 +1,17 @@
 package cat
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/auth"
+	"github.com/genryusaishigikuni/spy_cats/pkg/etag"
+	"github.com/genryusaishigikuni/spy_cats/pkg/thecatapi"
 )
 
 // Handler handles HTTP requests for the "cat" domain.
@@ -18,17 +25,76 @@ func NewHandler(s Service) *Handler {
 }
 
 // RegisterRoutes sets up the cat endpoints under "/cats".
-func (h *Handler) RegisterRoutes(r *gin.Engine) {
+func (h *Handler) RegisterRoutes(r gin.IRouter) {
 	catGroup := r.Group("/cats")
 	{
-		catGroup.POST("", h.createCat)       // POST /cats
-		catGroup.GET("", h.listCats)         // GET /cats
-		catGroup.GET("/:id", h.getCat)       // GET /cats/:id
-		catGroup.PUT("/:id", h.updateCat)    // PUT /cats/:id
-		catGroup.DELETE("/:id", h.deleteCat) // DELETE /cats/:id
+		catGroup.POST("", h.createCat)                                   // POST /cats
+		catGroup.GET("", h.listCats)                                     // GET /cats?skill=lockpicking&min_level=3&include_deleted=true
+		catGroup.GET("/:id", h.getCat)                                   // GET /cats/:id
+		catGroup.PUT("/:id", etag.IfMatch(h.catVersion), h.updateCat)    // PUT /cats/:id
+		catGroup.PATCH("/:id", etag.IfMatch(h.catVersion), h.patchCat)   // PATCH /cats/:id (JSON Merge Patch)
+		catGroup.DELETE("/:id", etag.IfMatch(h.catVersion), h.deleteCat) // DELETE /cats/:id (retires the cat)
+		catGroup.POST("/:id/restore", h.restoreCat)                      // POST /cats/:id/restore
+
+		catGroup.PATCH("/:id/status", etag.IfMatch(h.catVersion), h.setStatus)               // PATCH /cats/:id/status
+		catGroup.GET("/:id/leaves", h.listLeaves)                                            // GET /cats/:id/leaves
+		catGroup.POST("/:id/leaves", h.addLeave)                                             // POST /cats/:id/leaves
+		catGroup.DELETE("/:id/leaves/:leaveId", etag.IfMatch(h.leaveVersion), h.removeLeave) // DELETE /cats/:id/leaves/:leaveId
 	}
 }
 
+// catVersion looks up the version of the cat addressed by :id for If-Match checks.
+func (h *Handler) catVersion(c *gin.Context) (interface{}, uint, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, 0, false
+	}
+	cat, err := h.service.GetCat(c.Request.Context(), uint(id))
+	if err != nil {
+		return nil, 0, false
+	}
+	return cat, cat.Version, true
+}
+
+// leaveVersion looks up the version of the leave addressed by :id and :leaveId.
+func (h *Handler) leaveVersion(c *gin.Context) (interface{}, uint, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, 0, false
+	}
+	leaveID, err := strconv.Atoi(c.Param("leaveId"))
+	if err != nil {
+		return nil, 0, false
+	}
+	leave, err := h.service.GetLeave(c.Request.Context(), uint(id), uint(leaveID))
+	if err != nil {
+		return nil, 0, false
+	}
+	return leave, leave.Version, true
+}
+
+// validationFailed reports FieldErrors as 422 with the offending fields. It
+// returns false for any other error.
+func validationFailed(c *gin.Context, err error) bool {
+	var fields FieldErrors
+	if !errors.As(err, &fields) {
+		return false
+	}
+	c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "validation failed", "fields": fields})
+	return true
+}
+
+// breedsUnavailable reports a TheCatAPI outage as 503, since the request can
+// succeed once the breed catalog is reachable again. It returns false for any
+// other error.
+func breedsUnavailable(c *gin.Context, err error) bool {
+	if !errors.Is(err, thecatapi.ErrUnavailable) {
+		return false
+	}
+	c.JSON(http.StatusServiceUnavailable, gin.H{"error": "breed catalog unavailable: " + err.Error()})
+	return true
+}
+
 // createCat handles POST /cats
 func (h *Handler) createCat(c *gin.Context) {
 	var req struct {
@@ -43,19 +109,44 @@ func (h *Handler) createCat(c *gin.Context) {
 		return
 	}
 
-	cat, err := h.service.CreateCat(req.Name, req.Breed, req.YearsOfExperience, req.Salary)
+	cat, err := h.service.CreateCat(c.Request.Context(), req.Name, req.Breed, req.YearsOfExperience, req.Salary)
 	if err != nil {
+		if validationFailed(c, err) || breedsUnavailable(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	etag.Set(c, cat.Version)
 	c.JSON(http.StatusCreated, cat)
 }
 
-// listCats handles GET /cats
+// listCats handles GET /cats, optionally filtered by skill and min_level.
+// Retired cats are only listed with ?include_deleted=true.
 func (h *Handler) listCats(c *gin.Context) {
-	cats, err := h.service.ListCats()
+	skill := c.Query("skill")
+	if skill == "" {
+		cats, err := h.service.ListCats(c.Request.Context(), c.Query("include_deleted") == "true")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, cats)
+		return
+	}
+
+	minLevel, err := strconv.Atoi(c.DefaultQuery("min_level", "1"))
 	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid min_level"})
+		return
+	}
+	cats, err := h.service.ListCatsBySkill(c.Request.Context(), skill, minLevel)
+	if err != nil {
+		if errors.Is(err, ErrInvalidMinLevel) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -71,11 +162,12 @@ func (h *Handler) getCat(c *gin.Context) {
 		return
 	}
 
-	cat, err := h.service.GetCat(uint(id))
+	cat, err := h.service.GetCat(c.Request.Context(), uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Cat not found"})
 		return
 	}
+	etag.Set(c, cat.Version)
 	c.JSON(http.StatusOK, cat)
 }
 
@@ -93,6 +185,7 @@ func (h *Handler) updateCat(c *gin.Context) {
 		Breed             string  `json:"breed"`
 		YearsOfExperience int     `json:"years_of_experience"`
 		Salary            float64 `json:"salary"`
+		SalaryReason      string  `json:"salary_reason"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -100,14 +193,60 @@ func (h *Handler) updateCat(c *gin.Context) {
 		return
 	}
 
-	updatedCat, err := h.service.UpdateCat(uint(id), req.Name, req.Breed, req.YearsOfExperience, req.Salary)
+	updatedCat, err := h.service.UpdateCat(c.Request.Context(), uint(id), req.Name, req.Breed, req.YearsOfExperience, req.Salary, req.SalaryReason)
 	if err != nil {
+		if etag.Conflict(c, err) || validationFailed(c, err) || breedsUnavailable(c, err) {
+			return
+		}
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
+	etag.Set(c, updatedCat.Version)
 	c.JSON(http.StatusOK, updatedCat)
 }
 
+// patchCat handles PATCH /cats/:id. The body is a JSON Merge Patch (RFC 7396):
+// only the members present are changed.
+func (h *Handler) patchCat(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cat ID"})
+		return
+	}
+
+	if ct := c.ContentType(); ct != "application/merge-patch+json" && ct != "application/json" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "PATCH expects application/merge-patch+json"})
+		return
+	}
+
+	var doc map[string]json.RawMessage
+	if err := json.NewDecoder(c.Request.Body).Decode(&doc); err != nil || doc == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the patch must be a JSON object"})
+		return
+	}
+	patch, err := DecodeCatPatch(doc)
+	if err != nil {
+		validationFailed(c, err)
+		return
+	}
+
+	cat, err := h.service.PatchCat(c.Request.Context(), uint(id), patch)
+	if err != nil {
+		if etag.Conflict(c, err) || validationFailed(c, err) || breedsUnavailable(c, err) {
+			return
+		}
+		if errors.Is(err, ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	etag.Set(c, cat.Version)
+	c.JSON(http.StatusOK, cat)
+}
+
 // deleteCat handles DELETE /cats/:id
 func (h *Handler) deleteCat(c *gin.Context) {
 	idStr := c.Param("id")
@@ -117,11 +256,137 @@ func (h *Handler) deleteCat(c *gin.Context) {
 		return
 	}
 
-	err = h.service.DeleteCat(uint(id))
+	err = h.service.DeleteCat(c.Request.Context(), uint(id))
+	if err != nil {
+		if etag.Conflict(c, err) {
+			return
+		}
+		if errors.Is(err, ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// restoreCat handles POST /cats/:id/restore
+func (h *Handler) restoreCat(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cat ID"})
+		return
+	}
+
+	p, _ := auth.FromContext(c)
+	cat, err := h.service.RestoreCat(c.Request.Context(), uint(id), p.Name)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	etag.Set(c, cat.Version)
+	c.JSON(http.StatusOK, cat)
+}
+
+// setStatus handles PATCH /cats/:id/status
+func (h *Handler) setStatus(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cat ID"})
+		return
+	}
+
+	var req struct {
+		Status string `json:"status"`
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p, _ := auth.FromContext(c)
+	cat, err := h.service.SetStatus(c.Request.Context(), uint(id), req.Status, p.Name, req.Reason)
+	if err != nil {
+		if etag.Conflict(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	etag.Set(c, cat.Version)
+	c.JSON(http.StatusOK, cat)
+}
+
+// listLeaves handles GET /cats/:id/leaves
+func (h *Handler) listLeaves(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cat ID"})
+		return
+	}
+
+	leaves, err := h.service.ListLeaves(c.Request.Context(), uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
+	c.JSON(http.StatusOK, leaves)
+}
+
+// addLeave handles POST /cats/:id/leaves
+func (h *Handler) addLeave(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cat ID"})
+		return
+	}
+
+	var req struct {
+		StartsAt time.Time `json:"starts_at"`
+		EndsAt   time.Time `json:"ends_at"`
+		Reason   string    `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	leave, err := h.service.AddLeave(c.Request.Context(), uint(id), req.StartsAt, req.EndsAt, req.Reason)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	etag.Set(c, leave.Version)
+	c.JSON(http.StatusCreated, leave)
+}
+
+// removeLeave handles DELETE /cats/:id/leaves/:leaveId
+func (h *Handler) removeLeave(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cat ID"})
+		return
+	}
+	leaveID, err := strconv.Atoi(c.Param("leaveId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid leave ID"})
+		return
+	}
 
+	if err := h.service.RemoveLeave(c.Request.Context(), uint(id), uint(leaveID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
 	c.Status(http.StatusNoContent)
 }
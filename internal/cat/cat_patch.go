@@ -0,0 +1,80 @@
+package cat
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// FieldErrors maps request field names to what is wrong with them.
+type FieldErrors map[string]string
+
+func (e FieldErrors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	msgs := make([]string, len(fields))
+	for i, field := range fields {
+		msgs[i] = field + ": " + e[field]
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// orNil returns nil when there are no errors, so the result can be returned as an error.
+func (e FieldErrors) orNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// CatPatch is a JSON Merge Patch (RFC 7396) of a cat. Nil fields are left unchanged.
+type CatPatch struct {
+	Name              *string
+	Breed             *string
+	YearsOfExperience *int
+	Salary            *float64
+	// SalaryReason is recorded with the salary change when Salary is patched.
+	SalaryReason string
+}
+
+// DecodeCatPatch reads a merge patch document. Members set to null would remove
+// the field, which no cat field allows, and unknown members are rejected, so that
+// a typo does not silently leave a field unchanged.
+func DecodeCatPatch(doc map[string]json.RawMessage) (CatPatch, error) {
+	var patch CatPatch
+	errs := FieldErrors{}
+
+	for field, raw := range doc {
+		if string(raw) == "null" {
+			if field != "salary_reason" {
+				errs[field] = "cannot be removed"
+			}
+			continue
+		}
+
+		var err error
+		switch field {
+		case "name":
+			err = json.Unmarshal(raw, &patch.Name)
+		case "breed":
+			err = json.Unmarshal(raw, &patch.Breed)
+		case "years_of_experience":
+			err = json.Unmarshal(raw, &patch.YearsOfExperience)
+		case "salary":
+			err = json.Unmarshal(raw, &patch.Salary)
+		case "salary_reason":
+			err = json.Unmarshal(raw, &patch.SalaryReason)
+		default:
+			errs[field] = "unknown field"
+			continue
+		}
+		if err != nil {
+			errs[field] = "has the wrong type"
+		}
+	}
+	return patch, errs.orNil()
+}
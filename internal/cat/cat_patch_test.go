@@ -0,0 +1,177 @@
+package cat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/clock"
+)
+
+func TestDecodeCatPatch(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		doc     string
+		want    CatPatch
+		wantErr FieldErrors
+	}{
+		{name: "empty", doc: `{}`},
+		{
+			name: "some fields",
+			doc:  `{"salary": 4200.5, "salary_reason": "promotion", "years_of_experience": 4}`,
+			want: CatPatch{Salary: ptr(4200.5), YearsOfExperience: ptr(4), SalaryReason: "promotion"},
+		},
+		{name: "null reason", doc: `{"name": "Tom", "salary_reason": null}`, want: CatPatch{Name: ptr("Tom")}},
+		{
+			name:    "invalid members",
+			doc:     `{"name": null, "breed": 7, "colour": "black"}`,
+			wantErr: FieldErrors{"name": "cannot be removed", "breed": "has the wrong type", "colour": "unknown field"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var doc map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(tc.doc), &doc); err != nil {
+				t.Fatal(err)
+			}
+			patch, err := DecodeCatPatch(doc)
+			if tc.wantErr != nil {
+				if !reflect.DeepEqual(err, tc.wantErr) {
+					t.Errorf("error = %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeCatPatch: %v", err)
+			}
+			if !reflect.DeepEqual(patch, tc.want) {
+				t.Errorf("patch = %+v, want %+v", patch, tc.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// patchRepo stores one cat. Methods the tests do not need are left to the
+// embedded nil interface and panic if called.
+type patchRepo struct {
+	Repository
+	cat      Cat
+	salaries []string
+}
+
+func (r *patchRepo) FindByID(_ context.Context, id uint) (*Cat, error) {
+	if id != r.cat.ID {
+		return nil, errors.New("record not found")
+	}
+	c := r.cat
+	return &c, nil
+}
+
+func (r *patchRepo) Update(_ context.Context, c *Cat) error {
+	r.cat = *c
+	return nil
+}
+
+func (r *patchRepo) Transaction(_ context.Context, fn func(tx Tx) error) error {
+	return fn(Tx{Cats: r, Salaries: r})
+}
+
+func (r *patchRepo) RecordSalaryChange(_ context.Context, _ uint, salary float64, _ time.Time, reason string) error {
+	r.cat.Salary = salary
+	r.salaries = append(r.salaries, reason)
+	return nil
+}
+
+// countingBreeds knows a fixed set of breeds and counts lookups.
+type countingBreeds struct {
+	BreedCatalog
+	lookups int
+}
+
+func (b *countingBreeds) Find(_ context.Context, name string) (*Breed, error) {
+	b.lookups++
+	if strings.EqualFold(name, "Siamese") || strings.EqualFold(name, "Bengal") {
+		return &Breed{Name: name}, nil
+	}
+	return nil, ErrInvalidBreed
+}
+
+func TestPatchCat(t *testing.T) {
+	tom := Cat{ID: 1, Name: "Tom", Breed: "Siamese", YearsOfExperience: 3, Salary: 3000}
+
+	for _, tc := range []struct {
+		name     string
+		patch    CatPatch
+		want     Cat
+		wantErr  FieldErrors
+		lookups  int
+		salaries []string
+	}{
+		{
+			name:     "salary only",
+			patch:    CatPatch{Salary: ptr(3500.0), SalaryReason: "promotion"},
+			want:     Cat{ID: 1, Name: "Tom", Breed: "Siamese", YearsOfExperience: 3, Salary: 3500},
+			salaries: []string{"promotion"},
+		},
+		{
+			name:     "salary without a reason",
+			patch:    CatPatch{Salary: ptr(3100.0)},
+			want:     Cat{ID: 1, Name: "Tom", Breed: "Siamese", YearsOfExperience: 3, Salary: 3100},
+			salaries: []string{"salary updated with cat record"},
+		},
+		{
+			name:  "unchanged breed is not looked up",
+			patch: CatPatch{Breed: ptr("Siamese"), YearsOfExperience: ptr(4)},
+			want:  Cat{ID: 1, Name: "Tom", Breed: "Siamese", YearsOfExperience: 4, Salary: 3000},
+		},
+		{
+			name:    "changed breed",
+			patch:   CatPatch{Breed: ptr("Bengal")},
+			want:    Cat{ID: 1, Name: "Tom", Breed: "Bengal", YearsOfExperience: 3, Salary: 3000},
+			lookups: 1,
+		},
+		{
+			name:    "invalid fields are reported together",
+			patch:   CatPatch{Name: ptr(""), Breed: ptr("Unicorn"), Salary: ptr(-1.0)},
+			wantErr: FieldErrors{"name": "cannot be empty", "breed": "is not a known breed", "salary": "cannot be negative"},
+			lookups: 1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &patchRepo{cat: tom}
+			breeds := &countingBreeds{}
+			s := NewService(repo, breeds, clock.System())
+
+			c, err := s.PatchCat(context.Background(), 1, tc.patch)
+			if tc.wantErr != nil {
+				if !reflect.DeepEqual(err, tc.wantErr) {
+					t.Errorf("error = %v, want %v", err, tc.wantErr)
+				}
+				if repo.cat != tom {
+					t.Errorf("stored cat = %+v, want it unchanged", repo.cat)
+				}
+			} else if err != nil {
+				t.Fatalf("PatchCat: %v", err)
+			} else if *c != tc.want || repo.cat != tc.want {
+				t.Errorf("cat = %+v, stored %+v, want %+v", *c, repo.cat, tc.want)
+			}
+			if breeds.lookups != tc.lookups {
+				t.Errorf("breed lookups = %d, want %d", breeds.lookups, tc.lookups)
+			}
+			if !reflect.DeepEqual(repo.salaries, tc.salaries) {
+				t.Errorf("salary changes = %v, want %v", repo.salaries, tc.salaries)
+			}
+		})
+	}
+
+	if _, err := NewService(&patchRepo{cat: tom}, &countingBreeds{}, clock.System()).PatchCat(context.Background(), 2, CatPatch{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("patching an unknown cat = %v, want ErrNotFound", err)
+	}
+}
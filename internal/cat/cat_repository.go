@@ -1,53 +1,248 @@
 package cat
 
-import "gorm.io/gorm"
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/genryusaishigikuni/spy_cats/internal/history"
+	"github.com/genryusaishigikuni/spy_cats/pkg/optimistic"
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
+)
 
 type Repository interface {
-	Create(cat *Cat) error
-	FindByID(id uint) (*Cat, error)
-	List() ([]Cat, error)
-	Update(cat *Cat) error
-	Delete(id uint) error
+	Create(ctx context.Context, cat *Cat) error
+	FindByID(ctx context.Context, id uint) (*Cat, error)
+	// FindByIDForUpdate retrieves a Cat and locks its row until the end of
+	// the transaction the repository is bound to.
+	FindByIDForUpdate(ctx context.Context, id uint) (*Cat, error)
+	FindByIDs(ctx context.Context, ids []uint) ([]Cat, error)
+	List(ctx context.Context, includeRetired bool) ([]Cat, error)
+	ListBySkill(ctx context.Context, skillName string, minLevel int) ([]Cat, error)
+	Update(ctx context.Context, cat *Cat) error
+	HasOngoingMission(ctx context.Context, catID uint) (bool, error)
+
+	CreateLeave(ctx context.Context, l *Leave) error
+	ListLeaves(ctx context.Context, catID uint) ([]Leave, error)
+	FindLeave(ctx context.Context, catID, leaveID uint) (*Leave, error)
+	FindLeaveAt(ctx context.Context, catID uint, at time.Time) (*Leave, error)
+	ListLeavesAt(ctx context.Context, at time.Time) ([]Leave, error)
+	DeleteLeave(ctx context.Context, catID, leaveID uint) error
+
+	// Transaction runs fn with repositories bound to a database transaction,
+	// rolled back if fn fails.
+	Transaction(ctx context.Context, fn func(tx Tx) error) error
 }
 
+// Tx holds what a multi-step change to a cat writes through, all bound to the
+// same transaction.
+type Tx struct {
+	Cats    Repository
+	History history.Repository
+	// Salaries is nil if the repository was created without a salary recorder factory.
+	Salaries SalaryRecorder
+}
+
+// SalaryRecorderFactory builds a salary recorder on a transaction's database
+// handle, so that a cat and its salary history are written in one transaction.
+type SalaryRecorderFactory func(db *gorm.DB) SalaryRecorder
+
 type repository struct {
-	db *gorm.DB
+	db       *gorm.DB
+	salaries SalaryRecorderFactory
 }
 
 // NewRepository creates a new cat repository with the given GORM DB instance.
-func NewRepository(db *gorm.DB) Repository {
-	return &repository{db: db}
+// salaries builds the salary recorder of its transactions and may be nil when
+// they never change a salary.
+func NewRepository(db *gorm.DB, salaries SalaryRecorderFactory) Repository {
+	return &repository{db: db, salaries: salaries}
 }
 
 // Create inserts a new Cat record into the database.
-func (r *repository) Create(cat *Cat) error {
-	return r.db.Create(cat).Error
+func (r *repository) Create(ctx context.Context, cat *Cat) error {
+	ctx, span := tracing.Start(ctx, "cat.Repository.Create")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Create(cat).Error
 }
 
 // FindByID retrieves a Cat by its primary key (ID).
-func (r *repository) FindByID(id uint) (*Cat, error) {
+func (r *repository) FindByID(ctx context.Context, id uint) (*Cat, error) {
+	ctx, span := tracing.Start(ctx, "cat.Repository.FindByID")
+	defer span.End()
+
+	var c Cat
+	if err := r.db.WithContext(ctx).First(&c, id).Error; err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// FindByIDForUpdate retrieves a Cat with SELECT ... FOR UPDATE.
+func (r *repository) FindByIDForUpdate(ctx context.Context, id uint) (*Cat, error) {
+	ctx, span := tracing.Start(ctx, "cat.Repository.FindByIDForUpdate")
+	defer span.End()
+
 	var c Cat
-	if err := r.db.First(&c, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&c, id).Error; err != nil {
 		return nil, err
 	}
 	return &c, nil
 }
 
-// List retrieves all Cat records from the database.
-func (r *repository) List() ([]Cat, error) {
+// FindByIDs retrieves the cats with the given IDs, in no particular order.
+func (r *repository) FindByIDs(ctx context.Context, ids []uint) ([]Cat, error) {
+	ctx, span := tracing.Start(ctx, "cat.Repository.FindByIDs")
+	defer span.End()
+
+	var cats []Cat
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&cats).Error; err != nil {
+		return nil, err
+	}
+	return cats, nil
+}
+
+// List retrieves Cat records from the database. Retired cats are the cat domain's
+// soft-deleted rows and are only included when asked for.
+func (r *repository) List(ctx context.Context, includeRetired bool) ([]Cat, error) {
+	ctx, span := tracing.Start(ctx, "cat.Repository.List")
+	defer span.End()
+
+	var cats []Cat
+	query := r.db.WithContext(ctx)
+	if !includeRetired {
+		query = query.Where("status <> ?", StatusRetired)
+	}
+	if err := query.Find(&cats).Error; err != nil {
+		return nil, err
+	}
+	return cats, nil
+}
+
+// ListBySkill retrieves cats whose proficiency in the named skill is at least minLevel.
+func (r *repository) ListBySkill(ctx context.Context, skillName string, minLevel int) ([]Cat, error) {
+	ctx, span := tracing.Start(ctx, "cat.Repository.ListBySkill")
+	defer span.End()
+
 	var cats []Cat
-	if err := r.db.Find(&cats).Error; err != nil {
+	if err := r.db.WithContext(ctx).
+		Joins("JOIN cat_skills ON cat_skills.cat_id = cats.id").
+		Joins("JOIN skills ON skills.id = cat_skills.skill_id").
+		Where("skills.name = LOWER(?) AND cat_skills.level >= ?", skillName, minLevel).
+		Where("cats.status <> ?", StatusRetired).
+		Find(&cats).Error; err != nil {
 		return nil, err
 	}
 	return cats, nil
 }
 
-// Update applies changes to an existing Cat record in the database.
-func (r *repository) Update(cat *Cat) error {
-	return r.db.Save(cat).Error
+// Update applies changes to an existing Cat record in the database. It fails with
+// optimistic.ErrConflict if the cat was modified since it was read.
+func (r *repository) Update(ctx context.Context, cat *Cat) error {
+	ctx, span := tracing.Start(ctx, "cat.Repository.Update")
+	defer span.End()
+
+	return optimistic.Update(r.db.WithContext(ctx), cat, &cat.Version)
+}
+
+// HasOngoingMission reports whether any mission not yet completed is assigned to the cat.
+func (r *repository) HasOngoingMission(ctx context.Context, catID uint) (bool, error) {
+	ctx, span := tracing.Start(ctx, "cat.Repository.HasOngoingMission")
+	defer span.End()
+
+	var count int64
+	if err := r.db.WithContext(ctx).Table("missions").
+		Where("cat_id = ? AND status <> ? AND deleted_at IS NULL", catID, "COMPLETED").
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// CreateLeave inserts a new Leave period.
+func (r *repository) CreateLeave(ctx context.Context, l *Leave) error {
+	ctx, span := tracing.Start(ctx, "cat.Repository.CreateLeave")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Create(l).Error
+}
+
+// ListLeaves returns a cat's leave periods ordered by start.
+func (r *repository) ListLeaves(ctx context.Context, catID uint) ([]Leave, error) {
+	ctx, span := tracing.Start(ctx, "cat.Repository.ListLeaves")
+	defer span.End()
+
+	var leaves []Leave
+	if err := r.db.WithContext(ctx).Where("cat_id = ?", catID).Order("starts_at").Find(&leaves).Error; err != nil {
+		return nil, err
+	}
+	return leaves, nil
+}
+
+// FindLeave retrieves one of a cat's leave periods.
+func (r *repository) FindLeave(ctx context.Context, catID, leaveID uint) (*Leave, error) {
+	ctx, span := tracing.Start(ctx, "cat.Repository.FindLeave")
+	defer span.End()
+
+	var l Leave
+	if err := r.db.WithContext(ctx).Where("cat_id = ?", catID).First(&l, leaveID).Error; err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// FindLeaveAt returns the leave period covering the given time, if any.
+func (r *repository) FindLeaveAt(ctx context.Context, catID uint, at time.Time) (*Leave, error) {
+	ctx, span := tracing.Start(ctx, "cat.Repository.FindLeaveAt")
+	defer span.End()
+
+	var l Leave
+	if err := r.db.WithContext(ctx).
+		Where("cat_id = ? AND starts_at <= ? AND ends_at > ?", catID, at, at).
+		First(&l).Error; err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// ListLeavesAt returns every cat's leave period covering the given time.
+func (r *repository) ListLeavesAt(ctx context.Context, at time.Time) ([]Leave, error) {
+	ctx, span := tracing.Start(ctx, "cat.Repository.ListLeavesAt")
+	defer span.End()
+
+	var leaves []Leave
+	if err := r.db.WithContext(ctx).
+		Where("starts_at <= ? AND ends_at > ?", at, at).
+		Find(&leaves).Error; err != nil {
+		return nil, err
+	}
+	return leaves, nil
 }
 
-// Delete removes a Cat record from the database by its ID.
-func (r *repository) Delete(id uint) error {
-	return r.db.Delete(&Cat{}, id).Error
+// DeleteLeave removes one of a cat's leave periods.
+func (r *repository) DeleteLeave(ctx context.Context, catID, leaveID uint) error {
+	ctx, span := tracing.Start(ctx, "cat.Repository.DeleteLeave")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Where("cat_id = ?", catID).Delete(&Leave{}, leaveID).Error
+}
+
+// Transaction runs fn in a database transaction.
+func (r *repository) Transaction(ctx context.Context, fn func(tx Tx) error) error {
+	ctx, span := tracing.Start(ctx, "cat.Repository.Transaction")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		tx := Tx{
+			Cats:    &repository{db: db, salaries: r.salaries},
+			History: history.NewRepository(db),
+		}
+		if r.salaries != nil {
+			tx.Salaries = r.salaries(db)
+		}
+		return fn(tx)
+	})
 }
@@ -1,45 +1,99 @@
 package cat
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"strings"
 	"time"
+
+	"github.com/genryusaishigikuni/spy_cats/internal/history"
+	"github.com/genryusaishigikuni/spy_cats/pkg/clock"
+	"github.com/genryusaishigikuni/spy_cats/pkg/errcode"
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
 )
 
+// statuses lists every valid cat status.
+var statuses = map[string]bool{
+	StatusActive:  true,
+	StatusOnLeave: true,
+	StatusInjured: true,
+	StatusRetired: true,
+	StatusKIA:     true,
+}
+
+// Errors returned by the cat service.
+var (
+	ErrNotFound      = errcode.New("cat_not_found", "cat not found")
+	ErrLeaveNotFound = errcode.New("leave_not_found", "leave not found")
+	ErrInvalidStatus = errcode.New("invalid_cat_status", "status must be one of ACTIVE, ON_LEAVE, INJURED, RETIRED or KIA")
+	// ErrFinalStatus is returned for retired and KIA cats, whose status only
+	// changes through RestoreCat.
+	ErrFinalStatus     = errcode.New("cat_status_final", "retired and KIA cats cannot change status")
+	ErrNotRetired      = errcode.New("cat_not_retired", "only retired cats can be restored")
+	ErrOnMission       = errcode.New("cat_on_mission", "cannot retire a cat with an ongoing mission")
+	ErrCannotTakeLeave = errcode.New("cat_cannot_take_leave", "retired and KIA cats cannot take leave")
+	ErrInvalidLeave    = errcode.New("invalid_leave", "leave must end after it starts")
+	// ErrInvalidMinLevel is returned when filtering cats by a skill level outside 1–5.
+	ErrInvalidMinLevel = errcode.New("invalid_min_level", "min_level must be between 1 and 5")
+)
+
+// SalaryRecorder records salary changes in the payroll history. Cat salaries are
+// only ever changed through it so that no previous value is lost.
+type SalaryRecorder interface {
+	RecordSalaryChange(ctx context.Context, catID uint, salary float64, effectiveFrom time.Time, reason string) error
+}
+
 // Service defines business operations for the cat domain.
 type Service interface {
-	CreateCat(name, breed string, years int, salary float64) (*Cat, error)
-	GetCat(id uint) (*Cat, error)
-	ListCats() ([]Cat, error)
-	UpdateCat(id uint, name, breed string, years int, salary float64) (*Cat, error)
-	DeleteCat(id uint) error
+	CreateCat(ctx context.Context, name, breed string, years int, salary float64) (*Cat, error)
+	GetCat(ctx context.Context, id uint) (*Cat, error)
+	// GetCats returns the cats with the given IDs; unknown IDs are left out.
+	GetCats(ctx context.Context, ids []uint) ([]Cat, error)
+	ListCats(ctx context.Context, includeRetired bool) ([]Cat, error)
+	ListCatsBySkill(ctx context.Context, skill string, minLevel int) ([]Cat, error)
+	UpdateCat(ctx context.Context, id uint, name, breed string, years int, salary float64, salaryReason string) (*Cat, error)
+	// PatchCat changes only the fields set in the patch.
+	PatchCat(ctx context.Context, id uint, patch CatPatch) (*Cat, error)
+	// DeleteCat retires the cat; its record and history are kept.
+	DeleteCat(ctx context.Context, id uint) error
+	// RestoreCat brings a retired cat back to ACTIVE.
+	RestoreCat(ctx context.Context, id uint, actor string) (*Cat, error)
+
+	SetStatus(ctx context.Context, id uint, status, actor, reason string) (*Cat, error)
+	AddLeave(ctx context.Context, catID uint, startsAt, endsAt time.Time, reason string) (*Leave, error)
+	ListLeaves(ctx context.Context, catID uint) ([]Leave, error)
+	GetLeave(ctx context.Context, catID, leaveID uint) (*Leave, error)
+	RemoveLeave(ctx context.Context, catID, leaveID uint) error
 }
 
 type service struct {
-	repo Repository
+	repo   Repository
+	breeds BreedCatalog
+	clock  clock.Clock
 }
 
-// NewService creates a new cat service with the given cat repository.
-func NewService(r Repository) Service {
-	return &service{repo: r}
+// NewService creates a new cat service with the given cat repository and breed
+// catalog. Salary changes and history entries are written through the
+// repository's transactions.
+func NewService(r Repository, breeds BreedCatalog, clk clock.Clock) Service {
+	return &service{
+		repo:   r,
+		breeds: breeds,
+		clock:  clk,
+	}
 }
 
 // CreateCat creates a new Cat record after validations (including breed).
-func (s *service) CreateCat(name, breed string, years int, salary float64) (*Cat, error) {
-	if name == "" {
-		return nil, errors.New("cat name cannot be empty")
-	}
-	if years < 0 {
-		return nil, errors.New("years of experience cannot be negative")
-	}
-	if salary < 0 {
-		return nil, errors.New("salary cannot be negative")
+// Invalid fields are reported together as FieldErrors.
+func (s *service) CreateCat(ctx context.Context, name, breed string, years int, salary float64) (*Cat, error) {
+	ctx, span := tracing.Start(ctx, "cat.Service.CreateCat")
+	defer span.End()
+
+	errs := validateCat(name, years, salary)
+	if err := s.checkBreed(ctx, breed, errs); err != nil {
+		return nil, err
 	}
-	if err := validateBreed(breed); err != nil {
+	if err := errs.orNil(); err != nil {
 		return nil, err
 	}
 
@@ -47,103 +101,320 @@ func (s *service) CreateCat(name, breed string, years int, salary float64) (*Cat
 		Name:              name,
 		Breed:             breed,
 		YearsOfExperience: years,
-		Salary:            salary,
+		Status:            StatusActive,
 	}
 
-	if err := s.repo.Create(c); err != nil {
+	err := s.repo.Transaction(ctx, func(tx Tx) error {
+		if err := tx.Cats.Create(ctx, c); err != nil {
+			return err
+		}
+		// The starting salary is the first entry of the cat's salary history.
+		return tx.Salaries.RecordSalaryChange(ctx, c.ID, salary, time.Time{}, "initial salary")
+	})
+	if err != nil {
 		return nil, err
 	}
-	return c, nil
+	return s.repo.FindByID(ctx, c.ID)
 }
 
 // GetCat retrieves a cat by its ID.
-func (s *service) GetCat(id uint) (*Cat, error) {
-	cat, err := s.repo.FindByID(id)
+func (s *service) GetCat(ctx context.Context, id uint) (*Cat, error) {
+	ctx, span := tracing.Start(ctx, "cat.Service.GetCat")
+	defer span.End()
+
+	cat, err := s.repo.FindByID(ctx, id)
 	if err != nil {
-		return nil, errors.New("cat not found")
+		return nil, ErrNotFound
 	}
 	return cat, nil
 }
 
-// ListCats retrieves all cats.
-func (s *service) ListCats() ([]Cat, error) {
-	return s.repo.List()
+// GetCats retrieves several cats at once, retired ones included.
+func (s *service) GetCats(ctx context.Context, ids []uint) ([]Cat, error) {
+	ctx, span := tracing.Start(ctx, "cat.Service.GetCats")
+	defer span.End()
+
+	return s.repo.FindByIDs(ctx, ids)
+}
+
+// ListCats retrieves all cats, leaving out retired ones unless includeRetired is set.
+func (s *service) ListCats(ctx context.Context, includeRetired bool) ([]Cat, error) {
+	ctx, span := tracing.Start(ctx, "cat.Service.ListCats")
+	defer span.End()
+
+	return s.repo.List(ctx, includeRetired)
 }
 
-// UpdateCat updates an existing cat's data, including breed validation.
-func (s *service) UpdateCat(id uint, name, breed string, years int, salary float64) (*Cat, error) {
-	c, err := s.repo.FindByID(id)
+// ListCatsBySkill retrieves cats proficient in a skill at minLevel or above.
+func (s *service) ListCatsBySkill(ctx context.Context, skill string, minLevel int) ([]Cat, error) {
+	ctx, span := tracing.Start(ctx, "cat.Service.ListCatsBySkill")
+	defer span.End()
+
+	if minLevel < 1 || minLevel > 5 {
+		return nil, ErrInvalidMinLevel
+	}
+	return s.repo.ListBySkill(ctx, skill, minLevel)
+}
+
+// UpdateCat replaces an existing cat's data.
+func (s *service) UpdateCat(ctx context.Context, id uint, name, breed string, years int, salary float64, salaryReason string) (*Cat, error) {
+	ctx, span := tracing.Start(ctx, "cat.Service.UpdateCat")
+	defer span.End()
+
+	c, err := s.repo.FindByID(ctx, id)
 	if err != nil {
-		return nil, errors.New("cat not found")
+		return nil, ErrNotFound
 	}
+	return s.applyUpdate(ctx, c, name, breed, years, salary, salaryReason)
+}
 
-	if name == "" {
-		return nil, errors.New("cat name cannot be empty")
+// PatchCat applies a merge patch to an existing cat.
+func (s *service) PatchCat(ctx context.Context, id uint, patch CatPatch) (*Cat, error) {
+	ctx, span := tracing.Start(ctx, "cat.Service.PatchCat")
+	defer span.End()
+
+	c, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, ErrNotFound
 	}
-	if years < 0 {
-		return nil, errors.New("years of experience cannot be negative")
+
+	name, breed, years, salary := c.Name, c.Breed, c.YearsOfExperience, c.Salary
+	if patch.Name != nil {
+		name = *patch.Name
 	}
-	if salary < 0 {
-		return nil, errors.New("salary cannot be negative")
+	if patch.Breed != nil {
+		breed = *patch.Breed
 	}
-	if err := validateBreed(breed); err != nil {
+	if patch.YearsOfExperience != nil {
+		years = *patch.YearsOfExperience
+	}
+	if patch.Salary != nil {
+		salary = *patch.Salary
+	}
+	return s.applyUpdate(ctx, c, name, breed, years, salary, patch.SalaryReason)
+}
+
+// applyUpdate validates and stores new values for a cat. The breed is only
+// looked up in the catalog when it changes, and a changed salary is recorded as
+// a salary change rather than overwritten, in the same transaction.
+func (s *service) applyUpdate(ctx context.Context, c *Cat, name, breed string, years int, salary float64, salaryReason string) (*Cat, error) {
+	errs := validateCat(name, years, salary)
+	if breed != c.Breed {
+		if err := s.checkBreed(ctx, breed, errs); err != nil {
+			return nil, err
+		}
+	}
+	if err := errs.orNil(); err != nil {
 		return nil, err
 	}
 
 	c.Name = name
 	c.Breed = breed
 	c.YearsOfExperience = years
-	c.Salary = salary
 
-	if err := s.repo.Update(c); err != nil {
+	salaryChanged := salary != c.Salary
+	if salaryChanged && salaryReason == "" {
+		salaryReason = "salary updated with cat record"
+	}
+	err := s.repo.Transaction(ctx, func(tx Tx) error {
+		if err := tx.Cats.Update(ctx, c); err != nil {
+			return err
+		}
+		if !salaryChanged {
+			return nil
+		}
+		return tx.Salaries.RecordSalaryChange(ctx, c.ID, salary, time.Time{}, salaryReason)
+	})
+	if err != nil {
 		return nil, err
 	}
+	if salaryChanged {
+		return s.repo.FindByID(ctx, c.ID)
+	}
 	return c, nil
 }
 
-// DeleteCat removes a cat from the database by ID.
-func (s *service) DeleteCat(id uint) error {
-	if err := s.repo.Delete(id); err != nil {
-		return errors.New("could not delete cat")
+// validateCat checks the fields of a cat that need no lookups.
+func validateCat(name string, years int, salary float64) FieldErrors {
+	errs := FieldErrors{}
+	if name == "" {
+		errs["name"] = "cannot be empty"
+	}
+	if years < 0 {
+		errs["years_of_experience"] = "cannot be negative"
 	}
-	return nil
+	if salary < 0 {
+		errs["salary"] = "cannot be negative"
+	}
+	return errs
 }
 
-// validateBreed checks if the given breed exists via TheCatAPI.
-func validateBreed(breed string) error {
-	client := &http.Client{Timeout: 5 * time.Second}
-	req, err := http.NewRequest("GET", "https://api.thecatapi.com/v1/breeds", nil)
+// checkBreed adds a field error for breeds the catalog does not know. Failing to
+// reach the catalog is not the caller's fault and is returned as an error instead.
+func (s *service) checkBreed(ctx context.Context, breed string, errs FieldErrors) error {
+	_, err := s.breeds.Find(ctx, breed)
+	if errors.Is(err, ErrInvalidBreed) {
+		errs["breed"] = "is not a known breed"
+		return nil
+	}
+	return err
+}
+
+// DeleteCat retires a cat instead of deleting it, so that the missions, notes and
+// payroll entries referencing it keep their history.
+func (s *service) DeleteCat(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "cat.Service.DeleteCat")
+	defer span.End()
+
+	_, err := s.SetStatus(ctx, id, StatusRetired, "", "retired via delete")
+	return err
+}
+
+// RestoreCat undoes a retirement. Cats killed in action cannot be restored.
+func (s *service) RestoreCat(ctx context.Context, id uint, actor string) (*Cat, error) {
+	ctx, span := tracing.Start(ctx, "cat.Service.RestoreCat")
+	defer span.End()
+
+	c, err := s.repo.FindByID(ctx, id)
 	if err != nil {
-		return fmt.Errorf("could not create request to thecatapi: %w", err)
+		return nil, ErrNotFound
+	}
+	if c.Status != StatusRetired {
+		return nil, fmt.Errorf("%w: cat is %s", ErrNotRetired, c.Status)
 	}
 
-	resp, err := client.Do(req)
+	c.Status = StatusActive
+	c.RetiredAt = nil
+	err = s.repo.Transaction(ctx, func(tx Tx) error {
+		if err := tx.Cats.Update(ctx, c); err != nil {
+			return err
+		}
+		return tx.History.Create(ctx, &history.Entry{
+			EntityType: history.EntityCat,
+			EntityID:   c.ID,
+			Action:     "RESTORE",
+			Actor:      actor,
+			Reason:     "restored from retirement",
+		})
+	})
 	if err != nil {
-		return fmt.Errorf("could not reach thecatapi: %w", err)
+		return nil, err
 	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
+	return c, nil
+}
+
+// SetStatus moves a cat through its lifecycle. RETIRED and KIA are final, and a
+// cat cannot be retired while on an ongoing mission.
+func (s *service) SetStatus(ctx context.Context, id uint, status, actor, reason string) (*Cat, error) {
+	ctx, span := tracing.Start(ctx, "cat.Service.SetStatus")
+	defer span.End()
+
+	c, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	if !statuses[status] {
+		return nil, ErrInvalidStatus
+	}
+	if c.Status == StatusRetired || c.Status == StatusKIA {
+		return nil, fmt.Errorf("%w: cat is %s", ErrFinalStatus, c.Status)
+	}
+
+	if status == StatusRetired {
+		busy, err := s.repo.HasOngoingMission(ctx, id)
 		if err != nil {
-			return
+			return nil, err
+		}
+		if busy {
+			return nil, ErrOnMission
+		}
+	}
+
+	previous := c.Status
+	c.Status = status
+	if status == StatusRetired || status == StatusKIA {
+		now := s.clock.Now()
+		c.RetiredAt = &now
+	}
+	if reason == "" {
+		reason = "status changed from " + previous
+	}
+	err = s.repo.Transaction(ctx, func(tx Tx) error {
+		if err := tx.Cats.Update(ctx, c); err != nil {
+			return err
 		}
-	}(resp.Body)
+		return tx.History.Create(ctx, &history.Entry{
+			EntityType: history.EntityCat,
+			EntityID:   c.ID,
+			Action:     "STATUS_" + status,
+			Actor:      actor,
+			Reason:     reason,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("thecatapi responded with status %d", resp.StatusCode)
+// AddLeave schedules a leave period for a cat.
+func (s *service) AddLeave(ctx context.Context, catID uint, startsAt, endsAt time.Time, reason string) (*Leave, error) {
+	ctx, span := tracing.Start(ctx, "cat.Service.AddLeave")
+	defer span.End()
+
+	c, err := s.repo.FindByID(ctx, catID)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	if c.Status == StatusRetired || c.Status == StatusKIA {
+		return nil, fmt.Errorf("%w: cat is %s", ErrCannotTakeLeave, c.Status)
+	}
+	if startsAt.IsZero() || endsAt.IsZero() || !endsAt.After(startsAt) {
+		return nil, ErrInvalidLeave
 	}
 
-	var breeds []struct {
-		Name string `json:"name"`
+	l := &Leave{
+		CatID:    catID,
+		StartsAt: startsAt,
+		EndsAt:   endsAt,
+		Reason:   reason,
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&breeds); err != nil {
-		return fmt.Errorf("could not decode breed data: %w", err)
+	if err := s.repo.CreateLeave(ctx, l); err != nil {
+		return nil, err
 	}
+	return l, nil
+}
 
-	// Check if breed is in the list
-	for _, b := range breeds {
-		if strings.EqualFold(b.Name, breed) {
-			return nil
-		}
+// ListLeaves returns a cat's leave periods.
+func (s *service) ListLeaves(ctx context.Context, catID uint) ([]Leave, error) {
+	ctx, span := tracing.Start(ctx, "cat.Service.ListLeaves")
+	defer span.End()
+
+	if _, err := s.repo.FindByID(ctx, catID); err != nil {
+		return nil, ErrNotFound
+	}
+	return s.repo.ListLeaves(ctx, catID)
+}
+
+// GetLeave returns one of a cat's leave periods.
+func (s *service) GetLeave(ctx context.Context, catID, leaveID uint) (*Leave, error) {
+	ctx, span := tracing.Start(ctx, "cat.Service.GetLeave")
+	defer span.End()
+
+	l, err := s.repo.FindLeave(ctx, catID, leaveID)
+	if err != nil {
+		return nil, ErrLeaveNotFound
+	}
+	return l, nil
+}
+
+// RemoveLeave cancels one of a cat's leave periods.
+func (s *service) RemoveLeave(ctx context.Context, catID, leaveID uint) error {
+	ctx, span := tracing.Start(ctx, "cat.Service.RemoveLeave")
+	defer span.End()
+
+	if _, err := s.repo.FindByID(ctx, catID); err != nil {
+		return ErrNotFound
 	}
-	return fmt.Errorf("invalid cat breed: %s", breed)
+	return s.repo.DeleteLeave(ctx, catID, leaveID)
 }
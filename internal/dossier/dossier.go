@@ -0,0 +1,57 @@
+package dossier
+
+import (
+	"time"
+
+	"github.com/genryusaishigikuni/spy_cats/internal/target"
+)
+
+// Dossier is a canonical person of interest that targets across missions can link to.
+type Dossier struct {
+	ID          uint     `gorm:"primaryKey"`
+	Name        string   `gorm:"index"`
+	Aliases     []string `gorm:"serializer:json"`
+	PhotoURL    string
+	ThreatLevel string // "LOW", "MEDIUM", "HIGH" or "CRITICAL"
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Version     uint `gorm:"not null;default:1"`
+
+	// Targets are unlinked, not deleted, when their dossier goes away.
+	Targets []target.Target `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL" json:"-"`
+}
+
+// MissionSummary is a read-only view of a mission that touched a dossier.
+type MissionSummary struct {
+	ID          uint
+	CatID       *uint
+	Status      string
+	CompletedAt *time.Time
+	CreatedAt   time.Time
+}
+
+// NoteSummary is a read-only view of a note written against a linked target.
+type NoteSummary struct {
+	ID        uint
+	TargetID  uint
+	Content   string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Detail aggregates everything known about a dossier.
+type Detail struct {
+	Dossier  Dossier
+	Missions []MissionSummary
+	Targets  []target.Target
+	Notes    []NoteSummary
+}
+
+// Suggestion is an existing dossier whose name or aliases resemble a target name.
+type Suggestion struct {
+	DossierID   uint
+	Name        string
+	MatchedOn   string  // the name or alias that matched
+	Score       float64 // similarity between 0 and 1
+	ThreatLevel string
+}
@@ -0,0 +1,205 @@
+package dossier
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/etag"
+)
+
+// Handler handles HTTP requests for the "dossier" domain.
+type Handler struct {
+	service Service
+}
+
+// NewHandler creates a new dossier Handler.
+func NewHandler(s Service) *Handler {
+	return &Handler{service: s}
+}
+
+// RegisterRoutes sets up the dossier endpoints under "/dossiers".
+func (h *Handler) RegisterRoutes(r gin.IRouter) {
+	dossierGroup := r.Group("/dossiers")
+	{
+		dossierGroup.POST("", h.createDossier)                                       // POST /dossiers
+		dossierGroup.GET("", h.listDossiers)                                         // GET /dossiers
+		dossierGroup.GET("/suggest", h.suggestDossiers)                              // GET /dossiers/suggest?name=
+		dossierGroup.GET("/:id", h.getDossier)                                       // GET /dossiers/:id
+		dossierGroup.PUT("/:id", etag.IfMatch(h.dossierVersion), h.updateDossier)    // PUT /dossiers/:id
+		dossierGroup.DELETE("/:id", etag.IfMatch(h.dossierVersion), h.deleteDossier) // DELETE /dossiers/:id
+	}
+
+	// Link a target to a dossier
+	r.PATCH("/targets/:id/dossier/:dossierId", etag.IfMatch(h.targetVersion), h.linkTarget)
+}
+
+// dossierVersion looks up the version of the dossier addressed by :id for If-Match checks.
+func (h *Handler) dossierVersion(c *gin.Context) (interface{}, uint, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, 0, false
+	}
+	d, err := h.service.FindDossier(c.Request.Context(), uint(id))
+	if err != nil {
+		return nil, 0, false
+	}
+	return d, d.Version, true
+}
+
+// targetVersion looks up the version of the target addressed by :id.
+func (h *Handler) targetVersion(c *gin.Context) (interface{}, uint, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, 0, false
+	}
+	t, err := h.service.GetTarget(c.Request.Context(), uint(id))
+	if err != nil {
+		return nil, 0, false
+	}
+	return t, t.Version, true
+}
+
+type dossierRequest struct {
+	Name        string   `json:"name"`
+	Aliases     []string `json:"aliases"`
+	PhotoURL    string   `json:"photo_url"`
+	ThreatLevel string   `json:"threat_level"`
+}
+
+// createDossier handles POST /dossiers
+func (h *Handler) createDossier(c *gin.Context) {
+	var req dossierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	d, err := h.service.CreateDossier(c.Request.Context(), req.Name, req.Aliases, req.PhotoURL, req.ThreatLevel)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	etag.Set(c, d.Version)
+	c.JSON(http.StatusCreated, d)
+}
+
+// listDossiers handles GET /dossiers
+func (h *Handler) listDossiers(c *gin.Context) {
+	dossiers, err := h.service.ListDossiers(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dossiers)
+}
+
+// suggestDossiers handles GET /dossiers/suggest?name=
+func (h *Handler) suggestDossiers(c *gin.Context) {
+	name := c.Query("name")
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name query parameter is required"})
+		return
+	}
+
+	suggestions, err := h.service.Suggest(c.Request.Context(), name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, suggestions)
+}
+
+// getDossier handles GET /dossiers/:id
+func (h *Handler) getDossier(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dossier ID"})
+		return
+	}
+
+	detail, err := h.service.GetDossier(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	// The detail embeds linked missions, targets and notes, so its tag follows them too.
+	etag.SetDerived(c, detail.Dossier.Version, detail)
+	c.JSON(http.StatusOK, detail)
+}
+
+// updateDossier handles PUT /dossiers/:id
+func (h *Handler) updateDossier(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dossier ID"})
+		return
+	}
+
+	var req dossierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	d, err := h.service.UpdateDossier(c.Request.Context(), uint(id), req.Name, req.Aliases, req.PhotoURL, req.ThreatLevel)
+	if err != nil {
+		if etag.Conflict(c, err) {
+			return
+		}
+		if errors.Is(err, ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	etag.Set(c, d.Version)
+	c.JSON(http.StatusOK, d)
+}
+
+// deleteDossier handles DELETE /dossiers/:id
+func (h *Handler) deleteDossier(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dossier ID"})
+		return
+	}
+
+	if err := h.service.DeleteDossier(c.Request.Context(), uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// linkTarget handles PATCH /targets/:id/dossier/:dossierId
+func (h *Handler) linkTarget(c *gin.Context) {
+	targetID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target ID"})
+		return
+	}
+	dossierID, err := strconv.Atoi(c.Param("dossierId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dossier ID"})
+		return
+	}
+
+	t, err := h.service.LinkTarget(c.Request.Context(), uint(targetID), uint(dossierID))
+	if err != nil {
+		if etag.Conflict(c, err) {
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	etag.Set(c, t.Version)
+	c.JSON(http.StatusOK, gin.H{"message": "Target linked to dossier"})
+}
@@ -0,0 +1,138 @@
+package dossier
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/genryusaishigikuni/spy_cats/internal/target"
+	"github.com/genryusaishigikuni/spy_cats/pkg/optimistic"
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
+)
+
+type Repository interface {
+	Create(ctx context.Context, d *Dossier) error
+	FindByID(ctx context.Context, id uint) (*Dossier, error)
+	List(ctx context.Context) ([]Dossier, error)
+	Update(ctx context.Context, d *Dossier) error
+	Delete(ctx context.Context, id uint) error
+
+	FindTargets(ctx context.Context, dossierID uint) ([]target.Target, error)
+	FindMissions(ctx context.Context, missionIDs []uint) ([]MissionSummary, error)
+	FindNotes(ctx context.Context, targetIDs []uint) ([]NoteSummary, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new dossier repository with the given GORM DB instance.
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// Create inserts a new Dossier record into the database.
+func (r *repository) Create(ctx context.Context, d *Dossier) error {
+	ctx, span := tracing.Start(ctx, "dossier.Repository.Create")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Create(d).Error
+}
+
+// FindByID retrieves a Dossier by its primary key (ID).
+func (r *repository) FindByID(ctx context.Context, id uint) (*Dossier, error) {
+	ctx, span := tracing.Start(ctx, "dossier.Repository.FindByID")
+	defer span.End()
+
+	var d Dossier
+	if err := r.db.WithContext(ctx).First(&d, id).Error; err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// List retrieves all Dossier records from the database.
+func (r *repository) List(ctx context.Context) ([]Dossier, error) {
+	ctx, span := tracing.Start(ctx, "dossier.Repository.List")
+	defer span.End()
+
+	var dossiers []Dossier
+	if err := r.db.WithContext(ctx).Find(&dossiers).Error; err != nil {
+		return nil, err
+	}
+	return dossiers, nil
+}
+
+// Update applies changes to an existing Dossier record in the database.
+// It fails with optimistic.ErrConflict if the dossier was modified since it was read.
+func (r *repository) Update(ctx context.Context, d *Dossier) error {
+	ctx, span := tracing.Start(ctx, "dossier.Repository.Update")
+	defer span.End()
+
+	return optimistic.Update(r.db.WithContext(ctx), d, &d.Version)
+}
+
+// Delete removes a Dossier and unlinks any targets that pointed at it.
+func (r *repository) Delete(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "dossier.Repository.Delete")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&target.Target{}).
+			Where("dossier_id = ?", id).
+			Updates(map[string]interface{}{"dossier_id": nil, "version": optimistic.Bump}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Dossier{}, id).Error
+	})
+}
+
+// FindTargets returns every target linked to the dossier, across all missions.
+func (r *repository) FindTargets(ctx context.Context, dossierID uint) ([]target.Target, error) {
+	ctx, span := tracing.Start(ctx, "dossier.Repository.FindTargets")
+	defer span.End()
+
+	var targets []target.Target
+	if err := r.db.WithContext(ctx).Where("dossier_id = ?", dossierID).Order("created_at").Find(&targets).Error; err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+// FindMissions returns a summary of the given missions.
+func (r *repository) FindMissions(ctx context.Context, missionIDs []uint) ([]MissionSummary, error) {
+	ctx, span := tracing.Start(ctx, "dossier.Repository.FindMissions")
+	defer span.End()
+
+	var missions []MissionSummary
+	if len(missionIDs) == 0 {
+		return missions, nil
+	}
+	if err := r.db.WithContext(ctx).Table("missions").
+		Select("id, cat_id, status, completed_at, created_at").
+		Where("id IN ? AND deleted_at IS NULL", missionIDs).
+		Order("created_at").
+		Scan(&missions).Error; err != nil {
+		return nil, err
+	}
+	return missions, nil
+}
+
+// FindNotes returns all notes written against the given targets.
+func (r *repository) FindNotes(ctx context.Context, targetIDs []uint) ([]NoteSummary, error) {
+	ctx, span := tracing.Start(ctx, "dossier.Repository.FindNotes")
+	defer span.End()
+
+	var notes []NoteSummary
+	if len(targetIDs) == 0 {
+		return notes, nil
+	}
+	if err := r.db.WithContext(ctx).Table("notes").
+		Select("id, target_id, content, created_at, updated_at").
+		Where("target_id IN ? AND deleted_at IS NULL", targetIDs).
+		Order("created_at").
+		Scan(&notes).Error; err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
@@ -0,0 +1,307 @@
+package dossier
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/genryusaishigikuni/spy_cats/internal/target"
+	"github.com/genryusaishigikuni/spy_cats/pkg/errcode"
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
+)
+
+// suggestionThreshold is the minimum similarity for a dossier to be suggested.
+const suggestionThreshold = 0.7
+
+// maxSuggestions caps how many dossiers Suggest returns.
+const maxSuggestions = 5
+
+// Errors returned by the dossier service.
+var (
+	ErrNotFound      = errcode.New("dossier_not_found", "dossier not found")
+	ErrNameRequired  = errcode.New("invalid_dossier", "dossier name cannot be empty")
+	ErrInvalidThreat = errcode.New("invalid_dossier", "threat level must be one of LOW, MEDIUM, HIGH or CRITICAL")
+)
+
+var threatLevels = map[string]bool{
+	"LOW":      true,
+	"MEDIUM":   true,
+	"HIGH":     true,
+	"CRITICAL": true,
+}
+
+// Service defines business operations for the dossier domain.
+type Service interface {
+	CreateDossier(ctx context.Context, name string, aliases []string, photoURL, threatLevel string) (*Dossier, error)
+	GetDossier(ctx context.Context, id uint) (*Detail, error)
+	// FindDossier returns the dossier alone, without the records linked to it.
+	FindDossier(ctx context.Context, id uint) (*Dossier, error)
+	ListDossiers(ctx context.Context) ([]Dossier, error)
+	UpdateDossier(ctx context.Context, id uint, name string, aliases []string, photoURL, threatLevel string) (*Dossier, error)
+	DeleteDossier(ctx context.Context, id uint) error
+
+	// LinkTarget attaches a mission target to a dossier.
+	LinkTarget(ctx context.Context, targetID, dossierID uint) (*target.Target, error)
+	GetTarget(ctx context.Context, targetID uint) (*target.Target, error)
+	// Suggest returns dossiers whose name or aliases resemble the given name.
+	Suggest(ctx context.Context, name string) ([]Suggestion, error)
+}
+
+type service struct {
+	repo       Repository
+	targetRepo target.Repository
+}
+
+// NewService creates a new dossier service with the given repositories.
+func NewService(r Repository, tRepo target.Repository) Service {
+	return &service{
+		repo:       r,
+		targetRepo: tRepo,
+	}
+}
+
+// CreateDossier creates a new person of interest.
+func (s *service) CreateDossier(ctx context.Context, name string, aliases []string, photoURL, threatLevel string) (*Dossier, error) {
+	ctx, span := tracing.Start(ctx, "dossier.Service.CreateDossier")
+	defer span.End()
+
+	d := &Dossier{}
+	if err := applyFields(d, name, aliases, photoURL, threatLevel); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// GetDossier returns a dossier together with every mission, target and note that touched it.
+func (s *service) GetDossier(ctx context.Context, id uint) (*Detail, error) {
+	ctx, span := tracing.Start(ctx, "dossier.Service.GetDossier")
+	defer span.End()
+
+	d, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	targets, err := s.repo.FindTargets(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var missionIDs, targetIDs []uint
+	seen := make(map[uint]bool)
+	for _, t := range targets {
+		targetIDs = append(targetIDs, t.ID)
+		if !seen[t.MissionID] {
+			seen[t.MissionID] = true
+			missionIDs = append(missionIDs, t.MissionID)
+		}
+	}
+
+	missions, err := s.repo.FindMissions(ctx, missionIDs)
+	if err != nil {
+		return nil, err
+	}
+	notes, err := s.repo.FindNotes(ctx, targetIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Detail{
+		Dossier:  *d,
+		Missions: missions,
+		Targets:  targets,
+		Notes:    notes,
+	}, nil
+}
+
+// FindDossier retrieves a dossier by ID.
+func (s *service) FindDossier(ctx context.Context, id uint) (*Dossier, error) {
+	ctx, span := tracing.Start(ctx, "dossier.Service.FindDossier")
+	defer span.End()
+
+	d, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	return d, nil
+}
+
+// ListDossiers retrieves all dossiers.
+func (s *service) ListDossiers(ctx context.Context) ([]Dossier, error) {
+	ctx, span := tracing.Start(ctx, "dossier.Service.ListDossiers")
+	defer span.End()
+
+	return s.repo.List(ctx)
+}
+
+// UpdateDossier replaces a dossier's descriptive fields.
+func (s *service) UpdateDossier(ctx context.Context, id uint, name string, aliases []string, photoURL, threatLevel string) (*Dossier, error) {
+	ctx, span := tracing.Start(ctx, "dossier.Service.UpdateDossier")
+	defer span.End()
+
+	d, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	if err := applyFields(d, name, aliases, photoURL, threatLevel); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// DeleteDossier removes a dossier; linked targets are kept but unlinked.
+func (s *service) DeleteDossier(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "dossier.Service.DeleteDossier")
+	defer span.End()
+
+	if _, err := s.repo.FindByID(ctx, id); err != nil {
+		return ErrNotFound
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+// LinkTarget attaches a mission target to a dossier.
+func (s *service) LinkTarget(ctx context.Context, targetID, dossierID uint) (*target.Target, error) {
+	ctx, span := tracing.Start(ctx, "dossier.Service.LinkTarget")
+	defer span.End()
+
+	if _, err := s.repo.FindByID(ctx, dossierID); err != nil {
+		return nil, ErrNotFound
+	}
+	t, err := s.GetTarget(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	t.DossierID = &dossierID
+	if err := s.targetRepo.Update(ctx, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// GetTarget retrieves a target that may be linked to a dossier.
+func (s *service) GetTarget(ctx context.Context, targetID uint) (*target.Target, error) {
+	ctx, span := tracing.Start(ctx, "dossier.Service.GetTarget")
+	defer span.End()
+
+	t, err := s.targetRepo.FindByID(ctx, targetID)
+	if err != nil {
+		return nil, target.ErrNotFound
+	}
+	return t, nil
+}
+
+// Suggest ranks existing dossiers by how closely their name or aliases match name.
+func (s *service) Suggest(ctx context.Context, name string) ([]Suggestion, error) {
+	ctx, span := tracing.Start(ctx, "dossier.Service.Suggest")
+	defer span.End()
+
+	wanted := normalizeName(name)
+	if wanted == "" {
+		return nil, nil
+	}
+
+	dossiers, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var suggestions []Suggestion
+	for _, d := range dossiers {
+		best := Suggestion{DossierID: d.ID, Name: d.Name, ThreatLevel: d.ThreatLevel}
+		for _, candidate := range append([]string{d.Name}, d.Aliases...) {
+			score := similarity(wanted, normalizeName(candidate))
+			if score > best.Score {
+				best.Score = score
+				best.MatchedOn = candidate
+			}
+		}
+		if best.Score >= suggestionThreshold {
+			suggestions = append(suggestions, best)
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Score > suggestions[j].Score
+	})
+	if len(suggestions) > maxSuggestions {
+		suggestions = suggestions[:maxSuggestions]
+	}
+	return suggestions, nil
+}
+
+// applyFields validates and copies the editable fields onto d.
+func applyFields(d *Dossier, name string, aliases []string, photoURL, threatLevel string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ErrNameRequired
+	}
+
+	threatLevel = strings.ToUpper(strings.TrimSpace(threatLevel))
+	if threatLevel == "" {
+		threatLevel = "LOW"
+	}
+	if !threatLevels[threatLevel] {
+		return ErrInvalidThreat
+	}
+
+	var cleaned []string
+	for _, a := range aliases {
+		if a = strings.TrimSpace(a); a != "" {
+			cleaned = append(cleaned, a)
+		}
+	}
+
+	d.Name = name
+	d.Aliases = cleaned
+	d.PhotoURL = strings.TrimSpace(photoURL)
+	d.ThreatLevel = threatLevel
+	return nil
+}
+
+// normalizeName lowercases a name and collapses everything but letters and digits into single spaces.
+func normalizeName(name string) string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// similarity returns a score in [0, 1] derived from the Levenshtein distance of a and b.
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+// levenshtein computes the edit distance between two rune slices.
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,158 @@
+package graph
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// listFactor is how many items a list field without a first argument is
+// assumed to hold when the cost of a query is estimated.
+const listFactor = 10
+
+// maxIntrospectionDepth bounds how deeply introspection fields nest. It is
+// separate from Limits.MaxDepth so that tools can run the standard
+// introspection query, whose type references nest about a dozen levels.
+const maxIntrospectionDepth = 15
+
+// measure estimates the cost of an operation before it runs: every field
+// costs 1 and the fields below a list count as many times over as the list's
+// first argument allows, or listFactor times for lists without one, so a list
+// of missions with their targets' notes costs far more than a single mission.
+// Introspection fields cost 1 each without the list factor, and their nesting
+// is recorded in introspectionDepth rather than counted in the operation's
+// depth. The document must have been validated.
+type measure struct {
+	schema    *graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+
+	introspecting      bool
+	introspectionDepth int
+}
+
+func newMeasure(schema *graphql.Schema, doc *ast.Document, variables map[string]interface{}) *measure {
+	m := &measure{schema: schema, fragments: make(map[string]*ast.FragmentDefinition), variables: variables}
+	for _, def := range doc.Definitions {
+		if f, ok := def.(*ast.FragmentDefinition); ok {
+			m.fragments[f.Name.Value] = f
+		}
+	}
+	return m
+}
+
+// operation returns the operation's complexity and how deeply its fields nest.
+func (m *measure) operation(op *ast.OperationDefinition) (complexity, depth int) {
+	root := m.schema.QueryType()
+	if op.Operation == ast.OperationTypeMutation {
+		root = m.schema.MutationType()
+	}
+	return m.selectionSet(root, op.SelectionSet)
+}
+
+func (m *measure) selectionSet(parent *graphql.Object, set *ast.SelectionSet) (complexity, depth int) {
+	if parent == nil || set == nil {
+		return 0, 0
+	}
+	for _, sel := range set.Selections {
+		var c, d int
+		switch sel := sel.(type) {
+		case *ast.Field:
+			c, d = m.field(parent, sel)
+		case *ast.InlineFragment:
+			c, d = m.selectionSet(m.typeCondition(parent, sel.TypeCondition), sel.SelectionSet)
+		case *ast.FragmentSpread:
+			if f, ok := m.fragments[sel.Name.Value]; ok {
+				c, d = m.selectionSet(m.typeCondition(parent, f.TypeCondition), f.SelectionSet)
+			}
+		}
+		complexity += c
+		depth = max(depth, d)
+	}
+	return complexity, depth
+}
+
+func (m *measure) field(parent *graphql.Object, f *ast.Field) (complexity, depth int) {
+	if strings.HasPrefix(f.Name.Value, "__") && !m.introspecting {
+		m.introspecting = true
+		c, d := m.field(parent, f)
+		m.introspecting = false
+		m.introspectionDepth = max(m.introspectionDepth, d)
+		return c, 0
+	}
+	def := fieldDefinition(parent, f.Name.Value)
+	if def == nil {
+		return 1, 1
+	}
+
+	factor := 1
+	t := def.Type
+unwrap:
+	for {
+		switch wrapped := t.(type) {
+		case *graphql.NonNull:
+			t = wrapped.OfType
+		case *graphql.List:
+			if !m.introspecting {
+				factor *= m.listSize(def, f)
+			}
+			t = wrapped.OfType
+		default:
+			break unwrap
+		}
+	}
+	child, _ := t.(*graphql.Object)
+	c, d := m.selectionSet(child, f.SelectionSet)
+	return 1 + factor*c, 1 + d
+}
+
+// listSize is how many items a list field is assumed to return: the value of
+// its first argument, capped at maxFirst, or listFactor if it has none.
+func (m *measure) listSize(def *graphql.FieldDefinition, f *ast.Field) int {
+	for _, arg := range def.Args {
+		if arg.Name() != "first" {
+			continue
+		}
+		n, _ := arg.DefaultValue.(int)
+		for _, a := range f.Arguments {
+			if a.Name.Value != "first" {
+				continue
+			}
+			switch v := a.Value.(type) {
+			case *ast.IntValue:
+				n, _ = strconv.Atoi(v.Value)
+			case *ast.Variable:
+				// Variables decoded from JSON are float64.
+				if value, ok := m.variables[v.Name.Value].(float64); ok {
+					n = int(value)
+				}
+			}
+		}
+		return min(max(n, 0), maxFirst)
+	}
+	return listFactor
+}
+
+// fieldDefinition returns the definition of the named field of parent,
+// including the introspection fields every type has, or nil if there is none.
+func fieldDefinition(parent *graphql.Object, name string) *graphql.FieldDefinition {
+	switch name {
+	case "__schema":
+		return graphql.SchemaMetaFieldDef
+	case "__type":
+		return graphql.TypeMetaFieldDef
+	case "__typename":
+		return graphql.TypeNameMetaFieldDef
+	}
+	return parent.Fields()[name]
+}
+
+func (m *measure) typeCondition(parent *graphql.Object, cond *ast.Named) *graphql.Object {
+	if cond == nil {
+		return parent
+	}
+	t, _ := m.schema.Type(cond.Name.Value).(*graphql.Object)
+	return t
+}
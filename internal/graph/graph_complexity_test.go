@@ -0,0 +1,82 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/testutil"
+)
+
+func TestMeasure(t *testing.T) {
+	h, err := NewHandler(nil, nil, nil, nil, Limits{})
+	if err != nil {
+		t.Fatalf("NewHandler: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name               string
+		query              string
+		variables          map[string]interface{}
+		complexity, depth  int
+		introspectionDepth int
+	}{
+		{name: "single record", query: `{ mission(id: "1") { id status } }`, complexity: 3, depth: 2},
+		{name: "default first", query: `{ missions { id } }`, complexity: 1 + defaultFirst, depth: 2},
+		{name: "literal first", query: `{ missions(first: 5) { id status } }`, complexity: 11, depth: 2},
+		{
+			name:       "variable first",
+			query:      `query($n: Int) { missions(first: $n) { id } }`,
+			variables:  map[string]interface{}{"n": float64(3)},
+			complexity: 4, depth: 2,
+		},
+		{name: "first is capped", query: `{ missions(first: 1000) { id } }`, complexity: 1 + maxFirst, depth: 2},
+		{
+			name:       "list without first",
+			query:      `{ mission(id: "1") { targets { name } } }`,
+			complexity: 1 + 1 + listFactor, depth: 3,
+		},
+		{
+			name:               "typename",
+			query:              `{ mission(id: "1") { __typename id } }`,
+			complexity:         3,
+			depth:              2,
+			introspectionDepth: 1,
+		},
+		{
+			name:               "introspection nests without list factor",
+			query:              `{ __schema { types { fields { type { ofType { name } } } } } }`,
+			complexity:         6,
+			introspectionDepth: 6,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			doc, err := parser.Parse(parser.ParseParams{Source: tc.query})
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			m := newMeasure(&h.schema, doc, tc.variables)
+			complexity, depth := m.operation(doc.Definitions[0].(*ast.OperationDefinition))
+			if complexity != tc.complexity || depth != tc.depth || m.introspectionDepth != tc.introspectionDepth {
+				t.Errorf("complexity, depth, introspection depth = %d, %d, %d, want %d, %d, %d",
+					complexity, depth, m.introspectionDepth, tc.complexity, tc.depth, tc.introspectionDepth)
+			}
+		})
+	}
+}
+
+func TestMeasureAllowsStandardIntrospection(t *testing.T) {
+	h, err := NewHandler(nil, nil, nil, nil, Limits{})
+	if err != nil {
+		t.Fatalf("NewHandler: %v", err)
+	}
+	doc, err := parser.Parse(parser.ParseParams{Source: testutil.IntrospectionQuery})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	m := newMeasure(&h.schema, doc, nil)
+	m.operation(doc.Definitions[0].(*ast.OperationDefinition))
+	if m.introspectionDepth > maxIntrospectionDepth {
+		t.Errorf("introspection depth = %d, want at most %d", m.introspectionDepth, maxIntrospectionDepth)
+	}
+}
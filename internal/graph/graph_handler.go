@@ -0,0 +1,161 @@
+// Package graph serves cats, missions, targets and notes over GraphQL, so that
+// a client can fetch related records in one round trip. Related records are
+// loaded in batches, one query per relationship and level, and mutations go
+// through the domain services like the REST handlers do.
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+
+	"github.com/genryusaishigikuni/spy_cats/internal/cat"
+	"github.com/genryusaishigikuni/spy_cats/internal/mission"
+	"github.com/genryusaishigikuni/spy_cats/internal/note"
+	"github.com/genryusaishigikuni/spy_cats/internal/target"
+	"github.com/genryusaishigikuni/spy_cats/pkg/auth"
+)
+
+// Limits bound the queries the handler runs; see measure for how complexity
+// is estimated.
+type Limits struct {
+	MaxComplexity int
+	MaxDepth      int
+}
+
+// Handler handles GraphQL requests.
+type Handler struct {
+	cats     cat.Service
+	missions mission.Service
+	targets  target.Service
+	notes    note.Service
+	limits   Limits
+	schema   graphql.Schema
+}
+
+// NewHandler creates a GraphQL Handler over the domain services.
+func NewHandler(cats cat.Service, missions mission.Service, targets target.Service, notes note.Service, limits Limits) (*Handler, error) {
+	h := &Handler{cats: cats, missions: missions, targets: targets, notes: notes, limits: limits}
+	schema, err := h.newSchema()
+	if err != nil {
+		return nil, err
+	}
+	h.schema = schema
+	return h, nil
+}
+
+// RegisterRoutes sets up the GraphQL endpoint. Queries may be sent with GET
+// or POST, mutations only with POST.
+func (h *Handler) RegisterRoutes(r gin.IRouter) {
+	r.GET("/graphql", h.serve)
+	r.POST("/graphql", h.serve)
+}
+
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// serve handles GET and POST /graphql
+func (h *Handler) serve(c *gin.Context) {
+	var req request
+	if c.Request.Method == http.MethodGet {
+		req.Query = c.Query("query")
+		req.OperationName = c.Query("operationName")
+		if v := c.Query("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				c.JSON(http.StatusBadRequest, errorResponse("Invalid variables: "+err.Error()))
+				return
+			}
+		}
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, errorResponse(err.Error()))
+		return
+	}
+	if req.Query == "" {
+		c.JSON(http.StatusBadRequest, errorResponse("Missing query"))
+		return
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+		Body: []byte(req.Query),
+		Name: "GraphQL request",
+	})})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"errors": gqlerrors.FormatErrors(err)})
+		return
+	}
+	if v := graphql.ValidateDocument(&h.schema, doc, nil); !v.IsValid {
+		c.JSON(http.StatusBadRequest, gin.H{"errors": v.Errors})
+		return
+	}
+	op := operation(doc, req.OperationName)
+	if op == nil {
+		c.JSON(http.StatusBadRequest, errorResponse("Unknown or ambiguous operation"))
+		return
+	}
+	if c.Request.Method == http.MethodGet && op.Operation != ast.OperationTypeQuery {
+		c.Header("Allow", http.MethodPost)
+		c.JSON(http.StatusMethodNotAllowed, errorResponse("Mutations must be sent with POST"))
+		return
+	}
+
+	m := newMeasure(&h.schema, doc, req.Variables)
+	complexity, depth := m.operation(op)
+	if m.introspectionDepth > maxIntrospectionDepth {
+		c.JSON(http.StatusUnprocessableEntity, errorResponse(fmt.Sprintf("introspection is nested %d levels deep, at most %d", m.introspectionDepth, maxIntrospectionDepth)))
+		return
+	}
+	if depth > h.limits.MaxDepth {
+		c.JSON(http.StatusUnprocessableEntity, errorResponse(fmt.Sprintf("query is nested %d levels deep, at most %d", depth, h.limits.MaxDepth)))
+		return
+	}
+	if complexity > h.limits.MaxComplexity {
+		c.JSON(http.StatusUnprocessableEntity, errorResponse(fmt.Sprintf("query complexity is %d, at most %d", complexity, h.limits.MaxComplexity)))
+		return
+	}
+
+	p, _ := auth.FromContext(c)
+	ctx := c.Request.Context()
+	ctx = context.WithValue(ctx, stateKey{}, h.newState(ctx, p))
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        h.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       ctx,
+	})
+	c.JSON(http.StatusOK, result)
+}
+
+// operation returns the operation to run: the one named, or the only one.
+func operation(doc *ast.Document, name string) *ast.OperationDefinition {
+	var found *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if name == "" && found != nil {
+			return nil
+		}
+		if name == "" || (op.Name != nil && op.Name.Value == name) {
+			found = op
+		}
+	}
+	return found
+}
+
+// errorResponse is a GraphQL response holding a single error.
+func errorResponse(msg string) gin.H {
+	return gin.H{"errors": []gqlerrors.FormattedError{{Message: msg}}}
+}
@@ -0,0 +1,141 @@
+package graph
+
+import (
+	"context"
+	"sync"
+
+	"github.com/genryusaishigikuni/spy_cats/internal/cat"
+	"github.com/genryusaishigikuni/spy_cats/internal/mission"
+	"github.com/genryusaishigikuni/spy_cats/internal/note"
+	"github.com/genryusaishigikuni/spy_cats/internal/target"
+	"github.com/genryusaishigikuni/spy_cats/pkg/auth"
+)
+
+// loader batches the keys asked for while one level of a query is resolved
+// into a single fetch. The executor resolves a level completely, collecting
+// the thunks returned by load, before it calls any of them, so the first
+// thunk called fetches the keys of the whole level. Results are kept for the
+// rest of the request.
+type loader[V any] struct {
+	ctx   context.Context
+	fetch func(ctx context.Context, keys []uint) (map[uint]V, error)
+
+	mu      sync.Mutex
+	pending *batch[V]
+	batches map[uint]*batch[V] // by key, fetched or pending
+}
+
+type batch[V any] struct {
+	keys    []uint
+	once    sync.Once
+	results map[uint]V
+	err     error
+}
+
+func newLoader[V any](ctx context.Context, fetch func(ctx context.Context, keys []uint) (map[uint]V, error)) *loader[V] {
+	return &loader[V]{ctx: ctx, fetch: fetch, batches: make(map[uint]*batch[V])}
+}
+
+// load returns a thunk for the value under key. Keys without a value give the
+// zero value, which the executor serves as null or, for slices, an empty list.
+func (l *loader[V]) load(key uint) func() (interface{}, error) {
+	l.mu.Lock()
+	b, ok := l.batches[key]
+	if !ok {
+		if l.pending == nil {
+			l.pending = &batch[V]{}
+		}
+		b = l.pending
+		b.keys = append(b.keys, key)
+		l.batches[key] = b
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		b.once.Do(func() {
+			l.mu.Lock()
+			if l.pending == b {
+				l.pending = nil
+			}
+			l.mu.Unlock()
+			b.results, b.err = l.fetch(l.ctx, b.keys)
+		})
+		if b.err != nil {
+			return nil, b.err
+		}
+		return b.results[key], nil
+	}
+}
+
+// state is what resolvers need beyond their arguments: the request's
+// principal and its loaders.
+type state struct {
+	principal auth.Principal
+
+	cats             *loader[*cat.Cat]
+	missions         *loader[*mission.Mission]
+	missionsByCat    *loader[[]*mission.Mission]
+	targets          *loader[*target.Target]
+	targetsByMission *loader[[]*target.Target]
+	notesByTarget    *loader[[]*note.Note]
+	latestNotes      *loader[*note.Note]
+}
+
+type stateKey struct{}
+
+func (h *Handler) newState(ctx context.Context, p auth.Principal) *state {
+	return &state{
+		principal: p,
+		cats: newLoader(ctx, func(ctx context.Context, ids []uint) (map[uint]*cat.Cat, error) {
+			cats, err := h.cats.GetCats(ctx, ids)
+			return indexBy(cats, func(c *cat.Cat) uint { return c.ID }), err
+		}),
+		missions: newLoader(ctx, func(ctx context.Context, ids []uint) (map[uint]*mission.Mission, error) {
+			missions, err := h.missions.GetMissions(ctx, ids)
+			return indexBy(missions, func(m *mission.Mission) uint { return m.ID }), err
+		}),
+		missionsByCat: newLoader(ctx, func(ctx context.Context, catIDs []uint) (map[uint][]*mission.Mission, error) {
+			missions, err := h.missions.ListMissionsByCats(ctx, catIDs)
+			return groupBy(missions, (*mission.Mission).AssignedCatID), err
+		}),
+		targets: newLoader(ctx, func(ctx context.Context, ids []uint) (map[uint]*target.Target, error) {
+			targets, err := h.targets.GetTargets(ctx, ids)
+			return indexBy(targets, func(t *target.Target) uint { return t.ID }), err
+		}),
+		targetsByMission: newLoader(ctx, func(ctx context.Context, missionIDs []uint) (map[uint][]*target.Target, error) {
+			targets, err := h.targets.ListTargetsByMissions(ctx, missionIDs)
+			return groupBy(targets, func(t *target.Target) uint { return t.MissionID }), err
+		}),
+		notesByTarget: newLoader(ctx, func(ctx context.Context, targetIDs []uint) (map[uint][]*note.Note, error) {
+			notes, err := h.notes.ListNotesByTargets(ctx, targetIDs)
+			return groupBy(notes, func(n *note.Note) uint { return n.TargetID }), err
+		}),
+		latestNotes: newLoader(ctx, func(ctx context.Context, targetIDs []uint) (map[uint]*note.Note, error) {
+			notes, err := h.notes.LatestNotesByTargets(ctx, targetIDs)
+			return indexBy(notes, func(n *note.Note) uint { return n.TargetID }), err
+		}),
+	}
+}
+
+func stateFrom(ctx context.Context) *state {
+	return ctx.Value(stateKey{}).(*state)
+}
+
+// indexBy indexes items by key.
+func indexBy[T any](items []T, key func(*T) uint) map[uint]*T {
+	m := make(map[uint]*T, len(items))
+	for i := range items {
+		m[key(&items[i])] = &items[i]
+	}
+	return m
+}
+
+// groupBy groups items by key, keeping their order.
+func groupBy[T any](items []T, key func(*T) uint) map[uint][]*T {
+	m := make(map[uint][]*T)
+	for i := range items {
+		k := key(&items[i])
+		m[k] = append(m[k], &items[i])
+	}
+	return m
+}
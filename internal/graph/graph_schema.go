@@ -0,0 +1,585 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/graphql-go/graphql"
+
+	"github.com/genryusaishigikuni/spy_cats/internal/cat"
+	"github.com/genryusaishigikuni/spy_cats/internal/mission"
+	"github.com/genryusaishigikuni/spy_cats/internal/note"
+	"github.com/genryusaishigikuni/spy_cats/internal/target"
+	"github.com/genryusaishigikuni/spy_cats/pkg/auth"
+	"github.com/genryusaishigikuni/spy_cats/pkg/optimistic"
+)
+
+// newSchema builds the schema. Object fields without a resolver are read from
+// the model field of the same name; relationships go through the request's
+// loaders. Resolvers always get models as pointers.
+func (h *Handler) newSchema() (graphql.Schema, error) {
+	var catType, missionType, targetType, noteType *graphql.Object
+
+	catType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Cat",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":                {Type: graphql.NewNonNull(graphql.ID)},
+				"name":              {Type: graphql.NewNonNull(graphql.String)},
+				"breed":             {Type: graphql.NewNonNull(graphql.String)},
+				"yearsOfExperience": {Type: graphql.NewNonNull(graphql.Int)},
+				"salary":            {Type: graphql.NewNonNull(graphql.Float)},
+				"status":            {Type: graphql.NewNonNull(graphql.String)},
+				"retiredAt":         {Type: graphql.DateTime},
+				"createdAt":         {Type: graphql.NewNonNull(graphql.DateTime)},
+				"updatedAt":         {Type: graphql.NewNonNull(graphql.DateTime)},
+				"version":           {Type: graphql.NewNonNull(graphql.Int)},
+				"missions": {
+					Type:        listOf(missionType),
+					Description: "Every mission ever assigned to the cat.",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return stateFrom(p.Context).missionsByCat.load(p.Source.(*cat.Cat).ID), nil
+					},
+				},
+			}
+		}),
+	})
+
+	missionType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mission",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id": {Type: graphql.NewNonNull(graphql.ID)},
+				"catId": {
+					Type: graphql.ID,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return optionalID(p.Source.(*mission.Mission).CatID), nil
+					},
+				},
+				"cat": {
+					Type:        catType,
+					Description: "The assigned cat, null if the mission is unassigned.",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						m := p.Source.(*mission.Mission)
+						if m.CatID == nil {
+							return nil, nil
+						}
+						return stateFrom(p.Context).cats.load(*m.CatID), nil
+					},
+				},
+				"targets": {
+					Type: listOf(targetType),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return stateFrom(p.Context).targetsByMission.load(p.Source.(*mission.Mission).ID), nil
+					},
+				},
+				"status":         {Type: graphql.NewNonNull(graphql.String)},
+				"priority":       {Type: graphql.NewNonNull(graphql.String)},
+				"difficulty":     {Type: graphql.NewNonNull(graphql.Int)},
+				"requiredSkills": {Type: listOf(graphql.String)},
+				"startAt":        {Type: graphql.DateTime},
+				"dueAt":          {Type: graphql.DateTime},
+				"overdueAt":      {Type: graphql.DateTime},
+				"completedAt":    {Type: graphql.DateTime},
+				"createdAt":      {Type: graphql.NewNonNull(graphql.DateTime)},
+				"updatedAt":      {Type: graphql.NewNonNull(graphql.DateTime)},
+				"version":        {Type: graphql.NewNonNull(graphql.Int)},
+				"deletedAt": {
+					Type: graphql.DateTime,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						if d := p.Source.(*mission.Mission).DeletedAt; d.Valid {
+							return d.Time, nil
+						}
+						return nil, nil
+					},
+				},
+			}
+		}),
+	})
+
+	targetType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Target",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":        {Type: graphql.NewNonNull(graphql.ID)},
+				"missionId": {Type: graphql.NewNonNull(graphql.ID)},
+				"mission": {
+					Type: graphql.NewNonNull(missionType),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return stateFrom(p.Context).missions.load(p.Source.(*target.Target).MissionID), nil
+					},
+				},
+				"dossierId": {
+					Type: graphql.ID,
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return optionalID(p.Source.(*target.Target).DossierID), nil
+					},
+				},
+				"name":    {Type: graphql.NewNonNull(graphql.String)},
+				"country": {Type: graphql.NewNonNull(graphql.String)},
+				"notes": {
+					Type:        graphql.NewNonNull(graphql.String),
+					Description: "The target's own notes, given when it was added; the notes recorded on it are fieldNotes.",
+				},
+				"fieldNotes": {
+					Type:        listOf(noteType),
+					Description: "The notes recorded on the target, oldest first.",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return stateFrom(p.Context).notesByTarget.load(p.Source.(*target.Target).ID), nil
+					},
+				},
+				"latestNote": {
+					Type:        noteType,
+					Description: "The most recent note recorded on the target, null if there is none.",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return stateFrom(p.Context).latestNotes.load(p.Source.(*target.Target).ID), nil
+					},
+				},
+				"status":      {Type: graphql.NewNonNull(graphql.String)},
+				"dueAt":       {Type: graphql.DateTime},
+				"completedAt": {Type: graphql.DateTime},
+				"createdAt":   {Type: graphql.NewNonNull(graphql.DateTime)},
+				"updatedAt":   {Type: graphql.NewNonNull(graphql.DateTime)},
+				"version":     {Type: graphql.NewNonNull(graphql.Int)},
+			}
+		}),
+	})
+
+	noteType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Note",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":       {Type: graphql.NewNonNull(graphql.ID)},
+				"targetId": {Type: graphql.NewNonNull(graphql.ID)},
+				"target": {
+					Type: graphql.NewNonNull(targetType),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return stateFrom(p.Context).targets.load(p.Source.(*note.Note).TargetID), nil
+					},
+				},
+				"content":   {Type: graphql.NewNonNull(graphql.String)},
+				"createdAt": {Type: graphql.NewNonNull(graphql.DateTime)},
+				"updatedAt": {Type: graphql.NewNonNull(graphql.DateTime)},
+				"version":   {Type: graphql.NewNonNull(graphql.Int)},
+			}
+		}),
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"cats": {
+				Type: listOf(catType),
+				Args: graphql.FieldConfigArgument{
+					"includeRetired": {Type: graphql.Boolean, DefaultValue: false},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					cats, err := h.cats.ListCats(p.Context, p.Args["includeRetired"].(bool))
+					return pointers(cats), err
+				},
+			},
+			"cat": {
+				Type: catType,
+				Args: idArg("id"),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, err := argID(p, "id")
+					if err != nil {
+						return nil, err
+					}
+					return h.cats.GetCat(p.Context, id)
+				},
+			},
+			"missions": {
+				Type: listOf(missionType),
+				Args: graphql.FieldConfigArgument{
+					"status":         {Type: graphql.String, Description: "Only missions with this status, such as ONGOING."},
+					"includeDeleted": {Type: graphql.Boolean, DefaultValue: false},
+					"first":          firstArg(),
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					first, err := argFirst(p)
+					if err != nil {
+						return nil, err
+					}
+					status, _ := p.Args["status"].(string)
+					missions, err := h.missions.ListMissions(p.Context, mission.ListFilter{
+						IncludeDeleted: p.Args["includeDeleted"].(bool),
+						Status:         status,
+						Limit:          first,
+					})
+					if err != nil {
+						return nil, err
+					}
+					return pointers(missions), nil
+				},
+			},
+			"mission": {
+				Type: missionType,
+				Args: idArg("id"),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, err := argID(p, "id")
+					if err != nil {
+						return nil, err
+					}
+					return h.missions.GetMissionByID(p.Context, id)
+				},
+			},
+			"target": {
+				Type: targetType,
+				Args: idArg("id"),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, err := argID(p, "id")
+					if err != nil {
+						return nil, err
+					}
+					return h.targets.GetTarget(p.Context, id)
+				},
+			},
+			"note": {
+				Type: noteType,
+				Args: idArg("id"),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, err := argID(p, "id")
+					if err != nil {
+						return nil, err
+					}
+					return h.notes.GetNote(p.Context, id)
+				},
+			},
+		},
+	})
+
+	newMission := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "NewMission",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"catId":          {Type: graphql.ID, Description: "Leave out to create the mission unassigned."},
+			"targetNames":    {Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+			"startAt":        {Type: graphql.DateTime},
+			"dueAt":          {Type: graphql.DateTime},
+			"priority":       {Type: graphql.String},
+			"difficulty":     {Type: graphql.Int},
+			"requiredSkills": {Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createCat": {
+				Type: graphql.NewNonNull(catType),
+				Args: graphql.FieldConfigArgument{
+					"name":              {Type: graphql.NewNonNull(graphql.String)},
+					"breed":             {Type: graphql.NewNonNull(graphql.String)},
+					"yearsOfExperience": {Type: graphql.NewNonNull(graphql.Int)},
+					"salary":            {Type: graphql.NewNonNull(graphql.Float)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return h.cats.CreateCat(p.Context,
+						p.Args["name"].(string), p.Args["breed"].(string),
+						p.Args["yearsOfExperience"].(int), p.Args["salary"].(float64))
+				},
+			},
+			"deleteCat": {
+				Type:        graphql.NewNonNull(graphql.Boolean),
+				Description: "Retires the cat; its record and history are kept.",
+				Args:        versionedIDArgs(),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, err := argID(p, "id")
+					if err != nil {
+						return nil, err
+					}
+					c, err := h.cats.GetCat(p.Context, id)
+					if err != nil {
+						return nil, err
+					}
+					ctx, err := expect(p, c, c.Version)
+					if err != nil {
+						return nil, err
+					}
+					return true, h.cats.DeleteCat(ctx, id)
+				},
+			},
+			"createMission": {
+				Type: graphql.NewNonNull(missionType),
+				Args: graphql.FieldConfigArgument{
+					"input": {Type: graphql.NewNonNull(newMission)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					in := p.Args["input"].(map[string]interface{})
+					req := mission.NewMission{
+						TargetNames:    stringList(in["targetNames"]),
+						StartAt:        optionalTime(in["startAt"]),
+						DueAt:          optionalTime(in["dueAt"]),
+						RequiredSkills: stringList(in["requiredSkills"]),
+					}
+					if _, ok := in["catId"]; ok {
+						id, err := parseID("catId", in["catId"])
+						if err != nil {
+							return nil, err
+						}
+						req.CatID = id
+					}
+					req.Priority, _ = in["priority"].(string)
+					req.Difficulty, _ = in["difficulty"].(int)
+					m, _, err := h.missions.CreateMission(p.Context, req)
+					return m, err
+				},
+			},
+			"assignCat": {
+				Type: graphql.NewNonNull(missionType),
+				Args: graphql.FieldConfigArgument{
+					"missionId": {Type: graphql.NewNonNull(graphql.ID)},
+					"catId":     {Type: graphql.NewNonNull(graphql.ID)},
+					"overrideSkills": {
+						Type:         graphql.Boolean,
+						DefaultValue: false,
+						Description:  "Skips the required-skills check; reserved for supervisors.",
+					},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					missionID, err := argID(p, "missionId")
+					if err != nil {
+						return nil, err
+					}
+					catID, err := argID(p, "catId")
+					if err != nil {
+						return nil, err
+					}
+					override := p.Args["overrideSkills"].(bool)
+					principal := stateFrom(p.Context).principal
+					if override && principal.Role != auth.RoleSupervisor {
+						return nil, errors.New("requires " + auth.RoleSupervisor + " role")
+					}
+					if err := h.missions.AssignCat(p.Context, missionID, catID, override, principal.Name); err != nil {
+						return nil, err
+					}
+					return h.missions.GetMissionByID(p.Context, missionID)
+				},
+			},
+			"completeMission": {
+				Type: graphql.NewNonNull(missionType),
+				Args: idArg("id"),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, err := argID(p, "id")
+					if err != nil {
+						return nil, err
+					}
+					if err := h.missions.MarkMissionComplete(p.Context, id); err != nil {
+						return nil, err
+					}
+					return h.missions.GetMissionByID(p.Context, id)
+				},
+			},
+			"addTarget": {
+				Type: graphql.NewNonNull(targetType),
+				Args: graphql.FieldConfigArgument{
+					"missionId": {Type: graphql.NewNonNull(graphql.ID)},
+					"name":      {Type: graphql.NewNonNull(graphql.String)},
+					"country":   {Type: graphql.NewNonNull(graphql.String)},
+					"notes":     {Type: graphql.String, DefaultValue: ""},
+					"dueAt":     {Type: graphql.DateTime},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					missionID, err := argID(p, "missionId")
+					if err != nil {
+						return nil, err
+					}
+					t, _, err := h.missions.AddTargetToMission(p.Context, missionID,
+						p.Args["name"].(string), p.Args["country"].(string), p.Args["notes"].(string),
+						optionalTime(p.Args["dueAt"]))
+					return t, err
+				},
+			},
+			"completeTarget": {
+				Type: graphql.NewNonNull(targetType),
+				Args: idArg("id"),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, err := argID(p, "id")
+					if err != nil {
+						return nil, err
+					}
+					if err := h.missions.CompleteTarget(p.Context, id); err != nil {
+						return nil, err
+					}
+					return h.targets.GetTarget(p.Context, id)
+				},
+			},
+			"createNote": {
+				Type: graphql.NewNonNull(noteType),
+				Args: graphql.FieldConfigArgument{
+					"targetId": {Type: graphql.NewNonNull(graphql.ID)},
+					"content":  {Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					targetID, err := argID(p, "targetId")
+					if err != nil {
+						return nil, err
+					}
+					return h.notes.CreateNote(p.Context, targetID, p.Args["content"].(string))
+				},
+			},
+			"updateNote": {
+				Type: graphql.NewNonNull(noteType),
+				Args: graphql.FieldConfigArgument{
+					"id":              {Type: graphql.NewNonNull(graphql.ID)},
+					"expectedVersion": versionArg,
+					"content":         {Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, err := argID(p, "id")
+					if err != nil {
+						return nil, err
+					}
+					n, err := h.notes.GetNote(p.Context, id)
+					if err != nil {
+						return nil, err
+					}
+					ctx, err := expect(p, n, n.Version)
+					if err != nil {
+						return nil, err
+					}
+					return h.notes.UpdateNote(ctx, id, p.Args["content"].(string))
+				},
+			},
+			"deleteNote": {
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: versionedIDArgs(),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, err := argID(p, "id")
+					if err != nil {
+						return nil, err
+					}
+					n, err := h.notes.GetNote(p.Context, id)
+					if err != nil {
+						return nil, err
+					}
+					ctx, err := expect(p, n, n.Version)
+					if err != nil {
+						return nil, err
+					}
+					return true, h.notes.DeleteNote(ctx, id)
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+// listOf is a non-null list of non-null items.
+func listOf(t graphql.Type) *graphql.NonNull {
+	return graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(t)))
+}
+
+func idArg(name string) graphql.FieldConfigArgument {
+	return graphql.FieldConfigArgument{name: {Type: graphql.NewNonNull(graphql.ID)}}
+}
+
+// versionArg is the version of the record the client last read, the GraphQL
+// counterpart of the If-Match header the REST routes require.
+var versionArg = &graphql.ArgumentConfig{
+	Type:        graphql.NewNonNull(graphql.Int),
+	Description: "The record's version as last read; the change is refused if it has moved on since.",
+}
+
+func versionedIDArgs() graphql.FieldConfigArgument {
+	return graphql.FieldConfigArgument{
+		"id":              {Type: graphql.NewNonNull(graphql.ID)},
+		"expectedVersion": versionArg,
+	}
+}
+
+// expect checks the expectedVersion argument against the record's current
+// version and returns a context under which the record is written only if it
+// is still that version, as etag.IfMatch does for REST.
+func expect(p graphql.ResolveParams, record interface{}, version uint) (context.Context, error) {
+	expected := p.Args["expectedVersion"].(int)
+	if expected < 0 || uint(expected) != version {
+		return nil, optimistic.ErrConflict
+	}
+	return optimistic.Expect(p.Context, record, version), nil
+}
+
+// defaultFirst and maxFirst are how many items a list field with a first
+// argument returns by default and at most.
+const (
+	defaultFirst = 20
+	maxFirst     = 100
+)
+
+// firstArg is the argument limiting how many items a list field returns. The
+// complexity measure counts the list's fields that many times over.
+func firstArg() *graphql.ArgumentConfig {
+	return &graphql.ArgumentConfig{
+		Type:         graphql.Int,
+		DefaultValue: defaultFirst,
+		Description:  fmt.Sprintf("Return at most this many items (default %d, at most %d).", defaultFirst, maxFirst),
+	}
+}
+
+// argFirst returns the first argument, checking it is within bounds.
+func argFirst(p graphql.ResolveParams) (int, error) {
+	first, _ := p.Args["first"].(int)
+	if first < 1 || first > maxFirst {
+		return 0, fmt.Errorf("first must be between 1 and %d", maxFirst)
+	}
+	return first, nil
+}
+
+// argID parses the ID argument called name.
+func argID(p graphql.ResolveParams, name string) (uint, error) {
+	return parseID(name, p.Args[name])
+}
+
+func parseID(name string, v interface{}) (uint, error) {
+	s, _ := v.(string)
+	id, err := strconv.ParseUint(s, 10, 0)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, s)
+	}
+	return uint(id), nil
+}
+
+// optionalID serves a nullable foreign key.
+func optionalID(id *uint) interface{} {
+	if id == nil {
+		return nil
+	}
+	return *id
+}
+
+// optionalTime converts a nullable DateTime argument.
+func optionalTime(v interface{}) *time.Time {
+	t, ok := v.(time.Time)
+	if !ok {
+		return nil
+	}
+	return &t
+}
+
+// stringList converts a nullable list of strings argument.
+func stringList(v interface{}) []string {
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+	s := make([]string, 0, len(items))
+	for _, item := range items {
+		if str, ok := item.(string); ok {
+			s = append(s, str)
+		}
+	}
+	return s
+}
+
+// pointers lets resolvers always get models as pointers.
+func pointers[T any](items []T) []*T {
+	p := make([]*T, len(items))
+	for i := range items {
+		p[i] = &items[i]
+	}
+	return p
+}
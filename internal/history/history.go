@@ -0,0 +1,14 @@
+package history
+
+import "time"
+
+// Entry records a notable change made to a domain entity, such as a supervised override.
+type Entry struct {
+	ID         uint   `gorm:"primaryKey"`
+	EntityType string `gorm:"index:idx_history_entity"` // "mission", "target", ...
+	EntityID   uint   `gorm:"index:idx_history_entity"`
+	Action     string // e.g. "REOPEN"
+	Actor      string // name of the principal who made the change
+	Reason     string
+	CreatedAt  time.Time
+}
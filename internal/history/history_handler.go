@@ -0,0 +1,40 @@
+package history
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler exposes the recorded history of entities.
+type Handler struct {
+	service Service
+}
+
+// NewHandler creates a new history Handler.
+func NewHandler(s Service) *Handler {
+	return &Handler{service: s}
+}
+
+// RegisterRoutes sets up the history endpoint.
+func (h *Handler) RegisterRoutes(r gin.IRouter) {
+	r.GET("/history/:entityType/:id", h.listHistory) // GET /history/mission/1
+}
+
+// listHistory handles GET /history/:entityType/:id
+func (h *Handler) listHistory(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid entity ID"})
+		return
+	}
+
+	entries, err := h.service.ListForEntity(c.Request.Context(), c.Param("entityType"), uint(id))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
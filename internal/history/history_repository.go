@@ -0,0 +1,46 @@
+package history
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
+)
+
+type Repository interface {
+	Create(ctx context.Context, e *Entry) error
+	ListByEntity(ctx context.Context, entityType string, entityID uint) ([]Entry, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new history repository with the given GORM DB instance.
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// Create inserts a new history Entry.
+func (r *repository) Create(ctx context.Context, e *Entry) error {
+	ctx, span := tracing.Start(ctx, "history.Repository.Create")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Create(e).Error
+}
+
+// ListByEntity returns the history of a single entity, oldest first.
+func (r *repository) ListByEntity(ctx context.Context, entityType string, entityID uint) ([]Entry, error) {
+	ctx, span := tracing.Start(ctx, "history.Repository.ListByEntity")
+	defer span.End()
+
+	var entries []Entry
+	if err := r.db.WithContext(ctx).
+		Where("entity_type = ? AND entity_id = ?", entityType, entityID).
+		Order("created_at").
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,45 @@
+package history
+
+import (
+	"context"
+	"strings"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/errcode"
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
+)
+
+// Entity types recorded in history.
+const (
+	EntityCat     = "cat"
+	EntityMission = "mission"
+	EntityTarget  = "target"
+)
+
+// ErrUnknownEntity is returned for history requests about an unknown kind of record.
+var ErrUnknownEntity = errcode.New("unknown_entity_type", "unknown entity type")
+
+// Service defines read access to recorded history.
+type Service interface {
+	ListForEntity(ctx context.Context, entityType string, entityID uint) ([]Entry, error)
+}
+
+type service struct {
+	repo Repository
+}
+
+// NewService creates a new history service with the given repository.
+func NewService(r Repository) Service {
+	return &service{repo: r}
+}
+
+// ListForEntity returns the history of a cat, mission or target.
+func (s *service) ListForEntity(ctx context.Context, entityType string, entityID uint) ([]Entry, error) {
+	ctx, span := tracing.Start(ctx, "history.Service.ListForEntity")
+	defer span.End()
+
+	entityType = strings.ToLower(entityType)
+	if entityType != EntityCat && entityType != EntityMission && entityType != EntityTarget {
+		return nil, ErrUnknownEntity
+	}
+	return s.repo.ListByEntity(ctx, entityType, entityID)
+}
@@ -0,0 +1,72 @@
+package kpi
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/clock"
+)
+
+// scrapeTimeout bounds the queries run for one scrape.
+const scrapeTimeout = 5 * time.Second
+
+var (
+	ongoingMissionsDesc = prometheus.NewDesc(
+		"spy_cats_missions_ongoing", "Missions currently in progress.", nil, nil)
+	freeCatsDesc = prometheus.NewDesc(
+		"spy_cats_cats_free", "Active cats with no unfinished mission and not on leave.", nil, nil)
+	targetsCompletedDesc = prometheus.NewDesc(
+		"spy_cats_targets_completed_last_day", "Targets completed in the last 24 hours.", nil, nil)
+	missionDurationDesc = prometheus.NewDesc(
+		"spy_cats_mission_duration_average_seconds", "Average time from creation to completion of completed missions.", nil, nil)
+)
+
+// Collector exports business indicators, queried from the database on every
+// scrape so that all replicas report the same values.
+type Collector struct {
+	repo  Repository
+	clock clock.Clock
+}
+
+// NewCollector creates a Collector reading from repo.
+func NewCollector(repo Repository, clk clock.Clock) *Collector {
+	return &Collector{repo: repo, clock: clk}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- ongoingMissionsDesc
+	ch <- freeCatsDesc
+	ch <- targetsCompletedDesc
+	ch <- missionDurationDesc
+}
+
+// Collect implements prometheus.Collector. A failed query is reported as an
+// invalid metric and does not hide the others.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), scrapeTimeout)
+	defer cancel()
+	now := c.clock.Now()
+
+	gauge := func(desc *prometheus.Desc, value float64, err error) {
+		if err != nil {
+			ch <- prometheus.NewInvalidMetric(desc, err)
+			return
+		}
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value)
+	}
+
+	ongoing, err := c.repo.CountOngoingMissions(ctx)
+	gauge(ongoingMissionsDesc, float64(ongoing), err)
+
+	free, err := c.repo.CountFreeCats(ctx, now)
+	gauge(freeCatsDesc, float64(free), err)
+
+	completed, err := c.repo.CountTargetsCompletedSince(ctx, now.Add(-24*time.Hour))
+	gauge(targetsCompletedDesc, float64(completed), err)
+
+	avg, err := c.repo.AverageMissionDuration(ctx)
+	gauge(missionDurationDesc, avg.Seconds(), err)
+}
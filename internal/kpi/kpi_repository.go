@@ -0,0 +1,91 @@
+package kpi
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
+)
+
+// Repository computes business indicators with aggregate queries.
+type Repository interface {
+	// CountOngoingMissions counts missions in progress.
+	CountOngoingMissions(ctx context.Context) (int64, error)
+	// CountFreeCats counts active cats that are neither on a mission nor on leave at now.
+	CountFreeCats(ctx context.Context, now time.Time) (int64, error)
+	// CountTargetsCompletedSince counts targets completed at or after since.
+	CountTargetsCompletedSince(ctx context.Context, since time.Time) (int64, error)
+	// AverageMissionDuration averages CompletedAt - CreatedAt over completed
+	// missions; it is zero if none has been completed.
+	AverageMissionDuration(ctx context.Context) (time.Duration, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new KPI repository with the given GORM DB instance.
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// CountOngoingMissions counts missions with status ONGOING.
+func (r *repository) CountOngoingMissions(ctx context.Context) (int64, error) {
+	ctx, span := tracing.Start(ctx, "kpi.Repository.CountOngoingMissions")
+	defer span.End()
+
+	var count int64
+	if err := r.db.WithContext(ctx).Table("missions").
+		Where("status = ? AND deleted_at IS NULL", "ONGOING").
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// CountFreeCats counts ACTIVE cats without an unfinished mission or a leave covering now.
+func (r *repository) CountFreeCats(ctx context.Context, now time.Time) (int64, error) {
+	ctx, span := tracing.Start(ctx, "kpi.Repository.CountFreeCats")
+	defer span.End()
+
+	var count int64
+	if err := r.db.WithContext(ctx).Table("cats").
+		Where("status = ?", "ACTIVE").
+		Where("NOT EXISTS (SELECT 1 FROM missions WHERE missions.cat_id = cats.id AND missions.status <> ? AND missions.deleted_at IS NULL)", "COMPLETED").
+		Where("NOT EXISTS (SELECT 1 FROM leaves WHERE leaves.cat_id = cats.id AND leaves.starts_at <= ? AND leaves.ends_at > ?)", now, now).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// CountTargetsCompletedSince counts COMPLETED targets by their completion time.
+func (r *repository) CountTargetsCompletedSince(ctx context.Context, since time.Time) (int64, error) {
+	ctx, span := tracing.Start(ctx, "kpi.Repository.CountTargetsCompletedSince")
+	defer span.End()
+
+	var count int64
+	if err := r.db.WithContext(ctx).Table("targets").
+		Where("status = ? AND completed_at >= ? AND deleted_at IS NULL", "COMPLETED", since).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// AverageMissionDuration averages the lifetime of completed missions in Postgres.
+func (r *repository) AverageMissionDuration(ctx context.Context) (time.Duration, error) {
+	ctx, span := tracing.Start(ctx, "kpi.Repository.AverageMissionDuration")
+	defer span.End()
+
+	var seconds float64
+	if err := r.db.WithContext(ctx).Table("missions").
+		Select("COALESCE(AVG(EXTRACT(EPOCH FROM completed_at - created_at)), 0)").
+		Where("status = ? AND completed_at IS NOT NULL AND deleted_at IS NULL", "COMPLETED").
+		Scan(&seconds).Error; err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
@@ -2,14 +2,58 @@ package mission
 
 import (
 	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/genryusaishigikuni/spy_cats/internal/cat"
+	"github.com/genryusaishigikuni/spy_cats/internal/target"
 )
 
 // Mission model includes references to CatID, plus a CompletedAt if the mission is done.
+// Cats cannot be removed from under a mission; deleting a mission cascades to its targets.
 type Mission struct {
-	ID          uint       `gorm:"primaryKey"`
-	CatID       uint       // which cat is assigned
-	Status      string     // "ONGOING" or "COMPLETED"
-	CompletedAt *time.Time // null if not completed
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	ID             uint            `gorm:"primaryKey"`
+	CatID          *uint           `gorm:"index"` // which cat is assigned, null if unassigned
+	Cat            *cat.Cat        `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT" json:",omitempty"`
+	Targets        []target.Target `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:",omitempty"`
+	Status         string          // "DRAFT", "ONGOING", "SUSPENDED" or "COMPLETED"
+	Priority       string          // "LOW", "NORMAL", "HIGH" or "CRITICAL"
+	Difficulty     int             // 1 (routine) to 5 (extreme)
+	RequiredSkills []string        `gorm:"serializer:json"` // skills a cat needs for this mission
+	StartAt        *time.Time      // null if the mission starts immediately
+	DueAt          *time.Time      `gorm:"index"` // null if the mission has no deadline
+	OverdueAt      *time.Time      // set by the scheduler once the deadline has been missed
+	CompletedAt    *time.Time      // null if not completed
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Version        uint           `gorm:"not null;default:1"`
+	DeletedAt      gorm.DeletedAt `gorm:"index"` // soft delete, cascades to targets and notes
+}
+
+// AssignedCatID returns the assigned cat's ID, or 0 if the mission is unassigned.
+func (m *Mission) AssignedCatID() uint {
+	if m.CatID == nil {
+		return 0
+	}
+	return *m.CatID
+}
+
+// NewMission describes a mission to be created.
+type NewMission struct {
+	CatID          uint // 0 leaves the mission unassigned
+	TargetNames    []string
+	Targets        []NewTarget // created along with TargetNames
+	Draft          bool        // drafts stay unassigned until activated
+	StartAt        *time.Time
+	DueAt          *time.Time
+	Priority       string
+	Difficulty     int
+	RequiredSkills []string
+}
+
+// ListFilter narrows a listing of missions.
+type ListFilter struct {
+	IncludeDeleted bool
+	Status         string // "" for every status
+	Limit          int    // 0 for no limit
 }
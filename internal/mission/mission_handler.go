@@ -1,10 +1,16 @@
 package mission
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/genryusaishigikuni/spy_cats/internal/dossier"
+	"github.com/genryusaishigikuni/spy_cats/pkg/auth"
+	"github.com/genryusaishigikuni/spy_cats/pkg/etag"
 )
 
 // Handler for the mission domain
@@ -16,42 +22,126 @@ func NewHandler(s Service) *Handler {
 	return &Handler{service: s}
 }
 
-func (h *Handler) RegisterRoutes(r *gin.Engine) {
+func (h *Handler) RegisterRoutes(r gin.IRouter) {
 	missionGroup := r.Group("/missions")
 	{
-		missionGroup.POST("", h.createMission)       // POST /missions
-		missionGroup.GET("", h.listMissions)         // GET /missions
-		missionGroup.GET("/:id", h.getMissionByID)   // GET /missions/:id
-		missionGroup.DELETE("/:id", h.deleteMission) // DELETE /missions/:id
+		missionGroup.POST("", h.createMission)     // POST /missions
+		missionGroup.GET("", h.listMissions)       // GET /missions?overdue=true&include_deleted=true
+		missionGroup.GET("/:id", h.getMissionByID) // GET /missions/:id
+		missionGroup.GET("/:id/recommended-cats", h.recommendCats)
+		missionGroup.DELETE("/:id", etag.IfMatch(h.missionVersion), h.deleteMission) // DELETE /missions/:id
+		missionGroup.POST("/:id/restore", h.restoreMission)                          // POST /missions/:id/restore
 
 		// Assign cat to an existing mission
-		missionGroup.PATCH("/:id/assign-cat/:catId", h.assignCat)
-		missionGroup.PATCH("/:id/complete", h.markMissionComplete)
+		missionGroup.PATCH("/:id/assign-cat/:catId", etag.IfMatch(h.missionVersion), h.assignCat)
+		missionGroup.PATCH("/:id/complete", etag.IfMatch(h.missionVersion), h.markMissionComplete)
+		missionGroup.PATCH("/:id/schedule", etag.IfMatch(h.missionVersion), h.scheduleMission)
 		missionGroup.POST("/:id/targets", h.addTarget)
+
+		// Supervised override of a completed mission
+		missionGroup.POST("/:id/reopen", auth.RequireRole(auth.RoleSupervisor), h.reopenMission)
+
+		// Templates, cloning and drafts
+		missionGroup.POST("/from-template/:id", h.createFromTemplate) // POST /missions/from-template/:id
+		missionGroup.POST("/:id/clone", h.cloneMission)               // POST /missions/:id/clone
+		missionGroup.POST("/:id/activate", h.activateMission)         // POST /missions/:id/activate
+	}
+
+	templateGroup := r.Group("/mission-templates")
+	{
+		templateGroup.POST("", h.createTemplate)                                        // POST /mission-templates
+		templateGroup.GET("", h.listTemplates)                                          // GET /mission-templates
+		templateGroup.GET("/:id", h.getTemplate)                                        // GET /mission-templates/:id
+		templateGroup.PUT("/:id", etag.IfMatch(h.templateVersion), h.updateTemplate)    // PUT /mission-templates/:id
+		templateGroup.DELETE("/:id", etag.IfMatch(h.templateVersion), h.deleteTemplate) // DELETE /mission-templates/:id
 	}
 
 	// Mark a Target as complete
-	r.PATCH("/targets/:id/complete", h.completeTarget)
+	r.PATCH("/targets/:id/complete", etag.IfMatch(h.targetVersion), h.completeTarget)
+	r.PATCH("/targets/:id/deadline", etag.IfMatch(h.targetVersion), h.setTargetDeadline)
+	// Supervised override of a completed target
+	r.POST("/targets/:id/reopen", auth.RequireRole(auth.RoleSupervisor), h.reopenTarget)
+}
+
+// missionVersion looks up the version of the mission addressed by :id for If-Match checks.
+func (h *Handler) missionVersion(c *gin.Context) (interface{}, uint, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, 0, false
+	}
+	m, err := h.service.GetMissionByID(c.Request.Context(), uint(id))
+	if err != nil {
+		return nil, 0, false
+	}
+	return m, m.Version, true
+}
+
+// targetVersion looks up the version of the target addressed by :id.
+func (h *Handler) targetVersion(c *gin.Context) (interface{}, uint, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, 0, false
+	}
+	t, err := h.service.GetTarget(c.Request.Context(), uint(id))
+	if err != nil {
+		return nil, 0, false
+	}
+	return t, t.Version, true
+}
+
+// templateVersion looks up the version of the template addressed by :id.
+func (h *Handler) templateVersion(c *gin.Context) (interface{}, uint, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, 0, false
+	}
+	t, err := h.service.GetTemplate(c.Request.Context(), uint(id))
+	if err != nil {
+		return nil, 0, false
+	}
+	return t, t.Version, true
+}
+
+type reopenRequest struct {
+	Justification string `json:"justification"`
 }
 
 // createMission handles POST /missions
 func (h *Handler) createMission(c *gin.Context) {
 	var req struct {
-		CatID       uint     `json:"cat_id"`
-		TargetNames []string `json:"target_names"` // minimal example
+		CatID          uint       `json:"cat_id"`
+		TargetNames    []string   `json:"target_names"` // minimal example
+		StartAt        *time.Time `json:"start_at"`
+		DueAt          *time.Time `json:"due_at"`
+		Priority       string     `json:"priority"`
+		Difficulty     int        `json:"difficulty"`
+		RequiredSkills []string   `json:"required_skills"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	m, err := h.service.CreateMission(req.CatID, req.TargetNames)
+	m, suggestions, err := h.service.CreateMission(c.Request.Context(), NewMission{
+		CatID:          req.CatID,
+		TargetNames:    req.TargetNames,
+		StartAt:        req.StartAt,
+		DueAt:          req.DueAt,
+		Priority:       req.Priority,
+		Difficulty:     req.Difficulty,
+		RequiredSkills: req.RequiredSkills,
+	})
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, m)
+	// The mission's fields stay at the top level, as before suggestions were added
+	etag.Set(c, m.Version)
+	c.JSON(http.StatusCreated, struct {
+		*Mission
+		SuggestedDossiers map[uint][]dossier.Suggestion `json:"suggested_dossiers"`
+	}{m, suggestions})
 }
 
 // completeTarget handles PATCH /targets/:id/complete
@@ -63,16 +153,31 @@ func (h *Handler) completeTarget(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.CompleteTarget(uint(id)); err != nil {
+	if err := h.service.CompleteTarget(c.Request.Context(), uint(id)); err != nil {
+		if etag.Conflict(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Target completed"})
 }
 
-// listMissions handles GET /missions
+// listMissions handles GET /missions and GET /missions?overdue=true.
+// Deleted missions are only listed with ?include_deleted=true.
 func (h *Handler) listMissions(c *gin.Context) {
-	missions, err := h.service.ListMissions()
+	overdue, err := strconv.ParseBool(c.DefaultQuery("overdue", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid overdue filter"})
+		return
+	}
+
+	var missions []Mission
+	if overdue {
+		missions, err = h.service.ListOverdueMissions(c.Request.Context())
+	} else {
+		missions, err = h.service.ListMissions(c.Request.Context(), ListFilter{IncludeDeleted: c.Query("include_deleted") == "true"})
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -89,14 +194,32 @@ func (h *Handler) getMissionByID(c *gin.Context) {
 		return
 	}
 
-	m, err := h.service.GetMissionByID(uint(id))
+	m, err := h.service.GetMissionByID(c.Request.Context(), uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
+	etag.Set(c, m.Version)
 	c.JSON(http.StatusOK, m)
 }
 
+// recommendCats handles GET /missions/:id/recommended-cats
+func (h *Handler) recommendCats(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mission ID"})
+		return
+	}
+
+	recommendations, err := h.service.RecommendCats(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, recommendations)
+}
+
 // deleteMission handles DELETE /missions/:id
 func (h *Handler) deleteMission(c *gin.Context) {
 	idStr := c.Param("id")
@@ -106,14 +229,43 @@ func (h *Handler) deleteMission(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.DeleteMission(uint(id)); err != nil {
+	if err := h.service.DeleteMission(c.Request.Context(), uint(id)); err != nil {
+		if etag.Conflict(c, err) {
+			return
+		}
+		if errors.Is(err, ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		return
 	}
 	c.Status(http.StatusNoContent)
 }
 
-// assignCat handles PATCH /missions/:id/assign-cat/:catId
+// restoreMission handles POST /missions/:id/restore
+func (h *Handler) restoreMission(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mission ID"})
+		return
+	}
+
+	m, err := h.service.RestoreMission(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	etag.Set(c, m.Version)
+	c.JSON(http.StatusOK, m)
+}
+
+// assignCat handles PATCH /missions/:id/assign-cat/:catId[?override_skills=true]
 func (h *Handler) assignCat(c *gin.Context) {
 	missionIDStr := c.Param("id")
 	catIDStr := c.Param("catId")
@@ -129,7 +281,22 @@ func (h *Handler) assignCat(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.AssignCat(uint(missionID), uint(catID)); err != nil {
+	// Skipping the required-skills check is reserved for supervisors.
+	override, err := strconv.ParseBool(c.DefaultQuery("override_skills", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid override_skills flag"})
+		return
+	}
+	p, _ := auth.FromContext(c)
+	if override && p.Role != auth.RoleSupervisor {
+		c.JSON(http.StatusForbidden, gin.H{"error": "requires " + auth.RoleSupervisor + " role"})
+		return
+	}
+
+	if err := h.service.AssignCat(c.Request.Context(), uint(missionID), uint(catID), override, p.Name); err != nil {
+		if etag.Conflict(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -146,7 +313,10 @@ func (h *Handler) markMissionComplete(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.MarkMissionComplete(uint(id)); err != nil {
+	if err := h.service.MarkMissionComplete(c.Request.Context(), uint(id)); err != nil {
+		if etag.Conflict(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -163,19 +333,342 @@ func (h *Handler) addTarget(c *gin.Context) {
 	}
 
 	var req struct {
+		Name    string     `json:"name"`
+		Country string     `json:"country"`
+		Notes   string     `json:"notes"`
+		DueAt   *time.Time `json:"due_at"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	t, suggestions, err := h.service.AddTargetToMission(c.Request.Context(), uint(missionID), req.Name, req.Country, req.Notes, req.DueAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":            "Target added successfully",
+		"target":             t,
+		"suggested_dossiers": suggestions,
+	})
+}
+
+// scheduleMission handles PATCH /missions/:id/schedule
+func (h *Handler) scheduleMission(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mission ID"})
+		return
+	}
+
+	var req struct {
+		StartAt *time.Time `json:"start_at"`
+		DueAt   *time.Time `json:"due_at"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	m, err := h.service.ScheduleMission(c.Request.Context(), uint(id), req.StartAt, req.DueAt)
+	if err != nil {
+		if etag.Conflict(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	etag.Set(c, m.Version)
+	c.JSON(http.StatusOK, m)
+}
+
+// setTargetDeadline handles PATCH /targets/:id/deadline
+func (h *Handler) setTargetDeadline(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target ID"})
+		return
+	}
+
+	var req struct {
+		DueAt *time.Time `json:"due_at"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	t, err := h.service.SetTargetDeadline(c.Request.Context(), uint(id), req.DueAt)
+	if err != nil {
+		if etag.Conflict(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	etag.Set(c, t.Version)
+	c.JSON(http.StatusOK, t)
+}
+
+// reopenMission handles POST /missions/:id/reopen
+func (h *Handler) reopenMission(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mission ID"})
+		return
+	}
+
+	var req reopenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p, _ := auth.FromContext(c)
+	if err := h.service.ReopenMission(c.Request.Context(), uint(id), p.Name, req.Justification); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Mission reopened"})
+}
+
+// reopenTarget handles POST /targets/:id/reopen
+func (h *Handler) reopenTarget(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target ID"})
+		return
+	}
+
+	var req reopenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p, _ := auth.FromContext(c)
+	if err := h.service.ReopenTarget(c.Request.Context(), uint(id), p.Name, req.Justification); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Target reopened"})
+}
+
+type templateRequest struct {
+	Name    string `json:"name"`
+	Targets []struct {
 		Name    string `json:"name"`
 		Country string `json:"country"`
 		Notes   string `json:"notes"`
+	} `json:"targets"`
+	RequiredSkills []string `json:"required_skills"`
+	Priority       string   `json:"priority"`
+	Difficulty     int      `json:"difficulty"`
+	DurationHours  int      `json:"duration_hours"`
+}
+
+func (req templateRequest) template() *Template {
+	t := &Template{
+		Name:           req.Name,
+		RequiredSkills: req.RequiredSkills,
+		Priority:       req.Priority,
+		Difficulty:     req.Difficulty,
+		DurationHours:  req.DurationHours,
 	}
+	for _, tt := range req.Targets {
+		t.Targets = append(t.Targets, TemplateTarget{Name: tt.Name, Country: tt.Country, Notes: tt.Notes})
+	}
+	return t
+}
+
+// createTemplate handles POST /mission-templates
+func (h *Handler) createTemplate(c *gin.Context) {
+	var req templateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := h.service.AddTargetToMission(uint(missionID), req.Name, req.Country, req.Notes); err != nil {
+	t, err := h.service.CreateTemplate(c.Request.Context(), req.template())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	etag.Set(c, t.Version)
+	c.JSON(http.StatusCreated, t)
+}
+
+// listTemplates handles GET /mission-templates
+func (h *Handler) listTemplates(c *gin.Context) {
+	templates, err := h.service.ListTemplates(c.Request.Context())
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	c.JSON(http.StatusOK, templates)
+}
+
+// getTemplate handles GET /mission-templates/:id
+func (h *Handler) getTemplate(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	t, err := h.service.GetTemplate(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	etag.Set(c, t.Version)
+	c.JSON(http.StatusOK, t)
+}
+
+// updateTemplate handles PUT /mission-templates/:id
+func (h *Handler) updateTemplate(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	var req templateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	t, err := h.service.UpdateTemplate(c.Request.Context(), uint(id), req.template())
+	if err != nil {
+		if etag.Conflict(c, err) {
+			return
+		}
+		if errors.Is(err, ErrTemplateNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	etag.Set(c, t.Version)
+	c.JSON(http.StatusOK, t)
+}
+
+// deleteTemplate handles DELETE /mission-templates/:id
+func (h *Handler) deleteTemplate(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	if err := h.service.DeleteTemplate(c.Request.Context(), uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// createFromTemplate handles POST /missions/from-template/:id
+func (h *Handler) createFromTemplate(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return
+	}
+
+	var req struct {
+		CatID   uint       `json:"cat_id"`
+		StartAt *time.Time `json:"start_at"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Target added successfully"})
+	m, err := h.service.CreateFromTemplate(c.Request.Context(), uint(id), req.CatID, req.StartAt)
+	if err != nil {
+		if errors.Is(err, ErrTemplateNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	etag.Set(c, m.Version)
+	c.JSON(http.StatusCreated, m)
+}
+
+// cloneMission handles POST /missions/:id/clone
+func (h *Handler) cloneMission(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mission ID"})
+		return
+	}
+
+	p, _ := auth.FromContext(c)
+	m, err := h.service.CloneMission(c.Request.Context(), uint(id), p.Name)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	etag.Set(c, m.Version)
+	c.JSON(http.StatusCreated, m)
+}
+
+// activateMission handles POST /missions/:id/activate[?override_skills=true]
+func (h *Handler) activateMission(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mission ID"})
+		return
+	}
+
+	var req struct {
+		CatID uint `json:"cat_id"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Skipping the required-skills check is reserved for supervisors.
+	override, err := strconv.ParseBool(c.DefaultQuery("override_skills", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid override_skills flag"})
+		return
+	}
+	p, _ := auth.FromContext(c)
+	if override && p.Role != auth.RoleSupervisor {
+		c.JSON(http.StatusForbidden, gin.H{"error": "requires " + auth.RoleSupervisor + " role"})
+		return
+	}
+
+	m, err := h.service.ActivateMission(c.Request.Context(), uint(id), req.CatID, override, p.Name)
+	if err != nil {
+		if etag.Conflict(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	etag.Set(c, m.Version)
+	c.JSON(http.StatusOK, m)
 }
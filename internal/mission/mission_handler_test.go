@@ -0,0 +1,59 @@
+package mission
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/auth"
+)
+
+// reopenRecorder records the reopens that reach the service.
+type reopenRecorder struct {
+	Service
+	reopened []string
+}
+
+func (r *reopenRecorder) ReopenMission(_ context.Context, _ uint, actor, _ string) error {
+	r.reopened = append(r.reopened, actor)
+	return nil
+}
+
+func TestReopenMissionRequiresSupervisor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc := &reopenRecorder{}
+	r := gin.New()
+	r.Use(auth.Middleware(map[string]auth.Principal{
+		"handler-token":    {Name: "handler", Role: auth.RoleHandler},
+		"supervisor-token": {Name: "boss", Role: auth.RoleSupervisor},
+	}))
+	NewHandler(svc).RegisterRoutes(r)
+
+	for _, tc := range []struct {
+		token string
+		want  int
+	}{
+		{"", http.StatusUnauthorized},
+		{"handler-token", http.StatusForbidden},
+		{"supervisor-token", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/missions/1/reopen", strings.NewReader(`{"justification":"new evidence"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("token %q: status = %d, want %d", tc.token, w.Code, tc.want)
+		}
+	}
+
+	if len(svc.reopened) != 1 || svc.reopened[0] != "boss" {
+		t.Errorf("reopened by %v, want only [boss]", svc.reopened)
+	}
+}
@@ -0,0 +1,29 @@
+package mission
+
+import (
+	"context"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/logging"
+	"github.com/genryusaishigikuni/spy_cats/pkg/scheduler"
+)
+
+// overdueLockKey is the advisory lock key for the overdue mission job.
+const overdueLockKey int64 = 0x5ca7_0001
+
+// NewOverdueJob returns a scheduler job that flags and escalates overdue missions.
+func NewOverdueJob(s Service) scheduler.Job {
+	return scheduler.Job{
+		Name:    "mission-overdue",
+		LockKey: overdueLockKey,
+		Run: func(ctx context.Context) error {
+			flagged, err := s.ProcessOverdue(ctx)
+			if err != nil {
+				return err
+			}
+			if flagged > 0 {
+				logging.FromContext(ctx).InfoContext(ctx, "flagged overdue missions", "count", flagged)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,28 @@
+package mission
+
+import (
+	"context"
+	"time"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/logging"
+)
+
+// Notifier alerts handlers about missions that need attention.
+type Notifier interface {
+	MissionOverdue(ctx context.Context, m *Mission) error
+}
+
+// logNotifier writes notifications to the application log.
+type logNotifier struct{}
+
+// NewLogNotifier returns a Notifier that logs overdue missions.
+func NewLogNotifier() Notifier {
+	return logNotifier{}
+}
+
+// MissionOverdue logs that the mission missed its deadline.
+func (logNotifier) MissionOverdue(ctx context.Context, m *Mission) error {
+	logging.FromContext(ctx).WarnContext(ctx, "mission overdue",
+		"mission_id", m.ID, "cat_id", m.AssignedCatID(), "due_at", m.DueAt.Format(time.RFC3339), "status", m.Status)
+	return nil
+}
@@ -0,0 +1,214 @@
+package mission
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/genryusaishigikuni/spy_cats/internal/cat"
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
+)
+
+// Recommendation is a free cat ranked for a mission, with the breakdown of its score.
+type Recommendation struct {
+	CatID   uint
+	Name    string
+	Breed   string
+	Score   float64 // 0 to 100
+	Factors []ScoreFactor
+	// MissingSkills lists required skills the cat lacks; such cats rank last.
+	MissingSkills []string
+}
+
+// ScoreFactor explains one component of a recommendation score.
+type ScoreFactor struct {
+	Factor string
+	Points float64
+	Max    float64
+	Detail string
+}
+
+// priorityWeights scales how much salary cost matters: the more urgent the
+// mission, the less the agency cares about paying for the best cat.
+var priorityWeights = map[string]float64{
+	"LOW":      1.0,
+	"NORMAL":   0.75,
+	"HIGH":     0.5,
+	"CRITICAL": 0.25,
+}
+
+// Maximum points per scoring factor, before priority scaling of cost.
+const (
+	maxExperiencePoints = 35
+	maxBreedPoints      = 20
+	maxRecordPoints     = 25
+	maxCostPoints       = 20
+)
+
+// RecommendCats ranks every free cat for the mission. A cat is free when it is
+// available (active and not on leave) and has fewer ongoing missions than the
+// rules allow. The number of queries does not grow with the number of cats.
+func (s *service) RecommendCats(ctx context.Context, missionID uint) ([]Recommendation, error) {
+	ctx, span := tracing.Start(ctx, "mission.Service.RecommendCats")
+	defer span.End()
+
+	m, err := s.missionRepo.FindByID(ctx, missionID)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	if m.Status == "COMPLETED" {
+		return nil, ErrCompletedRecommend
+	}
+
+	cats, err := s.catRepo.List(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Everything scored per cat is loaded for all cats at once.
+	now := s.clock.Now()
+	leaves, err := s.catRepo.ListLeavesAt(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	onLeave := make(map[uint]bool, len(leaves))
+	for _, l := range leaves {
+		onLeave[l.CatID] = true
+	}
+
+	var candidates []cat.Cat
+	maxSalary := 0.0
+	for _, c := range cats {
+		maxSalary = math.Max(maxSalary, c.Salary)
+		if (c.Status == "" || c.Status == cat.StatusActive) && !onLeave[c.ID] {
+			candidates = append(candidates, c)
+		}
+	}
+	catIDs := make([]uint, len(candidates))
+	for i, c := range candidates {
+		catIDs[i] = c.ID
+	}
+
+	records, err := s.missionRepo.RecordsByCatIDs(ctx, catIDs)
+	if err != nil {
+		return nil, err
+	}
+	missing, err := s.skills.MissingSkillsByCat(ctx, catIDs, m.RequiredSkills)
+	if err != nil {
+		return nil, err
+	}
+
+	recommendations := make([]Recommendation, 0, len(candidates))
+	for _, c := range candidates {
+		record := records[c.ID]
+		if record.Ongoing >= s.rules.MaxConcurrentMissionsPerCat {
+			continue
+		}
+
+		factors := []ScoreFactor{
+			experienceFactor(c.YearsOfExperience, m.Difficulty),
+			s.breedFactor(ctx, c.Breed),
+			recordFactor(record),
+			costFactor(c.Salary, maxSalary, m.Priority),
+		}
+
+		score := 0.0
+		for _, f := range factors {
+			score += f.Points
+		}
+		recommendations = append(recommendations, Recommendation{
+			CatID:         c.ID,
+			Name:          c.Name,
+			Breed:         c.Breed,
+			Score:         round(score),
+			Factors:       factors,
+			MissingSkills: missing[c.ID],
+		})
+	}
+
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		a, b := recommendations[i], recommendations[j]
+		if (len(a.MissingSkills) == 0) != (len(b.MissingSkills) == 0) {
+			return len(a.MissingSkills) == 0
+		}
+		return a.Score > b.Score
+	})
+	return recommendations, nil
+}
+
+// experienceFactor rewards experience relative to what the mission's difficulty calls for:
+// two years per difficulty level earns full points.
+func experienceFactor(years, difficulty int) ScoreFactor {
+	if difficulty < 1 {
+		// Missions created before difficulty existed count as routine.
+		difficulty = 1
+	}
+	wanted := float64(difficulty * 2)
+	ratio := math.Min(float64(years)/wanted, 1)
+	return ScoreFactor{
+		Factor: "experience",
+		Points: round(ratio * maxExperiencePoints),
+		Max:    maxExperiencePoints,
+		Detail: fmt.Sprintf("%d years against %.0f wanted for difficulty %d", years, wanted, difficulty),
+	}
+}
+
+// breedFactor averages the breed's intelligence, adaptability and energy traits.
+func (s *service) breedFactor(ctx context.Context, breedName string) ScoreFactor {
+	f := ScoreFactor{Factor: "breed", Max: maxBreedPoints}
+
+	breed, err := s.breeds.Find(ctx, breedName)
+	if err != nil {
+		f.Points = maxBreedPoints / 2
+		f.Detail = "breed traits unavailable, scored as average"
+		return f
+	}
+
+	avg := float64(breed.Intelligence+breed.Adaptability+breed.EnergyLevel) / 3
+	f.Points = round(avg / 5 * maxBreedPoints)
+	f.Detail = fmt.Sprintf("%s: intelligence %d, adaptability %d, energy %d",
+		breed.Name, breed.Intelligence, breed.Adaptability, breed.EnergyLevel)
+	return f
+}
+
+// recordFactor rewards the share of past missions the cat completed.
+// Cats without history are scored as average.
+func recordFactor(record CatRecord) ScoreFactor {
+	f := ScoreFactor{Factor: "completion_rate", Max: maxRecordPoints}
+	if record.Total == 0 {
+		f.Points = maxRecordPoints / 2
+		f.Detail = "no past missions, scored as average"
+		return f
+	}
+
+	rate := float64(record.Completed) / float64(record.Total)
+	f.Points = round(rate * maxRecordPoints)
+	f.Detail = fmt.Sprintf("completed %d of %d past missions", record.Completed, record.Total)
+	return f
+}
+
+// costFactor rewards cheaper cats, weighted down for urgent missions.
+func costFactor(salary, maxSalary float64, priority string) ScoreFactor {
+	weight, ok := priorityWeights[priority]
+	if !ok {
+		// Missions created before priority existed count as NORMAL.
+		priority = "NORMAL"
+		weight = priorityWeights[priority]
+	}
+	f := ScoreFactor{Factor: "salary_cost", Max: round(maxCostPoints * weight)}
+
+	savings := 1.0
+	if maxSalary > 0 {
+		savings = 1 - salary/maxSalary
+	}
+	f.Points = round(savings * maxCostPoints * weight)
+	f.Detail = fmt.Sprintf("salary %.2f against agency maximum %.2f, weighted %.2f for %s priority",
+		salary, maxSalary, weight, priority)
+	return f
+}
+
+// round keeps scores readable at two decimals.
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
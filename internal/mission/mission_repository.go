@@ -1,14 +1,51 @@
 package mission
 
-import "gorm.io/gorm"
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/genryusaishigikuni/spy_cats/internal/cat"
+	"github.com/genryusaishigikuni/spy_cats/internal/history"
+	"github.com/genryusaishigikuni/spy_cats/internal/target"
+	"github.com/genryusaishigikuni/spy_cats/pkg/optimistic"
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
+)
 
 type Repository interface {
-	Create(m *Mission) error
-	FindByID(id uint) (*Mission, error)
-	Update(m *Mission) error
-	Delete(id uint) error
-	List() ([]Mission, error)
-	FindOngoingByCatID(catID uint) (*Mission, error)
+	Create(ctx context.Context, m *Mission) error
+	FindByID(ctx context.Context, id uint) (*Mission, error)
+	FindByIDs(ctx context.Context, ids []uint) ([]Mission, error)
+	Update(ctx context.Context, m *Mission) error
+	Delete(ctx context.Context, m *Mission) error
+	Restore(ctx context.Context, id uint) (*Mission, error)
+	List(ctx context.Context, filter ListFilter) ([]Mission, error)
+	CountOngoingByCatID(ctx context.Context, catID uint) (int64, error)
+	FindOverdue(ctx context.Context, now time.Time) ([]Mission, error)
+	ListByCatIDs(ctx context.Context, catIDs []uint) ([]Mission, error)
+	// RecordsByCatIDs counts the missions of each cat; cats without any are left out.
+	RecordsByCatIDs(ctx context.Context, catIDs []uint) (map[uint]CatRecord, error)
+	ListCompletedBetween(ctx context.Context, from, to time.Time) ([]Mission, error)
+
+	CreateTemplate(ctx context.Context, t *Template) error
+	FindTemplate(ctx context.Context, id uint) (*Template, error)
+	ListTemplates(ctx context.Context) ([]Template, error)
+	UpdateTemplate(ctx context.Context, t *Template) error
+	DeleteTemplate(ctx context.Context, id uint) error
+
+	// Transaction runs fn with repositories bound to a database transaction,
+	// rolled back if fn fails.
+	Transaction(ctx context.Context, fn func(tx Tx) error) error
+}
+
+// Tx holds the repositories a multi-step change writes through, all bound to
+// the same transaction.
+type Tx struct {
+	Missions Repository
+	Cats     cat.Repository
+	Targets  target.Repository
+	History  history.Repository
 }
 
 type repository struct {
@@ -20,45 +57,277 @@ func NewRepository(db *gorm.DB) Repository {
 }
 
 // Create inserts a new Mission record.
-func (r *repository) Create(m *Mission) error {
-	return r.db.Create(m).Error
+func (r *repository) Create(ctx context.Context, m *Mission) error {
+	ctx, span := tracing.Start(ctx, "mission.Repository.Create")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Create(m).Error
 }
 
 // FindByID retrieves a Mission by its primary key (ID).
-func (r *repository) FindByID(id uint) (*Mission, error) {
+func (r *repository) FindByID(ctx context.Context, id uint) (*Mission, error) {
+	ctx, span := tracing.Start(ctx, "mission.Repository.FindByID")
+	defer span.End()
+
 	var mission Mission
-	if err := r.db.First(&mission, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).First(&mission, id).Error; err != nil {
 		return nil, err
 	}
 	return &mission, nil
 }
 
-// Update applies changes to an existing Mission record.
-func (r *repository) Update(m *Mission) error {
-	return r.db.Save(m).Error
+// FindByIDs retrieves the missions with the given IDs, in no particular order.
+func (r *repository) FindByIDs(ctx context.Context, ids []uint) ([]Mission, error) {
+	ctx, span := tracing.Start(ctx, "mission.Repository.FindByIDs")
+	defer span.End()
+
+	var missions []Mission
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&missions).Error; err != nil {
+		return nil, err
+	}
+	return missions, nil
+}
+
+// Update applies changes to an existing Mission record, failing with
+// optimistic.ErrConflict if it was modified since it was read.
+func (r *repository) Update(ctx context.Context, m *Mission) error {
+	ctx, span := tracing.Start(ctx, "mission.Repository.Update")
+	defer span.End()
+
+	return optimistic.Update(r.db.WithContext(ctx), m, &m.Version)
 }
 
-// Delete removes a Mission by its ID.
-func (r *repository) Delete(id uint) error {
-	return r.db.Delete(&Mission{}, id).Error
+// Delete soft-deletes a Mission, cascading to its targets and their notes. It
+// returns optimistic.ErrConflict if the mission was modified since it was read.
+// All rows share the same deletion time so that Restore brings back exactly them.
+func (r *repository) Delete(ctx context.Context, m *Mission) error {
+	ctx, span := tracing.Start(ctx, "mission.Repository.Delete")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := tx.NowFunc()
+		m.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+		if err := optimistic.Update(tx, m, &m.Version); err != nil {
+			m.DeletedAt = gorm.DeletedAt{}
+			return err
+		}
+		targetIDs := tx.Table("targets").Select("id").Where("mission_id = ?", m.ID)
+		if err := tx.Table("notes").
+			Where("target_id IN (?) AND deleted_at IS NULL", targetIDs).
+			Update("deleted_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&target.Target{}).
+			Where("mission_id = ?", m.ID).
+			Update("deleted_at", now).Error
+	})
+}
+
+// Restore undoes Delete, bringing back the targets and notes deleted with the mission.
+func (r *repository) Restore(ctx context.Context, id uint) (*Mission, error) {
+	ctx, span := tracing.Start(ctx, "mission.Repository.Restore")
+	defer span.End()
+
+	var mission Mission
+	if err := r.db.WithContext(ctx).Unscoped().First(&mission, id).Error; err != nil {
+		return nil, err
+	}
+	if !mission.DeletedAt.Valid {
+		return nil, ErrNotDeleted
+	}
+
+	deletedAt := mission.DeletedAt.Time
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		targetIDs := tx.Table("targets").Select("id").Where("mission_id = ? AND deleted_at = ?", id, deletedAt)
+		restored := map[string]interface{}{"deleted_at": nil, "version": optimistic.Bump}
+		if err := tx.Table("notes").
+			Where("target_id IN (?) AND deleted_at = ?", targetIDs, deletedAt).
+			Updates(restored).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&target.Target{}).
+			Where("mission_id = ? AND deleted_at = ?", id, deletedAt).
+			Updates(restored).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&Mission{}).Where("id = ?", id).Updates(restored).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	mission.DeletedAt = gorm.DeletedAt{}
+	mission.Version++
+	return &mission, nil
 }
 
-// List returns all missions.
-func (r *repository) List() ([]Mission, error) {
+// List returns the missions matching filter in ID order.
+func (r *repository) List(ctx context.Context, filter ListFilter) ([]Mission, error) {
+	ctx, span := tracing.Start(ctx, "mission.Repository.List")
+	defer span.End()
+
 	var missions []Mission
-	if err := r.db.Find(&missions).Error; err != nil {
+	query := r.db.WithContext(ctx).Order("id")
+	if filter.IncludeDeleted {
+		query = query.Unscoped()
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if err := query.Find(&missions).Error; err != nil {
 		return nil, err
 	}
 	return missions, nil
 }
 
-// FindOngoingByCatID checks if the cat has an ongoing mission (Status != 'COMPLETED').
-func (r *repository) FindOngoingByCatID(catID uint) (*Mission, error) {
-	var m Mission
-	if err := r.db.
+// CountOngoingByCatID counts the cat's missions that are not completed yet.
+func (r *repository) CountOngoingByCatID(ctx context.Context, catID uint) (int64, error) {
+	ctx, span := tracing.Start(ctx, "mission.Repository.CountOngoingByCatID")
+	defer span.End()
+
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&Mission{}).
 		Where("cat_id = ? AND status <> ?", catID, "COMPLETED").
-		First(&m).Error; err != nil {
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// FindOverdue returns missions whose deadline has passed but that are not completed yet.
+// Drafts have not started and are never overdue.
+func (r *repository) FindOverdue(ctx context.Context, now time.Time) ([]Mission, error) {
+	ctx, span := tracing.Start(ctx, "mission.Repository.FindOverdue")
+	defer span.End()
+
+	var missions []Mission
+	if err := r.db.WithContext(ctx).
+		Where("due_at IS NOT NULL AND due_at < ? AND status NOT IN ?", now, []string{"COMPLETED", "DRAFT"}).
+		Order("due_at").
+		Find(&missions).Error; err != nil {
 		return nil, err
 	}
-	return &m, nil
+	return missions, nil
+}
+
+// ListByCatIDs returns every mission ever assigned to any of the cats.
+func (r *repository) ListByCatIDs(ctx context.Context, catIDs []uint) ([]Mission, error) {
+	ctx, span := tracing.Start(ctx, "mission.Repository.ListByCatIDs")
+	defer span.End()
+
+	var missions []Mission
+	if err := r.db.WithContext(ctx).Where("cat_id IN ?", catIDs).Order("id").Find(&missions).Error; err != nil {
+		return nil, err
+	}
+	return missions, nil
+}
+
+// CatRecord counts the missions ever assigned to a cat.
+type CatRecord struct {
+	CatID     uint
+	Total     int
+	Completed int
+	Ongoing   int // not completed yet
+}
+
+// RecordsByCatIDs counts the missions of every cat in one grouped query.
+func (r *repository) RecordsByCatIDs(ctx context.Context, catIDs []uint) (map[uint]CatRecord, error) {
+	ctx, span := tracing.Start(ctx, "mission.Repository.RecordsByCatIDs")
+	defer span.End()
+
+	var rows []CatRecord
+	if err := r.db.WithContext(ctx).Model(&Mission{}).
+		Select("cat_id, COUNT(*) AS total, "+
+			"COUNT(*) FILTER (WHERE status = ?) AS completed, "+
+			"COUNT(*) FILTER (WHERE status <> ?) AS ongoing", "COMPLETED", "COMPLETED").
+		Where("cat_id IN ?", catIDs).
+		Group("cat_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	records := make(map[uint]CatRecord, len(rows))
+	for _, row := range rows {
+		records[row.CatID] = row
+	}
+	return records, nil
+}
+
+// ListCompletedBetween returns missions completed in [from, to).
+func (r *repository) ListCompletedBetween(ctx context.Context, from, to time.Time) ([]Mission, error) {
+	ctx, span := tracing.Start(ctx, "mission.Repository.ListCompletedBetween")
+	defer span.End()
+
+	var missions []Mission
+	if err := r.db.WithContext(ctx).
+		Where("status = ? AND completed_at >= ? AND completed_at < ?", "COMPLETED", from, to).
+		Find(&missions).Error; err != nil {
+		return nil, err
+	}
+	return missions, nil
+}
+
+// CreateTemplate inserts a new mission Template.
+func (r *repository) CreateTemplate(ctx context.Context, t *Template) error {
+	ctx, span := tracing.Start(ctx, "mission.Repository.CreateTemplate")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Create(t).Error
+}
+
+// FindTemplate retrieves a mission Template by its primary key (ID).
+func (r *repository) FindTemplate(ctx context.Context, id uint) (*Template, error) {
+	ctx, span := tracing.Start(ctx, "mission.Repository.FindTemplate")
+	defer span.End()
+
+	var t Template
+	if err := r.db.WithContext(ctx).First(&t, id).Error; err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ListTemplates returns all mission templates ordered by name.
+func (r *repository) ListTemplates(ctx context.Context) ([]Template, error) {
+	ctx, span := tracing.Start(ctx, "mission.Repository.ListTemplates")
+	defer span.End()
+
+	var templates []Template
+	if err := r.db.WithContext(ctx).Order("name").Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// UpdateTemplate applies changes to a mission Template, failing with
+// optimistic.ErrConflict if it was modified since it was read.
+func (r *repository) UpdateTemplate(ctx context.Context, t *Template) error {
+	ctx, span := tracing.Start(ctx, "mission.Repository.UpdateTemplate")
+	defer span.End()
+
+	return optimistic.Update(r.db.WithContext(ctx), t, &t.Version)
+}
+
+// DeleteTemplate removes a mission Template.
+func (r *repository) DeleteTemplate(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "mission.Repository.DeleteTemplate")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Delete(&Template{}, id).Error
+}
+
+// Transaction runs fn in a database transaction.
+func (r *repository) Transaction(ctx context.Context, fn func(tx Tx) error) error {
+	ctx, span := tracing.Start(ctx, "mission.Repository.Transaction")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(Tx{
+			Missions: NewRepository(tx),
+			Cats:     cat.NewRepository(tx, nil),
+			Targets:  target.NewRepository(tx),
+			History:  history.NewRepository(tx),
+		})
+	})
 }
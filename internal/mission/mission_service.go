@@ -1,111 +1,297 @@
 package mission
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/genryusaishigikuni/spy_cats/internal/cat"
+	"github.com/genryusaishigikuni/spy_cats/internal/dossier"
+	"github.com/genryusaishigikuni/spy_cats/internal/history"
+	"github.com/genryusaishigikuni/spy_cats/internal/skill"
 	"github.com/genryusaishigikuni/spy_cats/internal/target"
+	"github.com/genryusaishigikuni/spy_cats/pkg/clock"
+	"github.com/genryusaishigikuni/spy_cats/pkg/errcode"
+	"github.com/genryusaishigikuni/spy_cats/pkg/rules"
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
 	"gorm.io/gorm"
 )
 
-type Service interface {
-	CreateMission(catID uint, targetNames []string) (*Mission, error)
-	CompleteTarget(targetID uint) error
+// Escalation modes for missions that miss their deadline.
+const (
+	EscalateNotify  = "notify"  // notify handlers only
+	EscalateSuspend = "suspend" // notify handlers and suspend the mission
+)
 
-	ListMissions() ([]Mission, error)
-	GetMissionByID(id uint) (*Mission, error)
-	DeleteMission(id uint) error
-	MarkMissionComplete(missionID uint) error
-	AssignCat(missionID, catID uint) error
+// Errors returned by the mission service. Several refusals share a code, e.g.
+// every change a completed mission no longer allows is mission_completed.
+var (
+	ErrNotFound              = errcode.New("mission_not_found", "mission not found")
+	ErrNotDeleted            = errcode.New("mission_not_deleted", "mission is not deleted")
+	ErrDraftWithCat          = errcode.New("mission_draft", "a draft mission cannot have a cat; assign one when activating it")
+	ErrDraftAssign           = errcode.New("mission_draft", "activate the draft mission to assign a cat")
+	ErrDraftComplete         = errcode.New("mission_draft", "a draft mission cannot be completed")
+	ErrDraftTargets          = errcode.New("mission_draft", "targets of a draft mission cannot be completed")
+	ErrCompletedTarget       = errcode.New("mission_completed", "cannot add target to a completed mission")
+	ErrCompletedAssign       = errcode.New("mission_completed", "cannot assign a cat to a completed mission")
+	ErrCompletedSchedule     = errcode.New("mission_completed", "cannot reschedule a completed mission")
+	ErrCompletedRecommend    = errcode.New("mission_completed", "cannot recommend cats for a completed mission")
+	ErrNotCompleted          = errcode.New("mission_not_completed", "mission is not completed")
+	ErrAssigned              = errcode.New("mission_assigned", "cannot delete a mission that is assigned to a cat")
+	ErrTargetCount           = errcode.New("invalid_target_count", "mission has the wrong number of targets")
+	ErrTargetCompleted       = errcode.New("target_completed", "target is already completed")
+	ErrTargetDeadline        = errcode.New("target_completed", "cannot change the deadline of a completed target")
+	ErrTargetNotDone         = errcode.New("target_not_completed", "target is not completed")
+	ErrTargetDueLate         = errcode.New("target_due_after_mission", "target cannot be due after its mission")
+	ErrInvalidSchedule       = errcode.New("invalid_schedule", "mission due date must be after its start date")
+	ErrInvalidPriority       = errcode.New("invalid_priority", "priority must be one of LOW, NORMAL, HIGH or CRITICAL")
+	ErrInvalidDifficulty     = errcode.New("invalid_difficulty", "difficulty must be between 1 and 5")
+	ErrMissingSkills         = errcode.New("cat_missing_skills", "cat lacks required skills")
+	ErrCatAtCapacity         = errcode.New("cat_at_capacity", "this cat cannot take another mission")
+	ErrCatCannotReturn       = errcode.New("cat_cannot_return", "the assigned cat cannot take the mission back")
+	ErrNoJustification       = errcode.New("justification_required", "a justification is required to reopen a mission")
+	ErrNoTargetJustification = errcode.New("justification_required", "a justification is required to reopen a target")
+)
+
+type Service interface {
+	// CreateMission also returns, by target ID, existing dossiers the new targets may refer to.
+	CreateMission(ctx context.Context, req NewMission) (*Mission, map[uint][]dossier.Suggestion, error)
+	CompleteTarget(ctx context.Context, targetID uint) error
+
+	ListMissions(ctx context.Context, filter ListFilter) ([]Mission, error)
+	ListOverdueMissions(ctx context.Context) ([]Mission, error)
+	GetMissionByID(ctx context.Context, id uint) (*Mission, error)
+	// GetMissions and ListMissionsByCats load missions in batches; unknown IDs are left out.
+	GetMissions(ctx context.Context, ids []uint) ([]Mission, error)
+	ListMissionsByCats(ctx context.Context, catIDs []uint) ([]Mission, error)
+	GetTarget(ctx context.Context, targetID uint) (*target.Target, error)
+	DeleteMission(ctx context.Context, id uint) error
+	RestoreMission(ctx context.Context, id uint) (*Mission, error)
+	MarkMissionComplete(ctx context.Context, missionID uint) error
+	// AssignCat assigns a cat to a mission. Cats lacking the mission's required
+	// skills are rejected unless overrideSkills is set; overrides are recorded for actor.
+	AssignCat(ctx context.Context, missionID, catID uint, overrideSkills bool, actor string) error
 
 	// AddTargetToMission New: Add a target to an existing mission.
-	AddTargetToMission(missionID uint, name, country, notes string) error
+	// It also returns existing dossiers that the new target may refer to.
+	AddTargetToMission(ctx context.Context, missionID uint, name, country, notes string, dueAt *time.Time) (*target.Target, []dossier.Suggestion, error)
+
+	// ScheduleMission and SetTargetDeadline change mission and target deadlines.
+	ScheduleMission(ctx context.Context, missionID uint, startAt, dueAt *time.Time) (*Mission, error)
+	SetTargetDeadline(ctx context.Context, targetID uint, dueAt *time.Time) (*target.Target, error)
+
+	// RecommendCats ranks free cats for a mission, best first.
+	RecommendCats(ctx context.Context, missionID uint) ([]Recommendation, error)
+
+	// ProcessOverdue flags missions past their deadline and escalates them.
+	// It returns the number of missions newly flagged.
+	ProcessOverdue(ctx context.Context) (int, error)
+
+	// ReopenTarget and ReopenMission are supervised overrides that undo a completion.
+	ReopenTarget(ctx context.Context, targetID uint, actor, justification string) error
+	ReopenMission(ctx context.Context, missionID uint, actor, justification string) error
+
+	// Mission templates and cloning.
+	CreateTemplate(ctx context.Context, t *Template) (*Template, error)
+	GetTemplate(ctx context.Context, id uint) (*Template, error)
+	ListTemplates(ctx context.Context) ([]Template, error)
+	UpdateTemplate(ctx context.Context, id uint, t *Template) (*Template, error)
+	DeleteTemplate(ctx context.Context, id uint) error
+	CreateFromTemplate(ctx context.Context, templateID, catID uint, startAt *time.Time) (*Mission, error)
+	CloneMission(ctx context.Context, missionID uint, actor string) (*Mission, error)
+	// ActivateMission starts a DRAFT mission, optionally assigning a cat to it.
+	ActivateMission(ctx context.Context, missionID, catID uint, overrideSkills bool, actor string) (*Mission, error)
 }
 
 type service struct {
 	missionRepo Repository
 	catRepo     cat.Repository
 	targetRepo  target.Repository
+	historyRepo history.Repository
+	dossiers    dossier.Service
+	skills      skill.Service
+	breeds      cat.BreedCatalog
+	clock       clock.Clock
+	notifier    Notifier
+	escalation  string
+	rules       rules.Rules
 }
 
 func NewService(
 	mRepo Repository,
 	cRepo cat.Repository,
 	tRepo target.Repository,
+	hRepo history.Repository,
+	dService dossier.Service,
+	sService skill.Service,
+	breeds cat.BreedCatalog,
+	clk clock.Clock,
+	notifier Notifier,
+	escalation string,
+	rules rules.Rules,
 ) Service {
 	return &service{
 		missionRepo: mRepo,
 		catRepo:     cRepo,
 		targetRepo:  tRepo,
+		historyRepo: hRepo,
+		dossiers:    dService,
+		skills:      sService,
+		breeds:      breeds,
+		clock:       clk,
+		notifier:    notifier,
+		escalation:  escalation,
+		rules:       rules,
 	}
 }
 
-// CreateMission creates a new mission, ensuring the cat is valid and doesn't have an ongoing mission, plus 1–3 targets.
-func (s *service) CreateMission(catID uint, targetNames []string) (*Mission, error) {
-	// Validate the cat
-	_, err := s.catRepo.FindByID(catID)
-	if err != nil {
-		return nil, errors.New("cat not found")
+// CreateMission creates a new mission with as many targets as the rules allow
+// (1–3 by default). When a cat is given it must exist and have room for another
+// ongoing mission; otherwise the mission is left
+// unassigned until AssignCat is called. Draft missions are always unassigned.
+func (s *service) CreateMission(ctx context.Context, req NewMission) (*Mission, map[uint][]dossier.Suggestion, error) {
+	ctx, span := tracing.Start(ctx, "mission.Service.CreateMission")
+	defer span.End()
+
+	return s.createMission(ctx, req, nil)
+}
+
+// createMission implements CreateMission. The mission and its targets are
+// stored in one transaction, together with whatever record writes within it,
+// and the cat's row is locked while its availability and capacity are checked,
+// as in reopenMission.
+func (s *service) createMission(ctx context.Context, req NewMission, record func(tx Tx, m *Mission) error) (*Mission, map[uint][]dossier.Suggestion, error) {
+	if req.Draft && req.CatID != 0 {
+		return nil, nil, ErrDraftWithCat
 	}
 
-	// Check if the cat already has an ongoing mission
-	_, err = s.missionRepo.FindOngoingByCatID(catID)
-	if err == nil {
-		return nil, errors.New("this cat already has an ongoing mission")
-	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, err
+	// Validate the number of targets
+	targets := req.Targets
+	for _, name := range req.TargetNames {
+		targets = append(targets, NewTarget{Name: name})
+	}
+	if err := s.checkTargetCount(len(targets)); err != nil {
+		return nil, nil, err
+	}
+
+	if err := validateSchedule(req.StartAt, req.DueAt); err != nil {
+		return nil, nil, err
 	}
 
-	// Validate targets (1 to 3)
-	if len(targetNames) < 1 || len(targetNames) > 3 {
-		return nil, errors.New("mission must have between 1 and 3 targets")
+	priority, difficulty, err := normalizeRating(req.Priority, req.Difficulty)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	requiredSkills, err := s.skills.NormalizeNames(ctx, req.RequiredSkills)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Create mission
+	status := "ONGOING"
+	if req.Draft {
+		status = "DRAFT"
+	}
 	m := &Mission{
-		CatID:  catID,
-		Status: "ONGOING",
+		Status:         status,
+		Priority:       priority,
+		Difficulty:     difficulty,
+		RequiredSkills: requiredSkills,
+		StartAt:        req.StartAt,
+		DueAt:          req.DueAt,
 	}
-	if err := s.missionRepo.Create(m); err != nil {
-		return nil, err
+	if req.CatID != 0 {
+		m.CatID = &req.CatID
 	}
 
-	// Create targets for this mission using the target repository directly.
-	for _, tName := range targetNames {
-		t := &target.Target{
-			MissionID: m.ID,
-			Name:      tName,
-			Status:    "ONGOING",
+	err = s.missionRepo.Transaction(ctx, func(tx Tx) error {
+		if req.CatID != 0 {
+			c, err := tx.Cats.FindByIDForUpdate(ctx, req.CatID)
+			if err != nil {
+				return cat.ErrNotFound
+			}
+
+			// The cat must be available when the mission starts
+			at := s.clock.Now()
+			if req.StartAt != nil && req.StartAt.After(at) {
+				at = *req.StartAt
+			}
+			if err := cat.CheckAvailable(ctx, tx.Cats, c, at); err != nil {
+				return err
+			}
+			if err := s.checkCatCapacity(ctx, tx.Missions, c.ID); err != nil {
+				return err
+			}
+			if err := s.checkSkills(ctx, c.ID, requiredSkills); err != nil {
+				return err
+			}
 		}
-		if err := s.targetRepo.Create(t); err != nil {
-			return nil, err
+
+		if err := tx.Missions.Create(ctx, m); err != nil {
+			return err
+		}
+		for _, nt := range targets {
+			t := target.Target{
+				MissionID: m.ID,
+				Name:      nt.Name,
+				Country:   nt.Country,
+				Notes:     nt.Notes,
+				Status:    "ONGOING",
+			}
+			if err := tx.Targets.Create(ctx, &t); err != nil {
+				return err
+			}
+			m.Targets = append(m.Targets, t)
+		}
+
+		if record != nil {
+			return record(tx, m)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return m, nil
+	// Suggest dossiers for each target like AddTargetToMission does. A failed
+	// lookup must not undo the mission creation.
+	suggestions := make(map[uint][]dossier.Suggestion)
+	for _, t := range m.Targets {
+		if found, err := s.dossiers.Suggest(ctx, t.Name); err == nil && len(found) > 0 {
+			suggestions[t.ID] = found
+		}
+	}
+	return m, suggestions, nil
 }
 
 // AddTargetToMission adds a new target to an existing mission,
-// ensuring the mission is ongoing and does not exceed 3 targets.
-func (s *service) AddTargetToMission(missionID uint, name, country, notes string) error {
+// ensuring the mission is ongoing and does not exceed the rules' target limit.
+// Dossiers resembling the target's name are returned as suggestions.
+func (s *service) AddTargetToMission(ctx context.Context, missionID uint, name, country, notes string, dueAt *time.Time) (*target.Target, []dossier.Suggestion, error) {
+	ctx, span := tracing.Start(ctx, "mission.Service.AddTargetToMission")
+	defer span.End()
+
 	// 1) Check mission exists and is not completed
-	m, err := s.missionRepo.FindByID(missionID)
+	m, err := s.missionRepo.FindByID(ctx, missionID)
 	if err != nil {
-		return fmt.Errorf("mission not found: %w", err)
+		return nil, nil, ErrNotFound
 	}
 	if m.Status == "COMPLETED" {
-		return errors.New("cannot add target to a completed mission")
+		return nil, nil, ErrCompletedTarget
 	}
 
 	// 2) Check number of existing targets
-	existingTargets, err := s.targetRepo.FindByMissionID(missionID)
+	existingTargets, err := s.targetRepo.FindByMissionID(ctx, missionID)
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		return err
+		return nil, nil, err
 	}
-	if len(existingTargets) >= 3 {
-		return errors.New("cannot add more than 3 targets to a mission")
+	if len(existingTargets) >= s.rules.MaxTargetsPerMission {
+		return nil, nil, fmt.Errorf("%w: at most %d", target.ErrTargetLimit, s.rules.MaxTargetsPerMission)
+	}
+	if err := validateTargetDeadline(m, dueAt); err != nil {
+		return nil, nil, err
 	}
 
 	// 3) Create the new target with additional fields Country and Notes
@@ -115,34 +301,54 @@ func (s *service) AddTargetToMission(missionID uint, name, country, notes string
 		Country:   country,
 		Notes:     notes,
 		Status:    "ONGOING",
+		DueAt:     dueAt,
+	}
+	if err := s.targetRepo.Create(ctx, t); err != nil {
+		return nil, nil, err
 	}
-	return s.targetRepo.Create(t)
+
+	// 4) Suggest dossiers; a failed lookup must not undo the target creation.
+	suggestions, err := s.dossiers.Suggest(ctx, name)
+	if err != nil {
+		suggestions = nil
+	}
+	return t, suggestions, nil
 }
 
 // CompleteTarget marks a target as completed and, if all targets in the mission are completed,
 // marks the mission as completed as well.
-func (s *service) CompleteTarget(targetID uint) error {
+func (s *service) CompleteTarget(ctx context.Context, targetID uint) error {
+	ctx, span := tracing.Start(ctx, "mission.Service.CompleteTarget")
+	defer span.End()
+
 	// Fetch the target
-	t, err := s.targetRepo.FindByID(targetID)
+	t, err := s.targetRepo.FindByID(ctx, targetID)
 	if err != nil {
 		return err
 	}
 
 	if t.Status == "COMPLETED" {
-		return errors.New("target is already completed")
+		return ErrTargetCompleted
+	}
+	m, err := s.missionRepo.FindByID(ctx, t.MissionID)
+	if err != nil {
+		return ErrNotFound
+	}
+	if m.Status == "DRAFT" {
+		return ErrDraftTargets
 	}
 
 	// Mark the target as completed
 	t.Status = "COMPLETED"
-	now := time.Now()
+	now := s.clock.Now()
 	t.CompletedAt = &now
 
-	if err := s.targetRepo.Update(t); err != nil {
+	if err := s.targetRepo.Update(ctx, t); err != nil {
 		return err
 	}
 
 	// Check if all targets for this mission are completed
-	targets, err := s.targetRepo.FindByMissionID(t.MissionID)
+	targets, err := s.targetRepo.FindByMissionID(ctx, t.MissionID)
 	if err != nil {
 		return err
 	}
@@ -156,7 +362,7 @@ func (s *service) CompleteTarget(targetID uint) error {
 	}
 
 	if allDone {
-		if err := s.markMissionCompleted(t.MissionID); err != nil {
+		if err := s.markMissionCompleted(ctx, t.MissionID); err != nil {
 			return err
 		}
 	}
@@ -164,77 +370,479 @@ func (s *service) CompleteTarget(targetID uint) error {
 	return nil
 }
 
-// ListMissions returns all missions.
-func (s *service) ListMissions() ([]Mission, error) {
-	return s.missionRepo.List()
+// ListMissions returns the missions matching filter.
+func (s *service) ListMissions(ctx context.Context, filter ListFilter) ([]Mission, error) {
+	ctx, span := tracing.Start(ctx, "mission.Service.ListMissions")
+	defer span.End()
+
+	return s.missionRepo.List(ctx, filter)
+}
+
+// ListOverdueMissions returns missions past their deadline that are not completed.
+func (s *service) ListOverdueMissions(ctx context.Context) ([]Mission, error) {
+	ctx, span := tracing.Start(ctx, "mission.Service.ListOverdueMissions")
+	defer span.End()
+
+	return s.missionRepo.FindOverdue(ctx, s.clock.Now())
 }
 
 // GetMissionByID returns a single mission by ID.
-func (s *service) GetMissionByID(id uint) (*Mission, error) {
-	m, err := s.missionRepo.FindByID(id)
+func (s *service) GetMissionByID(ctx context.Context, id uint) (*Mission, error) {
+	ctx, span := tracing.Start(ctx, "mission.Service.GetMissionByID")
+	defer span.End()
+
+	m, err := s.missionRepo.FindByID(ctx, id)
 	if err != nil {
-		return nil, errors.New("mission not found")
+		return nil, ErrNotFound
 	}
 	return m, nil
 }
 
+// GetMissions returns the missions with the given IDs.
+func (s *service) GetMissions(ctx context.Context, ids []uint) ([]Mission, error) {
+	ctx, span := tracing.Start(ctx, "mission.Service.GetMissions")
+	defer span.End()
+
+	return s.missionRepo.FindByIDs(ctx, ids)
+}
+
+// ListMissionsByCats returns every mission ever assigned to any of the cats.
+func (s *service) ListMissionsByCats(ctx context.Context, catIDs []uint) ([]Mission, error) {
+	ctx, span := tracing.Start(ctx, "mission.Service.ListMissionsByCats")
+	defer span.End()
+
+	return s.missionRepo.ListByCatIDs(ctx, catIDs)
+}
+
+// GetTarget returns a single target by ID.
+func (s *service) GetTarget(ctx context.Context, targetID uint) (*target.Target, error) {
+	ctx, span := tracing.Start(ctx, "mission.Service.GetTarget")
+	defer span.End()
+
+	t, err := s.targetRepo.FindByID(ctx, targetID)
+	if err != nil {
+		return nil, target.ErrNotFound
+	}
+	return t, nil
+}
+
 // DeleteMission removes a mission if it isn't assigned to a cat.
-func (s *service) DeleteMission(id uint) error {
-	m, err := s.missionRepo.FindByID(id)
+func (s *service) DeleteMission(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "mission.Service.DeleteMission")
+	defer span.End()
+
+	m, err := s.missionRepo.FindByID(ctx, id)
 	if err != nil {
-		return errors.New("mission not found")
+		return ErrNotFound
 	}
 
 	// If a cat is assigned, forbid deletion.
-	if m.CatID != 0 {
-		return errors.New("cannot delete a mission that is assigned to a cat")
+	if m.CatID != nil {
+		return ErrAssigned
 	}
 
-	return s.missionRepo.Delete(id)
+	return s.missionRepo.Delete(ctx, m)
+}
+
+// RestoreMission brings back a deleted mission along with its targets and notes.
+func (s *service) RestoreMission(ctx context.Context, id uint) (*Mission, error) {
+	ctx, span := tracing.Start(ctx, "mission.Service.RestoreMission")
+	defer span.End()
+
+	m, err := s.missionRepo.Restore(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return m, err
 }
 
 // MarkMissionComplete forcibly completes a mission.
-func (s *service) MarkMissionComplete(missionID uint) error {
-	return s.markMissionCompleted(missionID)
+func (s *service) MarkMissionComplete(ctx context.Context, missionID uint) error {
+	ctx, span := tracing.Start(ctx, "mission.Service.MarkMissionComplete")
+	defer span.End()
+
+	return s.markMissionCompleted(ctx, missionID)
 }
 
 // AssignCat assigns a cat to an existing mission if valid.
-func (s *service) AssignCat(missionID, catID uint) error {
-	m, err := s.missionRepo.FindByID(missionID)
+func (s *service) AssignCat(ctx context.Context, missionID, catID uint, overrideSkills bool, actor string) error {
+	ctx, span := tracing.Start(ctx, "mission.Service.AssignCat")
+	defer span.End()
+
+	m, err := s.missionRepo.FindByID(ctx, missionID)
 	if err != nil {
-		return errors.New("mission not found")
+		return ErrNotFound
 	}
 	if m.Status == "COMPLETED" {
-		return errors.New("cannot assign a cat to a completed mission")
+		return ErrCompletedAssign
+	}
+	if m.Status == "DRAFT" {
+		return ErrDraftAssign
 	}
 
-	_, err = s.catRepo.FindByID(catID)
+	skillErr, err := s.checkAssignable(ctx, m, catID, overrideSkills)
 	if err != nil {
-		return errors.New("cat not found")
+		return err
+	}
+
+	m.CatID = &catID
+	if err := s.missionRepo.Update(ctx, m); err != nil {
+		return err
+	}
+
+	if skillErr != nil {
+		return s.recordSkillOverride(ctx, m.ID, catID, actor, skillErr)
+	}
+	return nil
+}
+
+// checkAssignable checks that a cat exists, is available and free, and has the
+// mission's required skills. Missing skills are returned as skillErr when
+// overrideSkills is set, so the caller can record the override.
+func (s *service) checkAssignable(ctx context.Context, m *Mission, catID uint, overrideSkills bool) (skillErr, err error) {
+	c, err := s.catRepo.FindByID(ctx, catID)
+	if err != nil {
+		return nil, cat.ErrNotFound
+	}
+	if err := cat.CheckAvailable(ctx, s.catRepo, c, s.clock.Now()); err != nil {
+		return nil, err
 	}
 
 	// Check if the cat is free.
-	_, err = s.missionRepo.FindOngoingByCatID(catID)
-	if err == nil {
-		return errors.New("this cat is already on another ongoing mission")
-	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+	if err := s.checkCatCapacity(ctx, s.missionRepo, catID); err != nil {
+		return nil, err
+	}
+
+	// Check the cat has the required skills, unless a supervisor overrides it.
+	skillErr = s.checkSkills(ctx, catID, m.RequiredSkills)
+	if skillErr != nil && !overrideSkills {
+		return nil, skillErr
+	}
+	return skillErr, nil
+}
+
+// recordSkillOverride records in history that a cat lacking skills was assigned.
+func (s *service) recordSkillOverride(ctx context.Context, missionID, catID uint, actor string, skillErr error) error {
+	return s.historyRepo.Create(ctx, &history.Entry{
+		EntityType: history.EntityMission,
+		EntityID:   missionID,
+		Action:     "SKILL_OVERRIDE",
+		Actor:      actor,
+		Reason:     fmt.Sprintf("assigned cat %d: %v", catID, skillErr),
+	})
+}
+
+// checkSkills returns an error naming the required skills the cat lacks.
+func (s *service) checkSkills(ctx context.Context, catID uint, required []string) error {
+	missing, err := s.skills.MissingSkills(ctx, catID, required)
+	if err != nil {
 		return err
 	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrMissingSkills, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// ScheduleMission sets a mission's start and due dates. Moving the deadline clears
+// any earlier overdue flag so the scheduler can re-evaluate it, and resumes a
+// mission that was suspended for being overdue.
+func (s *service) ScheduleMission(ctx context.Context, missionID uint, startAt, dueAt *time.Time) (*Mission, error) {
+	ctx, span := tracing.Start(ctx, "mission.Service.ScheduleMission")
+	defer span.End()
 
-	m.CatID = catID
-	return s.missionRepo.Update(m)
+	m, err := s.missionRepo.FindByID(ctx, missionID)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	if m.Status == "COMPLETED" {
+		return nil, ErrCompletedSchedule
+	}
+	if err := validateSchedule(startAt, dueAt); err != nil {
+		return nil, err
+	}
+
+	targets, err := s.targetRepo.FindByMissionID(ctx, missionID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range targets {
+		if dueAt != nil && t.DueAt != nil && t.DueAt.After(*dueAt) {
+			return nil, fmt.Errorf("%w: target %d is due after the new deadline", ErrTargetDueLate, t.ID)
+		}
+	}
+
+	m.StartAt = startAt
+	m.DueAt = dueAt
+	m.OverdueAt = nil
+	if m.Status == "SUSPENDED" {
+		m.Status = "ONGOING"
+	}
+	if err := s.missionRepo.Update(ctx, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SetTargetDeadline sets or clears a target's own deadline.
+func (s *service) SetTargetDeadline(ctx context.Context, targetID uint, dueAt *time.Time) (*target.Target, error) {
+	ctx, span := tracing.Start(ctx, "mission.Service.SetTargetDeadline")
+	defer span.End()
+
+	t, err := s.targetRepo.FindByID(ctx, targetID)
+	if err != nil {
+		return nil, target.ErrNotFound
+	}
+	if t.Status == "COMPLETED" {
+		return nil, ErrTargetDeadline
+	}
+
+	m, err := s.missionRepo.FindByID(ctx, t.MissionID)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	if err := validateTargetDeadline(m, dueAt); err != nil {
+		return nil, err
+	}
+
+	t.DueAt = dueAt
+	if err := s.targetRepo.Update(ctx, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ProcessOverdue flags ongoing missions that have missed their deadline, notifies
+// handlers and, when configured, suspends them. Each mission is escalated once.
+func (s *service) ProcessOverdue(ctx context.Context) (int, error) {
+	ctx, span := tracing.Start(ctx, "mission.Service.ProcessOverdue")
+	defer span.End()
+
+	now := s.clock.Now()
+	missions, err := s.missionRepo.FindOverdue(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+
+	flagged := 0
+	for i := range missions {
+		m := &missions[i]
+		if m.OverdueAt != nil || m.Status != "ONGOING" {
+			continue
+		}
+
+		m.OverdueAt = &now
+		if s.escalation == EscalateSuspend {
+			m.Status = "SUSPENDED"
+		}
+		// The flag and its history entry are written together, so that a failed
+		// entry leaves the mission to be flagged again on the next run.
+		err := s.missionRepo.Transaction(ctx, func(tx Tx) error {
+			if err := tx.Missions.Update(ctx, m); err != nil {
+				return err
+			}
+			return tx.History.Create(ctx, &history.Entry{
+				EntityType: history.EntityMission,
+				EntityID:   m.ID,
+				Action:     "OVERDUE",
+				Actor:      "scheduler",
+				Reason:     "deadline " + m.DueAt.Format(time.RFC3339) + " passed",
+			})
+		})
+		if err != nil {
+			return flagged, err
+		}
+		flagged++
+
+		if err := s.notifier.MissionOverdue(ctx, m); err != nil {
+			return flagged, err
+		}
+	}
+	return flagged, nil
+}
+
+// ReopenTarget restores a completed target to ONGOING. If its mission was completed
+// as well, the mission is reopened too, provided the cat is not busy elsewhere.
+func (s *service) ReopenTarget(ctx context.Context, targetID uint, actor, justification string) error {
+	ctx, span := tracing.Start(ctx, "mission.Service.ReopenTarget")
+	defer span.End()
+
+	if justification == "" {
+		return ErrNoTargetJustification
+	}
+
+	t, err := s.targetRepo.FindByID(ctx, targetID)
+	if err != nil {
+		return target.ErrNotFound
+	}
+	if t.Status != "COMPLETED" {
+		return ErrTargetNotDone
+	}
+
+	m, err := s.missionRepo.FindByID(ctx, t.MissionID)
+	if err != nil {
+		return ErrNotFound
+	}
+
+	// The mission, the target and their history entries change together or not at all
+	return s.missionRepo.Transaction(ctx, func(tx Tx) error {
+		if m.Status == "COMPLETED" {
+			if err := s.reopenMission(ctx, tx, m, actor, justification); err != nil {
+				return err
+			}
+		}
+
+		t.Status = "ONGOING"
+		t.CompletedAt = nil
+		if err := tx.Targets.Update(ctx, t); err != nil {
+			return err
+		}
+
+		return recordReopen(ctx, tx.History, history.EntityTarget, t.ID, actor, justification)
+	})
+}
+
+// ReopenMission restores a completed mission to ONGOING.
+func (s *service) ReopenMission(ctx context.Context, missionID uint, actor, justification string) error {
+	ctx, span := tracing.Start(ctx, "mission.Service.ReopenMission")
+	defer span.End()
+
+	if justification == "" {
+		return ErrNoJustification
+	}
+
+	m, err := s.missionRepo.FindByID(ctx, missionID)
+	if err != nil {
+		return ErrNotFound
+	}
+	if m.Status != "COMPLETED" {
+		return ErrNotCompleted
+	}
+
+	return s.missionRepo.Transaction(ctx, func(tx Tx) error {
+		return s.reopenMission(ctx, tx, m, actor, justification)
+	})
+}
+
+// reopenMission re-checks that the assigned cat is available and within the
+// concurrent-missions rule, then restores the mission and records the override
+// within tx. The cat's row stays locked until tx ends, so concurrent
+// assignments of the cat cannot both pass the check.
+func (s *service) reopenMission(ctx context.Context, tx Tx, m *Mission, actor, justification string) error {
+	if m.CatID != nil {
+		c, err := tx.Cats.FindByIDForUpdate(ctx, *m.CatID)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrCatCannotReturn, cat.ErrNotFound)
+		}
+		if err := cat.CheckAvailable(ctx, tx.Cats, c, s.clock.Now()); err != nil {
+			return fmt.Errorf("%w: %w", ErrCatCannotReturn, err)
+		}
+		if err := s.checkCatCapacity(ctx, tx.Missions, c.ID); err != nil {
+			return fmt.Errorf("%w: %w", ErrCatCannotReturn, err)
+		}
+	}
+
+	m.Status = "ONGOING"
+	m.CompletedAt = nil
+	m.OverdueAt = nil
+	if err := tx.Missions.Update(ctx, m); err != nil {
+		return err
+	}
+
+	return recordReopen(ctx, tx.History, history.EntityMission, m.ID, actor, justification)
+}
+
+// recordReopen writes a REOPEN override to history.
+func recordReopen(ctx context.Context, repo history.Repository, entityType string, entityID uint, actor, justification string) error {
+	return repo.Create(ctx, &history.Entry{
+		EntityType: entityType,
+		EntityID:   entityID,
+		Action:     "REOPEN",
+		Actor:      actor,
+		Reason:     justification,
+	})
 }
 
 // markMissionCompleted is an internal helper to mark a mission as completed.
-func (s *service) markMissionCompleted(missionID uint) error {
-	m, err := s.missionRepo.FindByID(missionID)
+func (s *service) markMissionCompleted(ctx context.Context, missionID uint) error {
+	m, err := s.missionRepo.FindByID(ctx, missionID)
 	if err != nil {
-		return errors.New("mission not found")
+		return ErrNotFound
+	}
+	if m.Status == "DRAFT" {
+		return ErrDraftComplete
 	}
 
-	now := time.Now()
+	now := s.clock.Now()
 	m.Status = "COMPLETED"
 	m.CompletedAt = &now
 
-	return s.missionRepo.Update(m)
+	return s.missionRepo.Update(ctx, m)
+}
+
+// checkTargetCount enforces the number of targets a mission is created with.
+func (s *service) checkTargetCount(n int) error {
+	if n < s.rules.MinTargetsPerMission || n > s.rules.MaxTargetsPerMission {
+		return fmt.Errorf("%w: it must have between %d and %d",
+			ErrTargetCount, s.rules.MinTargetsPerMission, s.rules.MaxTargetsPerMission)
+	}
+	return nil
+}
+
+// catCapacity reports whether a cat can take another mission under the rules'
+// limit on ongoing missions per cat, and how many it already has, counting
+// through missions so that a transaction sees its own changes.
+func (s *service) catCapacity(ctx context.Context, missions Repository, catID uint) (bool, int64, error) {
+	ongoing, err := missions.CountOngoingByCatID(ctx, catID)
+	if err != nil {
+		return false, 0, err
+	}
+	return ongoing < int64(s.rules.MaxConcurrentMissionsPerCat), ongoing, nil
+}
+
+// checkCatCapacity returns an error if the cat cannot take another mission.
+func (s *service) checkCatCapacity(ctx context.Context, missions Repository, catID uint) error {
+	free, ongoing, err := s.catCapacity(ctx, missions, catID)
+	if err != nil || free {
+		return err
+	}
+	if s.rules.MaxConcurrentMissionsPerCat == 1 {
+		return fmt.Errorf("%w: it already has an ongoing mission", ErrCatAtCapacity)
+	}
+	return fmt.Errorf("%w: it already has %d ongoing missions", ErrCatAtCapacity, ongoing)
+}
+
+// validateSchedule ensures a mission's due date does not precede its start date.
+func validateSchedule(startAt, dueAt *time.Time) error {
+	if startAt != nil && dueAt != nil && !dueAt.After(*startAt) {
+		return ErrInvalidSchedule
+	}
+	return nil
+}
+
+// validateTargetDeadline ensures a target is not due after its mission.
+func validateTargetDeadline(m *Mission, dueAt *time.Time) error {
+	if dueAt != nil && m.DueAt != nil && dueAt.After(*m.DueAt) {
+		return ErrTargetDueLate
+	}
+	return nil
+}
+
+// normalizeRating applies defaults to a mission's priority and difficulty and validates them.
+func normalizeRating(priority string, difficulty int) (string, int, error) {
+	priority = strings.ToUpper(strings.TrimSpace(priority))
+	if priority == "" {
+		priority = "NORMAL"
+	}
+	if _, ok := priorityWeights[priority]; !ok {
+		return "", 0, ErrInvalidPriority
+	}
+
+	if difficulty == 0 {
+		difficulty = 1
+	}
+	if difficulty < 1 || difficulty > 5 {
+		return "", 0, ErrInvalidDifficulty
+	}
+	return priority, difficulty, nil
 }
@@ -0,0 +1,332 @@
+package mission
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/genryusaishigikuni/spy_cats/internal/cat"
+	"github.com/genryusaishigikuni/spy_cats/internal/history"
+	"github.com/genryusaishigikuni/spy_cats/internal/target"
+	"github.com/genryusaishigikuni/spy_cats/pkg/clock"
+	"github.com/genryusaishigikuni/spy_cats/pkg/rules"
+)
+
+// fakeMissions keeps missions in memory. Methods the tests do not need are
+// left to the embedded nil interface and panic if called.
+type fakeMissions struct {
+	Repository
+	missions map[uint]*Mission
+	updates  int
+	cats     *fakeCats
+	history  *fakeHistory
+}
+
+func newFakeMissions(missions ...Mission) *fakeMissions {
+	f := &fakeMissions{missions: make(map[uint]*Mission)}
+	for i := range missions {
+		m := missions[i]
+		f.missions[m.ID] = &m
+	}
+	return f
+}
+
+func (f *fakeMissions) FindByID(_ context.Context, id uint) (*Mission, error) {
+	m, ok := f.missions[id]
+	if !ok {
+		return nil, errors.New("record not found")
+	}
+	cp := *m
+	return &cp, nil
+}
+
+func (f *fakeMissions) FindOverdue(_ context.Context, now time.Time) ([]Mission, error) {
+	var out []Mission
+	for _, m := range f.missions {
+		if m.DueAt != nil && m.DueAt.Before(now) && m.Status != "COMPLETED" {
+			out = append(out, *m)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeMissions) Update(_ context.Context, m *Mission) error {
+	cp := *m
+	f.missions[m.ID] = &cp
+	f.updates++
+	return nil
+}
+
+func (f *fakeMissions) CountOngoingByCatID(_ context.Context, catID uint) (int64, error) {
+	var n int64
+	for _, m := range f.missions {
+		if m.AssignedCatID() == catID && m.Status != "COMPLETED" {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Transaction runs fn on the fakes themselves; writes made before fn fails are
+// not rolled back.
+func (f *fakeMissions) Transaction(_ context.Context, fn func(tx Tx) error) error {
+	return fn(Tx{Missions: f, Cats: f.cats, Targets: fakeTargets{}, History: f.history})
+}
+
+type fakeCats struct {
+	cat.Repository
+	cats   map[uint]*cat.Cat
+	leaves []cat.Leave
+}
+
+func newFakeCats(cats ...cat.Cat) *fakeCats {
+	f := &fakeCats{cats: make(map[uint]*cat.Cat)}
+	for i := range cats {
+		c := cats[i]
+		f.cats[c.ID] = &c
+	}
+	return f
+}
+
+func (f *fakeCats) FindByID(_ context.Context, id uint) (*cat.Cat, error) {
+	c, ok := f.cats[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	cp := *c
+	return &cp, nil
+}
+
+func (f *fakeCats) FindByIDForUpdate(ctx context.Context, id uint) (*cat.Cat, error) {
+	return f.FindByID(ctx, id)
+}
+
+func (f *fakeCats) FindLeaveAt(_ context.Context, catID uint, at time.Time) (*cat.Leave, error) {
+	for i := range f.leaves {
+		l := f.leaves[i]
+		if l.CatID == catID && !at.Before(l.StartsAt) && at.Before(l.EndsAt) {
+			return &l, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+type fakeTargets struct {
+	target.Repository
+}
+
+func (fakeTargets) FindByMissionID(context.Context, uint) ([]target.Target, error) {
+	return nil, nil
+}
+
+type fakeHistory struct {
+	history.Repository
+	entries []history.Entry
+}
+
+func (f *fakeHistory) Create(_ context.Context, e *history.Entry) error {
+	f.entries = append(f.entries, *e)
+	return nil
+}
+
+type fakeNotifier struct {
+	notified []uint
+}
+
+func (f *fakeNotifier) MissionOverdue(_ context.Context, m *Mission) error {
+	f.notified = append(f.notified, m.ID)
+	return nil
+}
+
+var testNow = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestService(missions *fakeMissions, hist *fakeHistory, notifier *fakeNotifier, escalation string) Service {
+	if missions.cats == nil {
+		missions.cats = newFakeCats()
+	}
+	missions.history = hist
+	return NewService(missions, missions.cats, fakeTargets{}, hist, nil, nil, nil, clock.Fixed(testNow), notifier, escalation, rules.Default())
+}
+
+func TestProcessOverdue(t *testing.T) {
+	due := testNow.Add(-time.Hour)
+	for _, tc := range []struct {
+		escalation string
+		wantStatus string
+	}{
+		{EscalateNotify, "ONGOING"},
+		{EscalateSuspend, "SUSPENDED"},
+	} {
+		t.Run(tc.escalation, func(t *testing.T) {
+			missions := newFakeMissions(Mission{ID: 1, Status: "ONGOING", DueAt: &due})
+			hist := &fakeHistory{}
+			notifier := &fakeNotifier{}
+			s := newTestService(missions, hist, notifier, tc.escalation)
+
+			flagged, err := s.ProcessOverdue(context.Background())
+			if err != nil {
+				t.Fatalf("ProcessOverdue: %v", err)
+			}
+			if flagged != 1 {
+				t.Fatalf("flagged = %d, want 1", flagged)
+			}
+			m := missions.missions[1]
+			if m.OverdueAt == nil || !m.OverdueAt.Equal(testNow) {
+				t.Errorf("OverdueAt = %v, want %v", m.OverdueAt, testNow)
+			}
+			if m.Status != tc.wantStatus {
+				t.Errorf("Status = %q, want %q", m.Status, tc.wantStatus)
+			}
+			if len(notifier.notified) != 1 || notifier.notified[0] != 1 {
+				t.Errorf("notified = %v, want [1]", notifier.notified)
+			}
+			if len(hist.entries) != 1 || hist.entries[0].Action != "OVERDUE" {
+				t.Errorf("history = %+v, want one OVERDUE entry", hist.entries)
+			}
+		})
+	}
+}
+
+func TestProcessOverdueSkipsFlaggedMissions(t *testing.T) {
+	due := testNow.Add(-2 * time.Hour)
+	flaggedAt := testNow.Add(-time.Hour)
+	missions := newFakeMissions(
+		Mission{ID: 1, Status: "ONGOING", DueAt: &due, OverdueAt: &flaggedAt},
+		Mission{ID: 2, Status: "SUSPENDED", DueAt: &due, OverdueAt: &flaggedAt},
+	)
+	hist := &fakeHistory{}
+	notifier := &fakeNotifier{}
+	s := newTestService(missions, hist, notifier, EscalateSuspend)
+
+	flagged, err := s.ProcessOverdue(context.Background())
+	if err != nil {
+		t.Fatalf("ProcessOverdue: %v", err)
+	}
+	if flagged != 0 || missions.updates != 0 {
+		t.Errorf("flagged = %d, updates = %d, want none", flagged, missions.updates)
+	}
+	if len(notifier.notified) != 0 || len(hist.entries) != 0 {
+		t.Errorf("notified = %v, history = %+v, want none", notifier.notified, hist.entries)
+	}
+	if !missions.missions[1].OverdueAt.Equal(flaggedAt) {
+		t.Errorf("OverdueAt changed to %v", missions.missions[1].OverdueAt)
+	}
+}
+
+func TestScheduleMissionClearsOverdue(t *testing.T) {
+	due := testNow.Add(-time.Hour)
+	flaggedAt := testNow.Add(-time.Minute)
+	missions := newFakeMissions(Mission{ID: 1, Status: "SUSPENDED", DueAt: &due, OverdueAt: &flaggedAt})
+	s := newTestService(missions, &fakeHistory{}, &fakeNotifier{}, EscalateSuspend)
+
+	newDue := testNow.Add(24 * time.Hour)
+	m, err := s.ScheduleMission(context.Background(), 1, nil, &newDue)
+	if err != nil {
+		t.Fatalf("ScheduleMission: %v", err)
+	}
+	if m.OverdueAt != nil {
+		t.Errorf("OverdueAt = %v, want nil", m.OverdueAt)
+	}
+	if m.Status != "ONGOING" {
+		t.Errorf("Status = %q, want ONGOING", m.Status)
+	}
+	if stored := missions.missions[1]; stored.OverdueAt != nil || stored.Status != "ONGOING" || !stored.DueAt.Equal(newDue) {
+		t.Errorf("stored mission = %+v, want rescheduled", stored)
+	}
+
+	// Once rescheduled, the mission is not flagged again before its new deadline.
+	flagged, err := s.ProcessOverdue(context.Background())
+	if err != nil {
+		t.Fatalf("ProcessOverdue: %v", err)
+	}
+	if flagged != 0 {
+		t.Errorf("flagged = %d after rescheduling, want 0", flagged)
+	}
+}
+
+func TestReopenMission(t *testing.T) {
+	catID := uint(7)
+	completedAt := testNow.Add(-time.Hour)
+	for _, tc := range []struct {
+		name    string
+		cat     cat.Cat
+		leaves  []cat.Leave
+		others  []Mission
+		wantErr bool
+	}{
+		{name: "available cat", cat: cat.Cat{ID: catID, Status: cat.StatusActive}},
+		{
+			name:    "cat at capacity",
+			cat:     cat.Cat{ID: catID, Status: cat.StatusActive},
+			others:  []Mission{{ID: 2, CatID: &catID, Status: "ONGOING"}},
+			wantErr: true,
+		},
+		{
+			name:   "cat with only completed missions",
+			cat:    cat.Cat{ID: catID, Status: cat.StatusActive},
+			others: []Mission{{ID: 2, CatID: &catID, Status: "COMPLETED"}},
+		},
+		{name: "injured cat", cat: cat.Cat{ID: catID, Status: cat.StatusInjured}, wantErr: true},
+		{
+			name: "cat on leave",
+			cat:  cat.Cat{ID: catID, Status: cat.StatusActive},
+			leaves: []cat.Leave{
+				{CatID: catID, StartsAt: testNow.Add(-time.Hour), EndsAt: testNow.Add(time.Hour)},
+			},
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			missions := newFakeMissions(append(tc.others,
+				Mission{ID: 1, CatID: &catID, Status: "COMPLETED", CompletedAt: &completedAt})...)
+			missions.cats = newFakeCats(tc.cat)
+			missions.cats.leaves = tc.leaves
+			hist := &fakeHistory{}
+			s := newTestService(missions, hist, &fakeNotifier{}, EscalateNotify)
+
+			err := s.ReopenMission(context.Background(), 1, "boss", "evidence was planted")
+			m := missions.missions[1]
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("ReopenMission succeeded, want an error")
+				}
+				if m.Status != "COMPLETED" || len(hist.entries) != 0 {
+					t.Errorf("status = %q, history = %+v, want the mission left completed", m.Status, hist.entries)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReopenMission: %v", err)
+			}
+			if m.Status != "ONGOING" || m.CompletedAt != nil {
+				t.Errorf("mission = %+v, want ONGOING without CompletedAt", m)
+			}
+			want := history.Entry{
+				EntityType: history.EntityMission,
+				EntityID:   1,
+				Action:     "REOPEN",
+				Actor:      "boss",
+				Reason:     "evidence was planted",
+			}
+			if len(hist.entries) != 1 || hist.entries[0] != want {
+				t.Errorf("history = %+v, want [%+v]", hist.entries, want)
+			}
+		})
+	}
+}
+
+func TestReopenMissionRequiresJustification(t *testing.T) {
+	completedAt := testNow.Add(-time.Hour)
+	missions := newFakeMissions(Mission{ID: 1, Status: "COMPLETED", CompletedAt: &completedAt})
+	s := newTestService(missions, &fakeHistory{}, &fakeNotifier{}, EscalateNotify)
+
+	if err := s.ReopenMission(context.Background(), 1, "boss", ""); err == nil {
+		t.Fatal("ReopenMission succeeded without a justification")
+	}
+	if missions.missions[1].Status != "COMPLETED" {
+		t.Errorf("status = %q, want COMPLETED", missions.missions[1].Status)
+	}
+}
@@ -0,0 +1,252 @@
+package mission
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/genryusaishigikuni/spy_cats/internal/history"
+	"github.com/genryusaishigikuni/spy_cats/pkg/errcode"
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
+)
+
+// Errors returned for mission templates.
+var (
+	ErrTemplateNotFound = errcode.New("template_not_found", "template not found")
+	ErrNotDraft         = errcode.New("mission_not_draft", "only draft missions can be activated")
+	ErrTemplateName     = errcode.New("invalid_template", "template name is required")
+	ErrTemplateTarget   = errcode.New("invalid_template", "template targets need a name")
+	ErrTemplateDuration = errcode.New("invalid_template", "duration cannot be negative")
+)
+
+// Template is a reusable mission shape: default targets, skills and duration.
+type Template struct {
+	ID             uint `gorm:"primaryKey"`
+	Name           string
+	Targets        []TemplateTarget `gorm:"serializer:json"`
+	RequiredSkills []string         `gorm:"serializer:json"`
+	Priority       string
+	Difficulty     int
+	DurationHours  int // missions made from the template are due this long after they start, 0 for no deadline
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Version        uint `gorm:"not null;default:1"`
+}
+
+// TemplateTarget is a target created with every mission made from a template.
+type TemplateTarget struct {
+	Name    string
+	Country string
+	Notes   string
+}
+
+// NewTarget describes a target created together with its mission.
+type NewTarget struct {
+	Name    string
+	Country string
+	Notes   string
+}
+
+// CreateTemplate validates and stores a new mission template.
+func (s *service) CreateTemplate(ctx context.Context, t *Template) (*Template, error) {
+	ctx, span := tracing.Start(ctx, "mission.Service.CreateTemplate")
+	defer span.End()
+
+	if err := s.normalizeTemplate(ctx, t); err != nil {
+		return nil, err
+	}
+	t.ID = 0
+	if err := s.missionRepo.CreateTemplate(ctx, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// GetTemplate returns a mission template by ID.
+func (s *service) GetTemplate(ctx context.Context, id uint) (*Template, error) {
+	ctx, span := tracing.Start(ctx, "mission.Service.GetTemplate")
+	defer span.End()
+
+	t, err := s.missionRepo.FindTemplate(ctx, id)
+	if err != nil {
+		return nil, ErrTemplateNotFound
+	}
+	return t, nil
+}
+
+// ListTemplates returns every mission template.
+func (s *service) ListTemplates(ctx context.Context) ([]Template, error) {
+	ctx, span := tracing.Start(ctx, "mission.Service.ListTemplates")
+	defer span.End()
+
+	return s.missionRepo.ListTemplates(ctx)
+}
+
+// UpdateTemplate replaces a template's contents. Missions already made from it
+// are not affected.
+func (s *service) UpdateTemplate(ctx context.Context, id uint, update *Template) (*Template, error) {
+	ctx, span := tracing.Start(ctx, "mission.Service.UpdateTemplate")
+	defer span.End()
+
+	t, err := s.missionRepo.FindTemplate(ctx, id)
+	if err != nil {
+		return nil, ErrTemplateNotFound
+	}
+	if err := s.normalizeTemplate(ctx, update); err != nil {
+		return nil, err
+	}
+
+	t.Name = update.Name
+	t.Targets = update.Targets
+	t.RequiredSkills = update.RequiredSkills
+	t.Priority = update.Priority
+	t.Difficulty = update.Difficulty
+	t.DurationHours = update.DurationHours
+	if err := s.missionRepo.UpdateTemplate(ctx, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// DeleteTemplate removes a mission template.
+func (s *service) DeleteTemplate(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "mission.Service.DeleteTemplate")
+	defer span.End()
+
+	if _, err := s.missionRepo.FindTemplate(ctx, id); err != nil {
+		return ErrTemplateNotFound
+	}
+	return s.missionRepo.DeleteTemplate(ctx, id)
+}
+
+// CreateFromTemplate creates a mission shaped like the template and assigned to
+// the cat. It goes through CreateMission, so the usual cat and target checks
+// apply. The deadline is counted from startAt, or from now if it is nil.
+func (s *service) CreateFromTemplate(ctx context.Context, templateID, catID uint, startAt *time.Time) (*Mission, error) {
+	ctx, span := tracing.Start(ctx, "mission.Service.CreateFromTemplate")
+	defer span.End()
+
+	t, err := s.missionRepo.FindTemplate(ctx, templateID)
+	if err != nil {
+		return nil, ErrTemplateNotFound
+	}
+
+	req := NewMission{
+		CatID:          catID,
+		StartAt:        startAt,
+		Priority:       t.Priority,
+		Difficulty:     t.Difficulty,
+		RequiredSkills: t.RequiredSkills,
+	}
+	for _, tt := range t.Targets {
+		req.Targets = append(req.Targets, NewTarget(tt))
+	}
+	if t.DurationHours > 0 {
+		from := s.clock.Now()
+		if startAt != nil {
+			from = *startAt
+		}
+		due := from.Add(time.Duration(t.DurationHours) * time.Hour)
+		req.DueAt = &due
+	}
+	m, _, err := s.CreateMission(ctx, req)
+	return m, err
+}
+
+// CloneMission copies a mission's targets, priority, difficulty and required
+// skills into a new unassigned DRAFT mission. Deadlines are not copied.
+func (s *service) CloneMission(ctx context.Context, missionID uint, actor string) (*Mission, error) {
+	ctx, span := tracing.Start(ctx, "mission.Service.CloneMission")
+	defer span.End()
+
+	m, err := s.missionRepo.FindByID(ctx, missionID)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	targets, err := s.targetRepo.FindByMissionID(ctx, missionID)
+	if err != nil {
+		return nil, err
+	}
+
+	req := NewMission{
+		Draft:          true,
+		Priority:       m.Priority,
+		Difficulty:     m.Difficulty,
+		RequiredSkills: m.RequiredSkills,
+	}
+	for _, t := range targets {
+		req.Targets = append(req.Targets, NewTarget{Name: t.Name, Country: t.Country, Notes: t.Notes})
+	}
+	clone, _, err := s.createMission(ctx, req, func(tx Tx, clone *Mission) error {
+		return tx.History.Create(ctx, &history.Entry{
+			EntityType: history.EntityMission,
+			EntityID:   clone.ID,
+			Action:     "CLONE",
+			Actor:      actor,
+			Reason:     fmt.Sprintf("cloned from mission %d", missionID),
+		})
+	})
+	return clone, err
+}
+
+// ActivateMission turns a DRAFT mission into an ONGOING one, assigning the cat
+// if one is given. The cat is checked as in AssignCat.
+func (s *service) ActivateMission(ctx context.Context, missionID, catID uint, overrideSkills bool, actor string) (*Mission, error) {
+	ctx, span := tracing.Start(ctx, "mission.Service.ActivateMission")
+	defer span.End()
+
+	m, err := s.missionRepo.FindByID(ctx, missionID)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	if m.Status != "DRAFT" {
+		return nil, ErrNotDraft
+	}
+
+	var skillErr error
+	if catID != 0 {
+		if skillErr, err = s.checkAssignable(ctx, m, catID, overrideSkills); err != nil {
+			return nil, err
+		}
+		m.CatID = &catID
+	}
+	m.Status = "ONGOING"
+	if err := s.missionRepo.Update(ctx, m); err != nil {
+		return nil, err
+	}
+
+	if skillErr != nil {
+		if err := s.recordSkillOverride(ctx, m.ID, catID, actor, skillErr); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// normalizeTemplate validates a template and applies the same defaults
+// CreateMission would.
+func (s *service) normalizeTemplate(ctx context.Context, t *Template) error {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Name == "" {
+		return ErrTemplateName
+	}
+	if err := s.checkTargetCount(len(t.Targets)); err != nil {
+		return err
+	}
+	for _, tt := range t.Targets {
+		if strings.TrimSpace(tt.Name) == "" {
+			return ErrTemplateTarget
+		}
+	}
+	if t.DurationHours < 0 {
+		return ErrTemplateDuration
+	}
+
+	var err error
+	if t.Priority, t.Difficulty, err = normalizeRating(t.Priority, t.Difficulty); err != nil {
+		return err
+	}
+	t.RequiredSkills, err = s.skills.NormalizeNames(ctx, t.RequiredSkills)
+	return err
+}
@@ -1,11 +1,20 @@
 package note
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/genryusaishigikuni/spy_cats/internal/target"
+)
 
 type Note struct {
-	ID        uint `gorm:"primaryKey"`
-	TargetID  uint
+	ID        uint           `gorm:"primaryKey"`
+	TargetID  uint           `gorm:"index"`
+	Target    *target.Target `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:",omitempty"`
 	Content   string
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	Version   uint           `gorm:"not null;default:1"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
 }
@@ -1,10 +1,13 @@
 package note
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/etag"
 )
 
 // Handler handles HTTP requests for the "note" domain.
@@ -18,14 +21,30 @@ func NewHandler(s Service) *Handler {
 }
 
 // RegisterRoutes sets up note endpoints, for example to create/update notes.
-func (h *Handler) RegisterRoutes(r *gin.Engine) {
-	r.POST("/targets/:targetId/notes", h.createNote)
-	r.PUT("/notes/:id", h.updateNote)
+func (h *Handler) RegisterRoutes(r gin.IRouter) {
+	r.POST("/targets/:id/notes", h.createNote)
+	r.GET("/notes/:id", h.getNote)
+	r.PUT("/notes/:id", etag.IfMatch(h.noteVersion), h.updateNote)
+	r.DELETE("/notes/:id", etag.IfMatch(h.noteVersion), h.deleteNote)
+	r.POST("/notes/:id/restore", h.restoreNote)
+}
+
+// noteVersion looks up the version of the note addressed by :id for If-Match checks.
+func (h *Handler) noteVersion(c *gin.Context) (interface{}, uint, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, 0, false
+	}
+	n, err := h.service.GetNote(c.Request.Context(), uint(id))
+	if err != nil {
+		return nil, 0, false
+	}
+	return n, n.Version, true
 }
 
-// createNote handles POST /targets/:targetId/notes
+// createNote handles POST /targets/:id/notes
 func (h *Handler) createNote(c *gin.Context) {
-	targetIDStr := c.Param("targetId")
+	targetIDStr := c.Param("id")
 	targetID, err := strconv.Atoi(targetIDStr)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target ID"})
@@ -40,15 +59,39 @@ func (h *Handler) createNote(c *gin.Context) {
 		return
 	}
 
-	n, err := h.service.CreateNote(uint(targetID), req.Content)
+	n, err := h.service.CreateNote(c.Request.Context(), uint(targetID), req.Content)
 	if err != nil {
+		if errors.Is(err, ErrTooLong) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	etag.Set(c, n.Version)
 	c.JSON(http.StatusCreated, n)
 }
 
+// getNote handles GET /notes/:id
+func (h *Handler) getNote(c *gin.Context) {
+	idStr := c.Param("id")
+	noteID, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid note ID"})
+		return
+	}
+
+	n, err := h.service.GetNote(c.Request.Context(), uint(noteID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	etag.Set(c, n.Version)
+	c.JSON(http.StatusOK, n)
+}
+
 // updateNote handles PUT /notes/:id
 func (h *Handler) updateNote(c *gin.Context) {
 	idStr := c.Param("id")
@@ -66,11 +109,63 @@ func (h *Handler) updateNote(c *gin.Context) {
 		return
 	}
 
-	updatedNote, err := h.service.UpdateNote(uint(noteID), req.Content)
+	updatedNote, err := h.service.UpdateNote(c.Request.Context(), uint(noteID), req.Content)
 	if err != nil {
+		if etag.Conflict(c, err) {
+			return
+		}
+		if errors.Is(err, ErrTooLong) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	etag.Set(c, updatedNote.Version)
 	c.JSON(http.StatusOK, updatedNote)
 }
+
+// deleteNote handles DELETE /notes/:id
+func (h *Handler) deleteNote(c *gin.Context) {
+	idStr := c.Param("id")
+	noteID, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid note ID"})
+		return
+	}
+
+	if err := h.service.DeleteNote(c.Request.Context(), uint(noteID)); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// restoreNote handles POST /notes/:id/restore
+func (h *Handler) restoreNote(c *gin.Context) {
+	idStr := c.Param("id")
+	noteID, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid note ID"})
+		return
+	}
+
+	n, err := h.service.RestoreNote(c.Request.Context(), uint(noteID))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	etag.Set(c, n.Version)
+	c.JSON(http.StatusOK, n)
+}
@@ -1,12 +1,24 @@
 package note
 
-import "gorm.io/gorm"
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/optimistic"
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
+)
 
 type Repository interface {
-	Create(n *Note) error
-	FindByID(id uint) (*Note, error)
-	Update(n *Note) error
-	// etc...
+	Create(ctx context.Context, n *Note) error
+	FindByID(ctx context.Context, id uint) (*Note, error)
+	// FindWithDeleted retrieves a Note whether or not it is soft-deleted.
+	FindWithDeleted(ctx context.Context, id uint) (*Note, error)
+	FindByTargetIDs(ctx context.Context, targetIDs []uint) ([]Note, error)
+	FindLatestByTargetIDs(ctx context.Context, targetIDs []uint) ([]Note, error)
+	Update(ctx context.Context, n *Note) error
+	Delete(ctx context.Context, id uint) error
+	Restore(ctx context.Context, id uint) (*Note, error)
 }
 
 // repository implements the Repository interface for notes.
@@ -20,20 +32,115 @@ func NewRepository(db *gorm.DB) Repository {
 }
 
 // Create inserts a new Note record into the database.
-func (r *repository) Create(n *Note) error {
-	return r.db.Create(n).Error
+func (r *repository) Create(ctx context.Context, n *Note) error {
+	ctx, span := tracing.Start(ctx, "note.Repository.Create")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Create(n).Error
 }
 
 // FindByID retrieves a Note by its primary key (ID).
-func (r *repository) FindByID(id uint) (*Note, error) {
+func (r *repository) FindByID(ctx context.Context, id uint) (*Note, error) {
+	ctx, span := tracing.Start(ctx, "note.Repository.FindByID")
+	defer span.End()
+
 	var note Note
-	if err := r.db.First(&note, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).First(&note, id).Error; err != nil {
 		return nil, err
 	}
 	return &note, nil
 }
 
-// Update applies changes to an existing Note record in the database.
-func (r *repository) Update(n *Note) error {
-	return r.db.Save(n).Error
+// FindWithDeleted retrieves a Note by its ID, including soft-deleted ones.
+func (r *repository) FindWithDeleted(ctx context.Context, id uint) (*Note, error) {
+	ctx, span := tracing.Start(ctx, "note.Repository.FindWithDeleted")
+	defer span.End()
+
+	var n Note
+	if err := r.db.WithContext(ctx).Unscoped().First(&n, id).Error; err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// FindByTargetIDs retrieves the notes on any of the targets, oldest first.
+func (r *repository) FindByTargetIDs(ctx context.Context, targetIDs []uint) ([]Note, error) {
+	ctx, span := tracing.Start(ctx, "note.Repository.FindByTargetIDs")
+	defer span.End()
+
+	var notes []Note
+	if err := r.db.WithContext(ctx).
+		Where("target_id IN ?", targetIDs).
+		Order("created_at, id").
+		Find(&notes).Error; err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// FindLatestByTargetIDs retrieves the most recent note on each of the targets
+// that has any.
+func (r *repository) FindLatestByTargetIDs(ctx context.Context, targetIDs []uint) ([]Note, error) {
+	ctx, span := tracing.Start(ctx, "note.Repository.FindLatestByTargetIDs")
+	defer span.End()
+
+	var notes []Note
+	if err := r.db.WithContext(ctx).
+		Select("DISTINCT ON (target_id) *").
+		Where("target_id IN ?", targetIDs).
+		Order("target_id, created_at DESC, id DESC").
+		Find(&notes).Error; err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// Update applies changes to an existing Note record in the database. It fails with
+// optimistic.ErrConflict if the note was modified since it was read.
+func (r *repository) Update(ctx context.Context, n *Note) error {
+	ctx, span := tracing.Start(ctx, "note.Repository.Update")
+	defer span.End()
+
+	return optimistic.Update(r.db.WithContext(ctx), n, &n.Version)
+}
+
+// Delete soft-deletes a Note by its ID.
+func (r *repository) Delete(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "note.Repository.Delete")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Delete(&Note{}, id).Error
+}
+
+// Restore undoes Delete for a note whose target still exists.
+func (r *repository) Restore(ctx context.Context, id uint) (*Note, error) {
+	ctx, span := tracing.Start(ctx, "note.Repository.Restore")
+	defer span.End()
+
+	var note Note
+	if err := r.db.WithContext(ctx).Unscoped().First(&note, id).Error; err != nil {
+		return nil, err
+	}
+	if !note.DeletedAt.Valid {
+		return nil, ErrNotDeleted
+	}
+
+	var live int64
+	if err := r.db.WithContext(ctx).Table("targets").
+		Where("id = ? AND deleted_at IS NULL", note.TargetID).
+		Count(&live).Error; err != nil {
+		return nil, err
+	}
+	if live == 0 {
+		return nil, ErrTargetDeleted
+	}
+
+	if err := r.db.WithContext(ctx).Unscoped().Model(&Note{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"deleted_at": nil, "version": optimistic.Bump}).Error; err != nil {
+		return nil, err
+	}
+	note.DeletedAt = gorm.DeletedAt{}
+	note.Version++
+	return &note, nil
 }
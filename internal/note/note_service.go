@@ -1,46 +1,109 @@
 package note
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+
+	"github.com/genryusaishigikuni/spy_cats/internal/mission"
 	"github.com/genryusaishigikuni/spy_cats/internal/target"
+	"github.com/genryusaishigikuni/spy_cats/pkg/errcode"
+	"github.com/genryusaishigikuni/spy_cats/pkg/rules"
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
 )
 
 type Service interface {
-	CreateNote(targetID uint, content string) (*Note, error)
-	UpdateNote(noteID uint, content string) (*Note, error)
+	CreateNote(ctx context.Context, targetID uint, content string) (*Note, error)
+	GetNote(ctx context.Context, noteID uint) (*Note, error)
+	// ListNotesByTargets and LatestNotesByTargets load the notes of several targets at once.
+	ListNotesByTargets(ctx context.Context, targetIDs []uint) ([]Note, error)
+	LatestNotesByTargets(ctx context.Context, targetIDs []uint) ([]Note, error)
+	UpdateNote(ctx context.Context, noteID uint, content string) (*Note, error)
+	DeleteNote(ctx context.Context, noteID uint) error
+	RestoreNote(ctx context.Context, noteID uint) (*Note, error)
 }
 
+// Errors returned by the note service.
+var (
+	ErrNotFound = errcode.New("note_not_found", "note not found")
+	// ErrTooLong is returned for notes longer than the configured maximum.
+	ErrTooLong    = errcode.New("note_too_long", "note is too long")
+	ErrNotDeleted = errcode.New("note_not_deleted", "note is not deleted")
+	// ErrFrozen is returned for changes to notes whose target or mission is
+	// completed, naming which of the two as details.
+	ErrFrozen         = errcode.New("note_frozen", "notes of a completed target or mission cannot change")
+	ErrTargetDeleted  = errcode.New("target_deleted", "the note's target is deleted, restore the target instead")
+	ErrMissionDeleted = errcode.New("mission_deleted", "the note's mission is deleted, restore the mission instead")
+)
+
 type service struct {
 	noteRepo    Repository
 	targetRepo  target.Repository
-	missionRepo target.Repository
+	missionRepo mission.Repository
+	rules       rules.Rules
+	maxLength   int
 }
 
-func NewService(nRepo Repository, tRepo target.Repository) Service {
+// NewService creates a note Service. Notes may hold up to maxLength characters.
+func NewService(nRepo Repository, tRepo target.Repository, mRepo mission.Repository, rules rules.Rules, maxLength int) Service {
 	return &service{
-		noteRepo:   nRepo,
-		targetRepo: tRepo,
+		noteRepo:    nRepo,
+		targetRepo:  tRepo,
+		missionRepo: mRepo,
+		rules:       rules,
+		maxLength:   maxLength,
 	}
 }
 
-// CreateNote creates a new note for a target, disallowing creation if the target
-// is completed (frozen).
-func (s *service) CreateNote(targetID uint, content string) (*Note, error) {
-	t, err := s.targetRepo.FindByID(targetID)
-	if err != nil {
-		return nil, err
+// checkLength rejects content longer than maxLength characters.
+func (s *service) checkLength(content string) error {
+	if n := utf8.RuneCountInString(content); n > s.maxLength {
+		return fmt.Errorf("%w: %d characters, at most %d", ErrTooLong, n, s.maxLength)
+	}
+	return nil
+}
+
+// frozenBy returns "target" or "mission" when notes on the target are frozen
+// because that is completed, or "" when they may change. Notes never freeze if
+// the rules say so.
+func (s *service) frozenBy(ctx context.Context, t *target.Target) (string, error) {
+	if !s.rules.FreezeNotesOnCompletion {
+		return "", nil
 	}
 	if t.Status == "COMPLETED" {
-		return nil, errors.New("cannot add note to a completed target")
+		return "target", nil
 	}
-
-	// Also check mission status if you want to block notes if mission is completed
-	m, err := s.missionRepo.FindByID(t.MissionID)
+	m, err := s.missionRepo.FindByID(ctx, t.MissionID)
 	if err != nil {
-		return nil, errors.New("mission not found")
+		return "", mission.ErrNotFound
 	}
 	if m.Status == "COMPLETED" {
-		return nil, errors.New("cannot add note to a completed mission")
+		return "mission", nil
+	}
+	return "", nil
+}
+
+// CreateNote creates a new note for a target, disallowing creation if the target
+// or its mission is completed (frozen).
+func (s *service) CreateNote(ctx context.Context, targetID uint, content string) (*Note, error) {
+	ctx, span := tracing.Start(ctx, "note.Service.CreateNote")
+	defer span.End()
+
+	if err := s.checkLength(content); err != nil {
+		return nil, err
+	}
+
+	t, err := s.targetRepo.FindByID(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if by, err := s.frozenBy(ctx, t); err != nil {
+		return nil, err
+	} else if by != "" {
+		return nil, fmt.Errorf("%w: the %s is completed", ErrFrozen, by)
 	}
 
 	n := &Note{
@@ -48,43 +111,126 @@ func (s *service) CreateNote(targetID uint, content string) (*Note, error) {
 		Content:  content,
 	}
 
-	if err := s.noteRepo.Create(n); err != nil {
+	if err := s.noteRepo.Create(ctx, n); err != nil {
 		return nil, err
 	}
 	return n, nil
 }
 
+// GetNote retrieves a note by its ID.
+func (s *service) GetNote(ctx context.Context, noteID uint) (*Note, error) {
+	ctx, span := tracing.Start(ctx, "note.Service.GetNote")
+	defer span.End()
+
+	n, err := s.noteRepo.FindByID(ctx, noteID)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	return n, nil
+}
+
+// ListNotesByTargets retrieves the notes on the given targets, oldest first.
+func (s *service) ListNotesByTargets(ctx context.Context, targetIDs []uint) ([]Note, error) {
+	ctx, span := tracing.Start(ctx, "note.Service.ListNotesByTargets")
+	defer span.End()
+
+	return s.noteRepo.FindByTargetIDs(ctx, targetIDs)
+}
+
+// LatestNotesByTargets retrieves the most recent note on each of the given targets.
+func (s *service) LatestNotesByTargets(ctx context.Context, targetIDs []uint) ([]Note, error) {
+	ctx, span := tracing.Start(ctx, "note.Service.LatestNotesByTargets")
+	defer span.End()
+
+	return s.noteRepo.FindLatestByTargetIDs(ctx, targetIDs)
+}
+
 // UpdateNote updates an existing note's content, disallowing changes if
 // its target or mission is completed.
-func (s *service) UpdateNote(noteID uint, content string) (*Note, error) {
-	// Find existing note
-	n, err := s.noteRepo.FindByID(noteID)
-	if err != nil {
+func (s *service) UpdateNote(ctx context.Context, noteID uint, content string) (*Note, error) {
+	ctx, span := tracing.Start(ctx, "note.Service.UpdateNote")
+	defer span.End()
+
+	if err := s.checkLength(content); err != nil {
 		return nil, err
 	}
 
-	// Check if note's target is completed
-	t, err := s.targetRepo.FindByID(n.TargetID)
+	// Find existing note
+	n, err := s.noteRepo.FindByID(ctx, noteID)
 	if err != nil {
 		return nil, err
 	}
-	if t.Status == "COMPLETED" {
-		return nil, errors.New("cannot update note for a completed target")
-	}
 
-	// Check if mission is completed
-	m, err := s.missionRepo.FindByID(t.MissionID)
+	// Check if note's target or mission is completed
+	t, err := s.targetRepo.FindByID(ctx, n.TargetID)
 	if err != nil {
-		return nil, errors.New("mission not found")
+		return nil, err
 	}
-	if m.Status == "COMPLETED" {
-		return nil, errors.New("cannot update note for a completed mission")
+	if by, err := s.frozenBy(ctx, t); err != nil {
+		return nil, err
+	} else if by != "" {
+		return nil, fmt.Errorf("%w: the %s is completed", ErrFrozen, by)
 	}
 
 	// Update note content
 	n.Content = content
-	if err := s.noteRepo.Update(n); err != nil {
+	if err := s.noteRepo.Update(ctx, n); err != nil {
 		return nil, err
 	}
 	return n, nil
 }
+
+// DeleteNote soft-deletes a note. Like updates, deletions are disallowed once
+// the note's target or mission is completed, unless the rules turn freezing off.
+func (s *service) DeleteNote(ctx context.Context, noteID uint) error {
+	ctx, span := tracing.Start(ctx, "note.Service.DeleteNote")
+	defer span.End()
+
+	n, err := s.noteRepo.FindByID(ctx, noteID)
+	if err != nil {
+		return ErrNotFound
+	}
+
+	t, err := s.targetRepo.FindByID(ctx, n.TargetID)
+	if err != nil {
+		return err
+	}
+	if by, err := s.frozenBy(ctx, t); err != nil {
+		return err
+	} else if by != "" {
+		return fmt.Errorf("%w: the %s is completed", ErrFrozen, by)
+	}
+
+	return s.noteRepo.Delete(ctx, n.ID)
+}
+
+// RestoreNote brings back a soft-deleted note. Its target and mission must not
+// be deleted, and, like deletions, restores are disallowed once the target or
+// mission is completed, unless the rules turn freezing off.
+func (s *service) RestoreNote(ctx context.Context, noteID uint) (*Note, error) {
+	ctx, span := tracing.Start(ctx, "note.Service.RestoreNote")
+	defer span.End()
+
+	n, err := s.noteRepo.FindWithDeleted(ctx, noteID)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	t, err := s.targetRepo.FindByID(ctx, n.TargetID)
+	if err != nil {
+		return nil, ErrTargetDeleted
+	}
+	if _, err := s.missionRepo.FindByID(ctx, t.MissionID); err != nil {
+		return nil, ErrMissionDeleted
+	}
+	if by, err := s.frozenBy(ctx, t); err != nil {
+		return nil, err
+	} else if by != "" {
+		return nil, fmt.Errorf("%w: the %s is completed", ErrFrozen, by)
+	}
+
+	n, err = s.noteRepo.Restore(ctx, noteID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return n, err
+}
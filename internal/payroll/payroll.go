@@ -0,0 +1,41 @@
+package payroll
+
+import "time"
+
+// SalaryChange records a cat's salary from EffectiveFrom onwards.
+type SalaryChange struct {
+	ID             uint `gorm:"primaryKey"`
+	CatID          uint `gorm:"index"`
+	PreviousSalary float64
+	Salary         float64
+	EffectiveFrom  time.Time `gorm:"index"`
+	Reason         string
+	// Applied is set once the change has been copied onto Cat.Salary, or found
+	// to be superseded by a later change that already was.
+	Applied   bool
+	CreatedAt time.Time
+}
+
+// Run is a monthly payroll computation.
+type Run struct {
+	ID          uint   `gorm:"primaryKey"`
+	Period      string `gorm:"uniqueIndex"` // "2006-01"
+	PeriodStart time.Time
+	PeriodEnd   time.Time // exclusive
+	TotalPaid   float64
+	Payslips    []Payslip `gorm:"foreignKey:RunID"`
+	CreatedAt   time.Time
+}
+
+// Payslip is one cat's pay for a payroll run.
+type Payslip struct {
+	ID                uint `gorm:"primaryKey"`
+	RunID             uint `gorm:"index"`
+	CatID             uint `gorm:"index"`
+	CatName           string
+	BasePay           float64
+	MissionsCompleted int
+	Bonus             float64
+	Total             float64
+	CreatedAt         time.Time
+}
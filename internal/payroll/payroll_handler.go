@@ -0,0 +1,201 @@
+package payroll
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler handles HTTP requests for salaries and payroll runs.
+type Handler struct {
+	service Service
+}
+
+// NewHandler creates a new payroll Handler.
+func NewHandler(s Service) *Handler {
+	return &Handler{service: s}
+}
+
+// RegisterRoutes sets up the salary history and payroll endpoints.
+func (h *Handler) RegisterRoutes(r gin.IRouter) {
+	r.GET("/cats/:id/salary-history", h.salaryHistory)
+	r.POST("/cats/:id/salary-changes", h.changeSalary)
+
+	payrollGroup := r.Group("/payroll/runs")
+	{
+		payrollGroup.POST("", h.runPayroll)                       // POST /payroll/runs
+		payrollGroup.GET("", h.listRuns)                          // GET /payroll/runs
+		payrollGroup.GET("/:id", h.getRun)                        // GET /payroll/runs/:id
+		payrollGroup.GET("/:id/payslips", h.exportPayslips)       // GET /payroll/runs/:id/payslips?format=csv
+		payrollGroup.GET("/:id/payslips/:catId", h.getCatPayslip) // GET /payroll/runs/:id/payslips/:catId
+	}
+}
+
+// salaryHistory handles GET /cats/:id/salary-history
+func (h *Handler) salaryHistory(c *gin.Context) {
+	catID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cat ID"})
+		return
+	}
+
+	history, err := h.service.SalaryHistory(c.Request.Context(), uint(catID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+// changeSalary handles POST /cats/:id/salary-changes
+func (h *Handler) changeSalary(c *gin.Context) {
+	catID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cat ID"})
+		return
+	}
+
+	var req struct {
+		Salary        float64   `json:"salary"`
+		EffectiveFrom time.Time `json:"effective_from"`
+		Reason        string    `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	change, err := h.service.ChangeSalary(c.Request.Context(), uint(catID), req.Salary, req.EffectiveFrom, req.Reason)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, change)
+}
+
+// runPayroll handles POST /payroll/runs
+func (h *Handler) runPayroll(c *gin.Context) {
+	var req struct {
+		Year  int `json:"year"`
+		Month int `json:"month"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	run, err := h.service.RunPayroll(c.Request.Context(), req.Year, time.Month(req.Month))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, run)
+}
+
+// listRuns handles GET /payroll/runs
+func (h *Handler) listRuns(c *gin.Context) {
+	runs, err := h.service.ListRuns(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, runs)
+}
+
+// getRun handles GET /payroll/runs/:id
+func (h *Handler) getRun(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payroll run ID"})
+		return
+	}
+
+	run, err := h.service.GetRun(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// exportPayslips handles GET /payroll/runs/:id/payslips?format=json|csv
+func (h *Handler) exportPayslips(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payroll run ID"})
+		return
+	}
+
+	run, err := h.service.GetRun(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.JSON(http.StatusOK, run.Payslips)
+	case "csv":
+		writePayslipsCSV(c, run, run.Payslips)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+	}
+}
+
+// getCatPayslip handles GET /payroll/runs/:id/payslips/:catId?format=json|csv
+func (h *Handler) getCatPayslip(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payroll run ID"})
+		return
+	}
+	catID, err := strconv.Atoi(c.Param("catId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cat ID"})
+		return
+	}
+
+	run, err := h.service.GetRun(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, slip := range run.Payslips {
+		if slip.CatID != uint(catID) {
+			continue
+		}
+		if c.DefaultQuery("format", "json") == "csv" {
+			writePayslipsCSV(c, run, []Payslip{slip})
+			return
+		}
+		c.JSON(http.StatusOK, slip)
+		return
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "payslip not found"})
+}
+
+// writePayslipsCSV streams payslips as a CSV attachment.
+func writePayslipsCSV(c *gin.Context, run *Run, slips []Payslip) {
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", "attachment; filename=payslips-"+run.Period+".csv")
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{"period", "cat_id", "cat_name", "base_pay", "missions_completed", "bonus", "total"})
+	for _, slip := range slips {
+		_ = w.Write([]string{
+			run.Period,
+			strconv.FormatUint(uint64(slip.CatID), 10),
+			slip.CatName,
+			strconv.FormatFloat(slip.BasePay, 'f', 2, 64),
+			strconv.Itoa(slip.MissionsCompleted),
+			strconv.FormatFloat(slip.Bonus, 'f', 2, 64),
+			strconv.FormatFloat(slip.Total, 'f', 2, 64),
+		})
+	}
+	w.Flush()
+}
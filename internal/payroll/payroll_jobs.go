@@ -0,0 +1,29 @@
+package payroll
+
+import (
+	"context"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/logging"
+	"github.com/genryusaishigikuni/spy_cats/pkg/scheduler"
+)
+
+// salaryLockKey is the advisory lock key for the salary change job.
+const salaryLockKey int64 = 0x5ca7_0002
+
+// NewSalaryJob returns a scheduler job that applies future-dated salary changes once they take effect.
+func NewSalaryJob(s Service) scheduler.Job {
+	return scheduler.Job{
+		Name:    "payroll-salary-changes",
+		LockKey: salaryLockKey,
+		Run: func(ctx context.Context) error {
+			applied, err := s.ApplyDueSalaryChanges(ctx)
+			if err != nil {
+				return err
+			}
+			if applied > 0 {
+				logging.FromContext(ctx).InfoContext(ctx, "applied salary changes", "count", applied)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,140 @@
+package payroll
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/genryusaishigikuni/spy_cats/internal/cat"
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
+)
+
+type Repository interface {
+	CreateSalaryChange(ctx context.Context, c *SalaryChange) error
+	UpdateSalaryChange(ctx context.Context, c *SalaryChange) error
+	ListSalaryChanges(ctx context.Context, catID uint) ([]SalaryChange, error)
+	ListPendingSalaryChanges(ctx context.Context, now time.Time) ([]SalaryChange, error)
+
+	CreateRun(ctx context.Context, r *Run) error
+	FindRunByID(ctx context.Context, id uint) (*Run, error)
+	FindRunByPeriod(ctx context.Context, period string) (*Run, error)
+	ListRuns(ctx context.Context) ([]Run, error)
+
+	// Transaction runs fn with repositories bound to a database transaction,
+	// rolled back if fn fails.
+	Transaction(ctx context.Context, fn func(tx Tx) error) error
+}
+
+// Tx holds the repositories a salary change writes through, all bound to the
+// same transaction.
+type Tx struct {
+	Salaries Repository
+	Cats     cat.Repository
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new payroll repository with the given GORM DB instance.
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// CreateSalaryChange inserts a new SalaryChange.
+func (r *repository) CreateSalaryChange(ctx context.Context, c *SalaryChange) error {
+	ctx, span := tracing.Start(ctx, "payroll.Repository.CreateSalaryChange")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Create(c).Error
+}
+
+// UpdateSalaryChange applies changes to an existing SalaryChange.
+func (r *repository) UpdateSalaryChange(ctx context.Context, c *SalaryChange) error {
+	ctx, span := tracing.Start(ctx, "payroll.Repository.UpdateSalaryChange")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Save(c).Error
+}
+
+// ListSalaryChanges returns a cat's salary history ordered by effective date.
+func (r *repository) ListSalaryChanges(ctx context.Context, catID uint) ([]SalaryChange, error) {
+	ctx, span := tracing.Start(ctx, "payroll.Repository.ListSalaryChanges")
+	defer span.End()
+
+	var changes []SalaryChange
+	if err := r.db.WithContext(ctx).Where("cat_id = ?", catID).Order("effective_from, id").Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// ListPendingSalaryChanges returns changes that have taken effect but are not applied yet.
+func (r *repository) ListPendingSalaryChanges(ctx context.Context, now time.Time) ([]SalaryChange, error) {
+	ctx, span := tracing.Start(ctx, "payroll.Repository.ListPendingSalaryChanges")
+	defer span.End()
+
+	var changes []SalaryChange
+	if err := r.db.WithContext(ctx).
+		Where("applied = ? AND effective_from <= ?", false, now).
+		Order("effective_from, id").
+		Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// CreateRun inserts a payroll Run together with its payslips.
+func (r *repository) CreateRun(ctx context.Context, run *Run) error {
+	ctx, span := tracing.Start(ctx, "payroll.Repository.CreateRun")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Create(run).Error
+}
+
+// FindRunByID retrieves a Run and its payslips.
+func (r *repository) FindRunByID(ctx context.Context, id uint) (*Run, error) {
+	ctx, span := tracing.Start(ctx, "payroll.Repository.FindRunByID")
+	defer span.End()
+
+	var run Run
+	if err := r.db.WithContext(ctx).Preload("Payslips").First(&run, id).Error; err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// FindRunByPeriod retrieves the Run for a "2006-01" period.
+func (r *repository) FindRunByPeriod(ctx context.Context, period string) (*Run, error) {
+	ctx, span := tracing.Start(ctx, "payroll.Repository.FindRunByPeriod")
+	defer span.End()
+
+	var run Run
+	if err := r.db.WithContext(ctx).Where("period = ?", period).First(&run).Error; err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// ListRuns returns all runs without their payslips, newest first.
+func (r *repository) ListRuns(ctx context.Context) ([]Run, error) {
+	ctx, span := tracing.Start(ctx, "payroll.Repository.ListRuns")
+	defer span.End()
+
+	var runs []Run
+	if err := r.db.WithContext(ctx).Order("period_start DESC").Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// Transaction runs fn in a database transaction.
+func (r *repository) Transaction(ctx context.Context, fn func(tx Tx) error) error {
+	ctx, span := tracing.Start(ctx, "payroll.Repository.Transaction")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		return fn(Tx{Salaries: &repository{db: db}, Cats: cat.NewRepository(db, nil)})
+	})
+}
@@ -0,0 +1,337 @@
+package payroll
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/genryusaishigikuni/spy_cats/internal/cat"
+	"github.com/genryusaishigikuni/spy_cats/internal/mission"
+	"github.com/genryusaishigikuni/spy_cats/pkg/clock"
+	"github.com/genryusaishigikuni/spy_cats/pkg/errcode"
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
+	"gorm.io/gorm"
+)
+
+// periodLayout formats payroll periods.
+const periodLayout = "2006-01"
+
+// Errors returned by the payroll service.
+var (
+	ErrRunNotFound    = errcode.New("payroll_run_not_found", "payroll run not found")
+	ErrNegativeSalary = errcode.New("invalid_salary", "salary cannot be negative")
+	ErrReasonRequired = errcode.New("reason_required", "a reason is required for a salary change")
+	ErrInvalidMonth   = errcode.New("invalid_period", "month must be between 1 and 12")
+	ErrPeriodOpen     = errcode.New("payroll_period_open", "cannot run payroll for a period that has not ended")
+	ErrAlreadyRun     = errcode.New("payroll_already_run", "payroll has already been run")
+)
+
+// Service defines payroll operations: salary history and monthly runs.
+type Service interface {
+	// RecordSalaryChange satisfies cat.SalaryRecorder.
+	RecordSalaryChange(ctx context.Context, catID uint, salary float64, effectiveFrom time.Time, reason string) error
+	ChangeSalary(ctx context.Context, catID uint, salary float64, effectiveFrom time.Time, reason string) (*SalaryChange, error)
+	SalaryHistory(ctx context.Context, catID uint) ([]SalaryChange, error)
+	// ApplyDueSalaryChanges copies salary changes that have taken effect onto the cats.
+	ApplyDueSalaryChanges(ctx context.Context) (int, error)
+
+	RunPayroll(ctx context.Context, year int, month time.Month) (*Run, error)
+	GetRun(ctx context.Context, id uint) (*Run, error)
+	ListRuns(ctx context.Context) ([]Run, error)
+}
+
+type service struct {
+	repo         Repository
+	catRepo      cat.Repository
+	missionRepo  mission.Repository
+	clock        clock.Clock
+	missionBonus float64
+}
+
+// NewService creates a new payroll service. missionBonus is paid per completed
+// mission, multiplied by the mission's difficulty.
+func NewService(
+	r Repository,
+	cRepo cat.Repository,
+	mRepo mission.Repository,
+	clk clock.Clock,
+	missionBonus float64,
+) Service {
+	return &service{
+		repo:         r,
+		catRepo:      cRepo,
+		missionRepo:  mRepo,
+		clock:        clk,
+		missionBonus: missionBonus,
+	}
+}
+
+// RecordSalaryChange records a salary change, discarding the created entry.
+func (s *service) RecordSalaryChange(ctx context.Context, catID uint, salary float64, effectiveFrom time.Time, reason string) error {
+	ctx, span := tracing.Start(ctx, "payroll.Service.RecordSalaryChange")
+	defer span.End()
+
+	_, err := s.ChangeSalary(ctx, catID, salary, effectiveFrom, reason)
+	return err
+}
+
+// ChangeSalary adds an entry to a cat's salary history. Changes effective now or
+// earlier are applied to the cat immediately, in the same transaction; future
+// ones are applied by the scheduler.
+func (s *service) ChangeSalary(ctx context.Context, catID uint, salary float64, effectiveFrom time.Time, reason string) (*SalaryChange, error) {
+	ctx, span := tracing.Start(ctx, "payroll.Service.ChangeSalary")
+	defer span.End()
+
+	c, err := s.catRepo.FindByID(ctx, catID)
+	if err != nil {
+		return nil, cat.ErrNotFound
+	}
+	if salary < 0 {
+		return nil, ErrNegativeSalary
+	}
+	if reason == "" {
+		return nil, ErrReasonRequired
+	}
+
+	now := s.clock.Now()
+	if effectiveFrom.IsZero() {
+		effectiveFrom = now
+	}
+
+	change := &SalaryChange{
+		CatID:         catID,
+		Salary:        salary,
+		EffectiveFrom: effectiveFrom,
+		Reason:        reason,
+	}
+	err = s.repo.Transaction(ctx, func(tx Tx) error {
+		history, err := tx.Salaries.ListSalaryChanges(ctx, catID)
+		if err != nil {
+			return err
+		}
+		change.PreviousSalary = salaryAt(history, effectiveFrom, c.Salary)
+		if err := tx.Salaries.CreateSalaryChange(ctx, change); err != nil {
+			return err
+		}
+		if effectiveFrom.After(now) {
+			return nil
+		}
+		return apply(ctx, tx, history, change)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// SalaryHistory returns a cat's salary changes ordered by effective date.
+func (s *service) SalaryHistory(ctx context.Context, catID uint) ([]SalaryChange, error) {
+	ctx, span := tracing.Start(ctx, "payroll.Service.SalaryHistory")
+	defer span.End()
+
+	if _, err := s.catRepo.FindByID(ctx, catID); err != nil {
+		return nil, cat.ErrNotFound
+	}
+	return s.repo.ListSalaryChanges(ctx, catID)
+}
+
+// ApplyDueSalaryChanges applies every pending change whose effective date has passed.
+func (s *service) ApplyDueSalaryChanges(ctx context.Context) (int, error) {
+	ctx, span := tracing.Start(ctx, "payroll.Service.ApplyDueSalaryChanges")
+	defer span.End()
+
+	pending, err := s.repo.ListPendingSalaryChanges(ctx, s.clock.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range pending {
+		change := &pending[i]
+		err := s.repo.Transaction(ctx, func(tx Tx) error {
+			history, err := tx.Salaries.ListSalaryChanges(ctx, change.CatID)
+			if err != nil {
+				return err
+			}
+			return apply(ctx, tx, history, change)
+		})
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(pending), nil
+}
+
+// RunPayroll computes base pay and mission bonuses for every cat for the given month.
+// Each month can only be run once, and only after it has ended, so that no
+// later salary change or completed mission is left out.
+func (s *service) RunPayroll(ctx context.Context, year int, month time.Month) (*Run, error) {
+	ctx, span := tracing.Start(ctx, "payroll.Service.RunPayroll")
+	defer span.End()
+
+	if month < time.January || month > time.December {
+		return nil, ErrInvalidMonth
+	}
+
+	start := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	period := start.Format(periodLayout)
+	if end.After(s.clock.Now()) {
+		return nil, ErrPeriodOpen
+	}
+
+	_, err := s.repo.FindRunByPeriod(ctx, period)
+	if err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrAlreadyRun, period)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// Retired cats are paid for the part of the period before their retirement.
+	cats, err := s.catRepo.List(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	completed, err := s.missionRepo.ListCompletedBetween(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	missionsByCat := make(map[uint][]mission.Mission)
+	for _, m := range completed {
+		catID := m.AssignedCatID()
+		missionsByCat[catID] = append(missionsByCat[catID], m)
+	}
+
+	run := &Run{Period: period, PeriodStart: start, PeriodEnd: end}
+	for _, c := range cats {
+		paidUntil := end
+		if c.RetiredAt != nil && c.RetiredAt.Before(end) {
+			if !c.RetiredAt.After(start) {
+				continue
+			}
+			paidUntil = *c.RetiredAt
+		}
+		history, err := s.repo.ListSalaryChanges(ctx, c.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		slip := Payslip{
+			CatID:             c.ID,
+			CatName:           c.Name,
+			BasePay:           roundCents(basePay(history, c.Salary, start, end, paidUntil)),
+			MissionsCompleted: len(missionsByCat[c.ID]),
+		}
+		for _, m := range missionsByCat[c.ID] {
+			slip.Bonus += s.missionBonus * float64(max(m.Difficulty, 1))
+		}
+		slip.Bonus = roundCents(slip.Bonus)
+		slip.Total = roundCents(slip.BasePay + slip.Bonus)
+
+		run.TotalPaid += slip.Total
+		run.Payslips = append(run.Payslips, slip)
+	}
+	run.TotalPaid = roundCents(run.TotalPaid)
+
+	if err := s.repo.CreateRun(ctx, run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// GetRun returns a payroll run with its payslips.
+func (s *service) GetRun(ctx context.Context, id uint) (*Run, error) {
+	ctx, span := tracing.Start(ctx, "payroll.Service.GetRun")
+	defer span.End()
+
+	run, err := s.repo.FindRunByID(ctx, id)
+	if err != nil {
+		return nil, ErrRunNotFound
+	}
+	return run, nil
+}
+
+// ListRuns returns all payroll runs.
+func (s *service) ListRuns(ctx context.Context) ([]Run, error) {
+	ctx, span := tracing.Start(ctx, "payroll.Service.ListRuns")
+	defer span.End()
+
+	return s.repo.ListRuns(ctx)
+}
+
+// apply copies a salary change onto the cat and marks it applied. A change
+// taking effect before one that is already applied (a backdated change) only
+// completes the history: the cat keeps the later salary.
+func apply(ctx context.Context, tx Tx, history []SalaryChange, change *SalaryChange) error {
+	if !superseded(history, change) {
+		c, err := tx.Cats.FindByID(ctx, change.CatID)
+		if err != nil {
+			return err
+		}
+		c.Salary = change.Salary
+		if err := tx.Cats.Update(ctx, c); err != nil {
+			return err
+		}
+	}
+
+	change.Applied = true
+	return tx.Salaries.UpdateSalaryChange(ctx, change)
+}
+
+// superseded reports whether history holds an applied change taking effect
+// after change.
+func superseded(history []SalaryChange, change *SalaryChange) bool {
+	for _, ch := range history {
+		if ch.ID != change.ID && ch.Applied && ch.EffectiveFrom.After(change.EffectiveFrom) {
+			return true
+		}
+	}
+	return false
+}
+
+// salaryAt returns the salary in effect at t according to history (ordered by
+// effective date). Cats with no history at all are paid their current salary;
+// before the first entry, the salary it replaced applies.
+func salaryAt(history []SalaryChange, t time.Time, current float64) float64 {
+	if len(history) == 0 {
+		return current
+	}
+	salary := history[0].PreviousSalary
+	for _, ch := range history {
+		if ch.EffectiveFrom.After(t) {
+			break
+		}
+		salary = ch.Salary
+	}
+	return salary
+}
+
+// basePay prorates the monthly salary over [start, end) following the salary history.
+// Only the time before until is paid, so a cat retired mid-month gets a partial salary.
+func basePay(history []SalaryChange, current float64, start, end, until time.Time) float64 {
+	total := end.Sub(start).Seconds()
+	pay := 0.0
+
+	from := start
+	salary := salaryAt(history, start, current)
+	for _, ch := range history {
+		if !ch.EffectiveFrom.After(start) {
+			continue
+		}
+		if !ch.EffectiveFrom.Before(until) {
+			break
+		}
+		pay += salary * ch.EffectiveFrom.Sub(from).Seconds() / total
+		from = ch.EffectiveFrom
+		salary = ch.Salary
+	}
+	pay += salary * until.Sub(from).Seconds() / total
+	return pay
+}
+
+// roundCents rounds an amount to two decimals.
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,267 @@
+package payroll
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/genryusaishigikuni/spy_cats/internal/cat"
+	"github.com/genryusaishigikuni/spy_cats/internal/mission"
+	"github.com/genryusaishigikuni/spy_cats/pkg/clock"
+)
+
+// fakeRepo keeps salary changes and runs in memory. Methods the tests do not
+// need are left to the embedded nil interface and panic if called.
+type fakeRepo struct {
+	Repository
+	cats    *fakeCats
+	changes []SalaryChange
+	runs    []Run
+}
+
+func (f *fakeRepo) CreateSalaryChange(_ context.Context, c *SalaryChange) error {
+	c.ID = uint(len(f.changes) + 1)
+	f.changes = append(f.changes, *c)
+	return nil
+}
+
+func (f *fakeRepo) UpdateSalaryChange(_ context.Context, c *SalaryChange) error {
+	f.changes[c.ID-1] = *c
+	return nil
+}
+
+func (f *fakeRepo) ListSalaryChanges(_ context.Context, catID uint) ([]SalaryChange, error) {
+	var out []SalaryChange
+	for _, c := range f.changes {
+		if c.CatID == catID {
+			out = append(out, c)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].EffectiveFrom.Before(out[j].EffectiveFrom) })
+	return out, nil
+}
+
+func (f *fakeRepo) FindRunByPeriod(_ context.Context, period string) (*Run, error) {
+	for i := range f.runs {
+		if f.runs[i].Period == period {
+			return &f.runs[i], nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (f *fakeRepo) CreateRun(_ context.Context, r *Run) error {
+	f.runs = append(f.runs, *r)
+	return nil
+}
+
+func (f *fakeRepo) Transaction(_ context.Context, fn func(tx Tx) error) error {
+	return fn(Tx{Salaries: f, Cats: f.cats})
+}
+
+type fakeCats struct {
+	cat.Repository
+	cats map[uint]*cat.Cat
+}
+
+func (f *fakeCats) FindByID(_ context.Context, id uint) (*cat.Cat, error) {
+	c, ok := f.cats[id]
+	if !ok {
+		return nil, errors.New("record not found")
+	}
+	cp := *c
+	return &cp, nil
+}
+
+func (f *fakeCats) Update(_ context.Context, c *cat.Cat) error {
+	cp := *c
+	f.cats[c.ID] = &cp
+	return nil
+}
+
+func (f *fakeCats) List(context.Context, bool) ([]cat.Cat, error) {
+	var out []cat.Cat
+	for _, c := range f.cats {
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+type fakeMissions struct {
+	mission.Repository
+	completed []mission.Mission
+}
+
+func (f fakeMissions) ListCompletedBetween(context.Context, time.Time, time.Time) ([]mission.Mission, error) {
+	return f.completed, nil
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func newTestService(now time.Time, cats []cat.Cat, changes []SalaryChange, completed []mission.Mission) (Service, *fakeRepo) {
+	fc := &fakeCats{cats: make(map[uint]*cat.Cat)}
+	for i := range cats {
+		c := cats[i]
+		fc.cats[c.ID] = &c
+	}
+	repo := &fakeRepo{cats: fc}
+	for i := range changes {
+		ch := changes[i]
+		_ = repo.CreateSalaryChange(context.Background(), &ch)
+	}
+	return NewService(repo, fc, fakeMissions{completed: completed}, clock.Fixed(now), 100), repo
+}
+
+func TestSalaryAt(t *testing.T) {
+	history := []SalaryChange{
+		{PreviousSalary: 1000, Salary: 1200, EffectiveFrom: date(2024, 3, 1)},
+		{PreviousSalary: 1200, Salary: 1500, EffectiveFrom: date(2024, 6, 1)},
+	}
+	for _, tc := range []struct {
+		name    string
+		history []SalaryChange
+		at      time.Time
+		want    float64
+	}{
+		{"no history", nil, date(2024, 1, 1), 900},
+		{"before first change", history, date(2024, 2, 1), 1000},
+		{"on first change", history, date(2024, 3, 1), 1200},
+		{"between changes", history, date(2024, 4, 15), 1200},
+		{"after last change", history, date(2024, 7, 1), 1500},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := salaryAt(tc.history, tc.at, 900); got != tc.want {
+				t.Errorf("salaryAt = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBasePay(t *testing.T) {
+	start, end := date(2024, 4, 1), date(2024, 5, 1) // 30 days
+	for _, tc := range []struct {
+		name    string
+		history []SalaryChange
+		until   time.Time
+		want    float64
+	}{
+		{"full month, no history", nil, end, 3000},
+		{
+			"change before the period",
+			[]SalaryChange{{PreviousSalary: 3000, Salary: 6000, EffectiveFrom: date(2024, 1, 1)}},
+			end, 6000,
+		},
+		{
+			"change mid-period",
+			[]SalaryChange{{PreviousSalary: 3000, Salary: 6000, EffectiveFrom: date(2024, 4, 11)}},
+			end, 1000 + 4000,
+		},
+		{
+			"change after the period",
+			[]SalaryChange{{PreviousSalary: 3000, Salary: 6000, EffectiveFrom: date(2024, 5, 10)}},
+			end, 3000,
+		},
+		{"retired mid-period", nil, date(2024, 4, 16), 1500},
+		{
+			"change after retirement",
+			[]SalaryChange{{PreviousSalary: 3000, Salary: 6000, EffectiveFrom: date(2024, 4, 21)}},
+			date(2024, 4, 11), 1000,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := roundCents(basePay(tc.history, 3000, start, end, tc.until)); got != tc.want {
+				t.Errorf("basePay = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRunPayroll(t *testing.T) {
+	retired := date(2024, 4, 16)
+	retiredEarlier := date(2024, 3, 20)
+	cats := []cat.Cat{
+		{ID: 1, Name: "Tom", Salary: 3000},
+		{ID: 2, Name: "Felix", Salary: 6000, RetiredAt: &retired},
+		{ID: 3, Name: "Garfield", Salary: 3000, RetiredAt: &retiredEarlier},
+	}
+	changes := []SalaryChange{
+		{CatID: 1, PreviousSalary: 1500, Salary: 3000, EffectiveFrom: date(2024, 4, 16), Applied: true},
+	}
+	catID := uint(1)
+	completed := []mission.Mission{
+		{ID: 1, CatID: &catID, Difficulty: 3},
+		{ID: 2, CatID: &catID},
+	}
+
+	s, repo := newTestService(date(2024, 5, 1), cats, changes, completed)
+	run, err := s.RunPayroll(context.Background(), 2024, time.April)
+	if err != nil {
+		t.Fatalf("RunPayroll: %v", err)
+	}
+
+	want := []Payslip{
+		{CatID: 1, CatName: "Tom", BasePay: 2250, MissionsCompleted: 2, Bonus: 400, Total: 2650},
+		{CatID: 2, CatName: "Felix", BasePay: 3000, Total: 3000},
+	}
+	if len(run.Payslips) != len(want) {
+		t.Fatalf("payslips = %+v, want %+v", run.Payslips, want)
+	}
+	for i, w := range want {
+		if run.Payslips[i] != w {
+			t.Errorf("payslip %d = %+v, want %+v", i, run.Payslips[i], w)
+		}
+	}
+	if run.TotalPaid != 5650 {
+		t.Errorf("TotalPaid = %v, want 5650", run.TotalPaid)
+	}
+	if len(repo.runs) != 1 {
+		t.Errorf("stored runs = %d, want 1", len(repo.runs))
+	}
+
+	if _, err := s.RunPayroll(context.Background(), 2024, time.April); err == nil {
+		t.Error("second run for the same period succeeded")
+	}
+}
+
+func TestRunPayrollRejectsOpenPeriod(t *testing.T) {
+	s, repo := newTestService(date(2024, 4, 30), nil, nil, nil)
+	if _, err := s.RunPayroll(context.Background(), 2024, time.April); err == nil {
+		t.Fatal("RunPayroll succeeded before the period ended")
+	}
+	if len(repo.runs) != 0 {
+		t.Errorf("stored runs = %d, want 0", len(repo.runs))
+	}
+}
+
+func TestChangeSalaryBackdated(t *testing.T) {
+	cats := []cat.Cat{{ID: 1, Name: "Tom", Salary: 3000}}
+	changes := []SalaryChange{
+		{CatID: 1, PreviousSalary: 2000, Salary: 3000, EffectiveFrom: date(2024, 5, 1), Applied: true},
+	}
+	s, repo := newTestService(date(2024, 6, 1), cats, changes, nil)
+
+	change, err := s.ChangeSalary(context.Background(), 1, 2500, date(2024, 4, 1), "late raise")
+	if err != nil {
+		t.Fatalf("ChangeSalary: %v", err)
+	}
+	if !change.Applied || change.PreviousSalary != 2000 {
+		t.Errorf("change = %+v, want applied with previous salary 2000", change)
+	}
+	if got := repo.cats.cats[1].Salary; got != 3000 {
+		t.Errorf("cat salary = %v, want the later change's 3000", got)
+	}
+
+	if _, err := s.ChangeSalary(context.Background(), 1, 3500, date(2024, 5, 15), "raise"); err != nil {
+		t.Fatalf("ChangeSalary: %v", err)
+	}
+	if got := repo.cats.cats[1].Salary; got != 3500 {
+		t.Errorf("cat salary = %v, want 3500", got)
+	}
+}
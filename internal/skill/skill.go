@@ -0,0 +1,47 @@
+package skill
+
+import "time"
+
+// DefaultCatalog is the set of skills every agency starts with.
+var DefaultCatalog = []Skill{
+	{Name: "infiltration", Description: "Entering guarded premises unnoticed"},
+	{Name: "lockpicking", Description: "Opening locks without the key"},
+	{Name: "surveillance", Description: "Observing targets over long periods"},
+	{Name: "languages", Description: "Speaking and reading foreign languages"},
+}
+
+// Skill is an entry in the agency's skill catalog.
+type Skill struct {
+	ID          uint   `gorm:"primaryKey"`
+	Name        string `gorm:"uniqueIndex"`
+	Description string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Version     uint `gorm:"not null;default:1"`
+}
+
+// CatSkill records how proficient a cat is in a skill.
+type CatSkill struct {
+	ID        uint  `gorm:"primaryKey"`
+	CatID     uint  `gorm:"uniqueIndex:idx_cat_skill"`
+	SkillID   uint  `gorm:"uniqueIndex:idx_cat_skill"`
+	Skill     Skill `gorm:"foreignKey:SkillID"`
+	Level     int   // 1 (novice) to 5 (master)
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Version   uint `gorm:"not null;default:1"`
+}
+
+// Certification is a credential held by a cat, optionally tied to a skill.
+type Certification struct {
+	ID        uint `gorm:"primaryKey"`
+	CatID     uint `gorm:"index"`
+	SkillID   *uint
+	Name      string
+	IssuedAt  time.Time
+	ExpiresAt *time.Time // null if the certification never expires
+	Expired   bool       `gorm:"-"` // computed when listed
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Version   uint `gorm:"not null;default:1"`
+}
@@ -0,0 +1,310 @@
+package skill
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/etag"
+)
+
+// Handler handles HTTP requests for skills and certifications.
+type Handler struct {
+	service Service
+}
+
+// NewHandler creates a new skill Handler.
+func NewHandler(s Service) *Handler {
+	return &Handler{service: s}
+}
+
+// RegisterRoutes sets up the skill catalog and per-cat skill endpoints.
+func (h *Handler) RegisterRoutes(r gin.IRouter) {
+	skillGroup := r.Group("/skills")
+	{
+		skillGroup.POST("", h.createSkill)                                     // POST /skills
+		skillGroup.GET("", h.listSkills)                                       // GET /skills
+		skillGroup.GET("/:id", h.getSkill)                                     // GET /skills/:id
+		skillGroup.PUT("/:id", etag.IfMatch(h.skillVersion), h.updateSkill)    // PUT /skills/:id
+		skillGroup.DELETE("/:id", etag.IfMatch(h.skillVersion), h.deleteSkill) // DELETE /skills/:id
+	}
+
+	// Per-cat proficiencies; setting a skill the cat does not have yet needs no If-Match
+	r.GET("/cats/:id/skills", h.listCatSkills)
+	r.PUT("/cats/:id/skills/:skillId", etag.IfMatch(h.catSkillVersion), h.setCatSkill)
+	r.DELETE("/cats/:id/skills/:skillId", etag.IfMatch(h.catSkillVersion), h.removeCatSkill)
+
+	// Certifications
+	r.GET("/cats/:id/certifications", h.listCertifications)
+	r.POST("/cats/:id/certifications", h.addCertification)
+	r.DELETE("/certifications/:id", etag.IfMatch(h.certificationVersion), h.deleteCertification)
+}
+
+// skillVersion looks up the version of the skill addressed by :id for If-Match checks.
+func (h *Handler) skillVersion(c *gin.Context) (interface{}, uint, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, 0, false
+	}
+	sk, err := h.service.GetSkill(c.Request.Context(), uint(id))
+	if err != nil {
+		return nil, 0, false
+	}
+	return sk, sk.Version, true
+}
+
+// catSkillVersion looks up the version of the cat's proficiency addressed by :id and :skillId.
+func (h *Handler) catSkillVersion(c *gin.Context) (interface{}, uint, bool) {
+	catID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, 0, false
+	}
+	skillID, err := strconv.Atoi(c.Param("skillId"))
+	if err != nil {
+		return nil, 0, false
+	}
+	cs, err := h.service.GetCatSkill(c.Request.Context(), uint(catID), uint(skillID))
+	if err != nil {
+		return nil, 0, false
+	}
+	return cs, cs.Version, true
+}
+
+// certificationVersion looks up the version of the certification addressed by :id.
+func (h *Handler) certificationVersion(c *gin.Context) (interface{}, uint, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, 0, false
+	}
+	cert, err := h.service.GetCertification(c.Request.Context(), uint(id))
+	if err != nil {
+		return nil, 0, false
+	}
+	return cert, cert.Version, true
+}
+
+type skillRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// createSkill handles POST /skills
+func (h *Handler) createSkill(c *gin.Context) {
+	var req skillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sk, err := h.service.CreateSkill(c.Request.Context(), req.Name, req.Description)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	etag.Set(c, sk.Version)
+	c.JSON(http.StatusCreated, sk)
+}
+
+// getSkill handles GET /skills/:id
+func (h *Handler) getSkill(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid skill ID"})
+		return
+	}
+
+	sk, err := h.service.GetSkill(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	etag.Set(c, sk.Version)
+	c.JSON(http.StatusOK, sk)
+}
+
+// listSkills handles GET /skills
+func (h *Handler) listSkills(c *gin.Context) {
+	skills, err := h.service.ListSkills(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, skills)
+}
+
+// updateSkill handles PUT /skills/:id
+func (h *Handler) updateSkill(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid skill ID"})
+		return
+	}
+
+	var req skillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sk, err := h.service.UpdateSkill(c.Request.Context(), uint(id), req.Name, req.Description)
+	if err != nil {
+		if etag.Conflict(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	etag.Set(c, sk.Version)
+	c.JSON(http.StatusOK, sk)
+}
+
+// deleteSkill handles DELETE /skills/:id
+func (h *Handler) deleteSkill(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid skill ID"})
+		return
+	}
+
+	if err := h.service.DeleteSkill(c.Request.Context(), uint(id)); err != nil {
+		if errors.Is(err, ErrSkillInUse) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// listCatSkills handles GET /cats/:id/skills
+func (h *Handler) listCatSkills(c *gin.Context) {
+	catID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cat ID"})
+		return
+	}
+
+	skills, err := h.service.ListCatSkills(c.Request.Context(), uint(catID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, skills)
+}
+
+// setCatSkill handles PUT /cats/:id/skills/:skillId
+func (h *Handler) setCatSkill(c *gin.Context) {
+	catID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cat ID"})
+		return
+	}
+	skillID, err := strconv.Atoi(c.Param("skillId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid skill ID"})
+		return
+	}
+
+	var req struct {
+		Level int `json:"level"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cs, err := h.service.SetCatSkill(c.Request.Context(), uint(catID), uint(skillID), req.Level)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	etag.Set(c, cs.Version)
+	c.JSON(http.StatusOK, cs)
+}
+
+// removeCatSkill handles DELETE /cats/:id/skills/:skillId
+func (h *Handler) removeCatSkill(c *gin.Context) {
+	catID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cat ID"})
+		return
+	}
+	skillID, err := strconv.Atoi(c.Param("skillId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid skill ID"})
+		return
+	}
+
+	if err := h.service.RemoveCatSkill(c.Request.Context(), uint(catID), uint(skillID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// listCertifications handles GET /cats/:id/certifications
+func (h *Handler) listCertifications(c *gin.Context) {
+	catID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cat ID"})
+		return
+	}
+
+	certs, err := h.service.ListCertifications(c.Request.Context(), uint(catID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, certs)
+}
+
+// addCertification handles POST /cats/:id/certifications
+func (h *Handler) addCertification(c *gin.Context) {
+	catID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cat ID"})
+		return
+	}
+
+	var req struct {
+		SkillID   *uint      `json:"skill_id"`
+		Name      string     `json:"name"`
+		IssuedAt  time.Time  `json:"issued_at"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cert, err := h.service.AddCertification(c.Request.Context(), uint(catID), req.SkillID, req.Name, req.IssuedAt, req.ExpiresAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	etag.Set(c, cert.Version)
+	c.JSON(http.StatusCreated, cert)
+}
+
+// deleteCertification handles DELETE /certifications/:id
+func (h *Handler) deleteCertification(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid certification ID"})
+		return
+	}
+
+	if err := h.service.DeleteCertification(c.Request.Context(), uint(id)); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrCertificationNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,317 @@
+package skill
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/optimistic"
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
+)
+
+type Repository interface {
+	CreateSkill(ctx context.Context, s *Skill) error
+	FindSkillByID(ctx context.Context, id uint) (*Skill, error)
+	FindSkillByName(ctx context.Context, name string) (*Skill, error)
+	ListSkills(ctx context.Context) ([]Skill, error)
+	UpdateSkill(ctx context.Context, s *Skill) error
+	// RenameSkill stores a renamed skill and rewrites the mission and template
+	// requirements naming it by its previous name.
+	RenameSkill(ctx context.Context, s *Skill, previous string) error
+	// DeleteSkill fails with ErrSkillInUse while a mission or template
+	// requires the skill.
+	DeleteSkill(ctx context.Context, id uint) error
+
+	UpsertCatSkill(ctx context.Context, cs *CatSkill) error
+	FindCatSkill(ctx context.Context, catID, skillID uint) (*CatSkill, error)
+	ListCatSkills(ctx context.Context, catID uint) ([]CatSkill, error)
+	ListCatSkillsByCatIDs(ctx context.Context, catIDs []uint) ([]CatSkill, error)
+	DeleteCatSkill(ctx context.Context, catID, skillID uint) error
+
+	CreateCertification(ctx context.Context, c *Certification) error
+	FindCertification(ctx context.Context, id uint) (*Certification, error)
+	ListCertifications(ctx context.Context, catID uint) ([]Certification, error)
+	ListCertificationsByCatIDs(ctx context.Context, catIDs []uint) ([]Certification, error)
+	DeleteCertification(ctx context.Context, id uint) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new skill repository with the given GORM DB instance.
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// CreateSkill inserts a new Skill into the catalog.
+func (r *repository) CreateSkill(ctx context.Context, s *Skill) error {
+	ctx, span := tracing.Start(ctx, "skill.Repository.CreateSkill")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Create(s).Error
+}
+
+// FindSkillByID retrieves a Skill by its primary key (ID).
+func (r *repository) FindSkillByID(ctx context.Context, id uint) (*Skill, error) {
+	ctx, span := tracing.Start(ctx, "skill.Repository.FindSkillByID")
+	defer span.End()
+
+	var s Skill
+	if err := r.db.WithContext(ctx).First(&s, id).Error; err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// FindSkillByName retrieves a Skill by its (case-insensitive) name.
+func (r *repository) FindSkillByName(ctx context.Context, name string) (*Skill, error) {
+	ctx, span := tracing.Start(ctx, "skill.Repository.FindSkillByName")
+	defer span.End()
+
+	var s Skill
+	if err := r.db.WithContext(ctx).Where("name = ?", strings.ToLower(name)).First(&s).Error; err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// ListSkills returns the whole skill catalog.
+func (r *repository) ListSkills(ctx context.Context) ([]Skill, error) {
+	ctx, span := tracing.Start(ctx, "skill.Repository.ListSkills")
+	defer span.End()
+
+	var skills []Skill
+	if err := r.db.WithContext(ctx).Order("name").Find(&skills).Error; err != nil {
+		return nil, err
+	}
+	return skills, nil
+}
+
+// UpdateSkill applies changes to an existing Skill, failing with
+// optimistic.ErrConflict if it was modified since it was read.
+func (r *repository) UpdateSkill(ctx context.Context, s *Skill) error {
+	ctx, span := tracing.Start(ctx, "skill.Repository.UpdateSkill")
+	defer span.End()
+
+	return optimistic.Update(r.db.WithContext(ctx), s, &s.Version)
+}
+
+// requirementTables are the tables whose required_skills column lists skill
+// requirements by name, as "name" or "name:level".
+var requirementTables = []string{"missions", "templates"}
+
+// requiresSkill matches rows with a requirement naming the skill. Rows without
+// requirements may hold a JSON null rather than an array.
+const requiresSkill = `EXISTS (
+	SELECT 1 FROM jsonb_array_elements_text(
+		CASE jsonb_typeof(required_skills::jsonb) WHEN 'array' THEN required_skills::jsonb ELSE '[]'::jsonb END
+	) AS req
+	WHERE split_part(req, ':', 1) = ?
+)`
+
+// requirementRow is a mission or template reduced to its skill requirements.
+type requirementRow struct {
+	ID             uint
+	RequiredSkills []string `gorm:"serializer:json"`
+}
+
+// RenameSkill updates the skill and its requirements in one transaction.
+// Soft-deleted missions are rewritten as well, so that restoring one does not
+// bring back a name the catalog no longer knows.
+func (r *repository) RenameSkill(ctx context.Context, s *Skill, previous string) error {
+	ctx, span := tracing.Start(ctx, "skill.Repository.RenameSkill")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := optimistic.Update(tx, s, &s.Version); err != nil {
+			return err
+		}
+		for _, table := range requirementTables {
+			var rows []requirementRow
+			if err := tx.Table(table).Select("id, required_skills").Where(requiresSkill, previous).Find(&rows).Error; err != nil {
+				return err
+			}
+			for _, row := range rows {
+				required, err := json.Marshal(renameRequirement(row.RequiredSkills, previous, s.Name))
+				if err != nil {
+					return err
+				}
+				if err := tx.Table(table).Where("id = ?", row.ID).Updates(map[string]interface{}{
+					"required_skills": string(required),
+					"version":         optimistic.Bump,
+				}).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// renameRequirement replaces the skill name in requirements, keeping levels.
+func renameRequirement(required []string, from, to string) []string {
+	renamed := make([]string, len(required))
+	for i, req := range required {
+		name, level, hasLevel := strings.Cut(req, ":")
+		switch {
+		case name != from:
+			renamed[i] = req
+		case hasLevel:
+			renamed[i] = to + ":" + level
+		default:
+			renamed[i] = to
+		}
+	}
+	return renamed
+}
+
+// DeleteSkill removes a Skill together with every cat's proficiency in it.
+func (r *repository) DeleteSkill(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "skill.Repository.DeleteSkill")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var sk Skill
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&sk, id).Error; err != nil {
+			return err
+		}
+		for _, table := range requirementTables {
+			var n int64
+			if err := tx.Table(table).Where(requiresSkill, sk.Name).Count(&n).Error; err != nil {
+				return err
+			}
+			if n > 0 {
+				return ErrSkillInUse
+			}
+		}
+		if err := tx.Where("skill_id = ?", id).Delete(&CatSkill{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Skill{}, id).Error
+	})
+}
+
+// UpsertCatSkill creates or updates a cat's proficiency level in a skill.
+func (r *repository) UpsertCatSkill(ctx context.Context, cs *CatSkill) error {
+	ctx, span := tracing.Start(ctx, "skill.Repository.UpsertCatSkill")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "cat_id"}, {Name: "skill_id"}},
+		DoUpdates: append(
+			clause.AssignmentColumns([]string{"level", "updated_at"}),
+			clause.Assignment{Column: clause.Column{Name: "version"}, Value: gorm.Expr("cat_skills.version + 1")},
+		),
+	}).Create(cs).Error
+}
+
+// FindCatSkill retrieves a cat's proficiency in a skill.
+func (r *repository) FindCatSkill(ctx context.Context, catID, skillID uint) (*CatSkill, error) {
+	ctx, span := tracing.Start(ctx, "skill.Repository.FindCatSkill")
+	defer span.End()
+
+	var cs CatSkill
+	if err := r.db.WithContext(ctx).Where("cat_id = ? AND skill_id = ?", catID, skillID).First(&cs).Error; err != nil {
+		return nil, err
+	}
+	return &cs, nil
+}
+
+// ListCatSkills returns a cat's skills with their catalog entries.
+func (r *repository) ListCatSkills(ctx context.Context, catID uint) ([]CatSkill, error) {
+	ctx, span := tracing.Start(ctx, "skill.Repository.ListCatSkills")
+	defer span.End()
+
+	var skills []CatSkill
+	if err := r.db.WithContext(ctx).Preload("Skill").Where("cat_id = ?", catID).Find(&skills).Error; err != nil {
+		return nil, err
+	}
+	return skills, nil
+}
+
+// ListCatSkillsByCatIDs returns the skills of any of the cats with their catalog entries.
+func (r *repository) ListCatSkillsByCatIDs(ctx context.Context, catIDs []uint) ([]CatSkill, error) {
+	ctx, span := tracing.Start(ctx, "skill.Repository.ListCatSkillsByCatIDs")
+	defer span.End()
+
+	var skills []CatSkill
+	if err := r.db.WithContext(ctx).Preload("Skill").Where("cat_id IN ?", catIDs).Find(&skills).Error; err != nil {
+		return nil, err
+	}
+	return skills, nil
+}
+
+// DeleteCatSkill removes a skill from a cat, failing with
+// gorm.ErrRecordNotFound if the cat does not have it.
+func (r *repository) DeleteCatSkill(ctx context.Context, catID, skillID uint) error {
+	ctx, span := tracing.Start(ctx, "skill.Repository.DeleteCatSkill")
+	defer span.End()
+
+	res := r.db.WithContext(ctx).Where("cat_id = ? AND skill_id = ?", catID, skillID).Delete(&CatSkill{})
+	return deleted(res)
+}
+
+// CreateCertification inserts a new Certification.
+func (r *repository) CreateCertification(ctx context.Context, c *Certification) error {
+	ctx, span := tracing.Start(ctx, "skill.Repository.CreateCertification")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Create(c).Error
+}
+
+// FindCertification retrieves a Certification by its ID.
+func (r *repository) FindCertification(ctx context.Context, id uint) (*Certification, error) {
+	ctx, span := tracing.Start(ctx, "skill.Repository.FindCertification")
+	defer span.End()
+
+	var cert Certification
+	if err := r.db.WithContext(ctx).First(&cert, id).Error; err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// ListCertifications returns a cat's certifications, most recent first.
+func (r *repository) ListCertifications(ctx context.Context, catID uint) ([]Certification, error) {
+	ctx, span := tracing.Start(ctx, "skill.Repository.ListCertifications")
+	defer span.End()
+
+	var certs []Certification
+	if err := r.db.WithContext(ctx).Where("cat_id = ?", catID).Order("issued_at DESC").Find(&certs).Error; err != nil {
+		return nil, err
+	}
+	return certs, nil
+}
+
+// ListCertificationsByCatIDs returns the certifications of any of the cats.
+func (r *repository) ListCertificationsByCatIDs(ctx context.Context, catIDs []uint) ([]Certification, error) {
+	ctx, span := tracing.Start(ctx, "skill.Repository.ListCertificationsByCatIDs")
+	defer span.End()
+
+	var certs []Certification
+	if err := r.db.WithContext(ctx).Where("cat_id IN ?", catIDs).Find(&certs).Error; err != nil {
+		return nil, err
+	}
+	return certs, nil
+}
+
+// DeleteCertification removes a Certification by its ID, failing with
+// gorm.ErrRecordNotFound if there is none.
+func (r *repository) DeleteCertification(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "skill.Repository.DeleteCertification")
+	defer span.End()
+
+	return deleted(r.db.WithContext(ctx).Delete(&Certification{}, id))
+}
+
+// deleted reports gorm.ErrRecordNotFound for a delete that matched no row.
+func deleted(res *gorm.DB) error {
+	if res.Error == nil && res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return res.Error
+}
@@ -0,0 +1,431 @@
+package skill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/genryusaishigikuni/spy_cats/internal/cat"
+	"github.com/genryusaishigikuni/spy_cats/pkg/clock"
+	"github.com/genryusaishigikuni/spy_cats/pkg/errcode"
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
+)
+
+// Errors for records that do not exist; handlers map them to 404.
+var (
+	ErrNotFound              = errcode.New("skill_not_found", "skill not found")
+	ErrCatSkillNotFound      = errcode.New("cat_skill_not_found", "cat skill not found")
+	ErrCertificationNotFound = errcode.New("certification_not_found", "certification not found")
+)
+
+// Errors for invalid or conflicting changes.
+var (
+	ErrNameRequired  = errcode.New("invalid_skill", "skill name cannot be empty")
+	ErrAlreadyExists = errcode.New("skill_exists", "skill already exists")
+	ErrInvalidLevel  = errcode.New("invalid_skill_level", "skill level must be between 1 and 5")
+	// ErrUnknownSkill and ErrInvalidRequirement name the offending requirement
+	// as details.
+	ErrUnknownSkill       = errcode.New("unknown_skill", "unknown skill")
+	ErrInvalidRequirement = errcode.New("invalid_skill_level", "invalid skill requirement")
+	ErrCertificationName  = errcode.New("invalid_certification", "certification name cannot be empty")
+	ErrCertificationDates = errcode.New("invalid_certification", "certification must expire after it is issued")
+	// ErrSkillInUse is returned when deleting a skill that missions or templates
+	// still require.
+	ErrSkillInUse = errcode.New("skill_in_use", "skill is required by a mission or template")
+)
+
+// Service defines business operations for skills, cat proficiencies and certifications.
+type Service interface {
+	CreateSkill(ctx context.Context, name, description string) (*Skill, error)
+	ListSkills(ctx context.Context) ([]Skill, error)
+	GetSkill(ctx context.Context, id uint) (*Skill, error)
+	UpdateSkill(ctx context.Context, id uint, name, description string) (*Skill, error)
+	DeleteSkill(ctx context.Context, id uint) error
+
+	SetCatSkill(ctx context.Context, catID, skillID uint, level int) (*CatSkill, error)
+	ListCatSkills(ctx context.Context, catID uint) ([]CatSkill, error)
+	GetCatSkill(ctx context.Context, catID, skillID uint) (*CatSkill, error)
+	RemoveCatSkill(ctx context.Context, catID, skillID uint) error
+
+	AddCertification(ctx context.Context, catID uint, skillID *uint, name string, issuedAt time.Time, expiresAt *time.Time) (*Certification, error)
+	ListCertifications(ctx context.Context, catID uint) ([]Certification, error)
+	GetCertification(ctx context.Context, id uint) (*Certification, error)
+	DeleteCertification(ctx context.Context, id uint) error
+
+	// NormalizeNames lowercases skill requirements and ensures each skill is in
+	// the catalog. A requirement is a skill name, optionally followed by the
+	// minimum level, as in "lockpicking:3".
+	NormalizeNames(ctx context.Context, names []string) ([]string, error)
+	// MissingSkills returns the requirements the cat does not meet.
+	MissingSkills(ctx context.Context, catID uint, required []string) ([]string, error)
+	// MissingSkillsByCat returns the requirements each cat does not meet; cats
+	// meeting all of them are left out.
+	MissingSkillsByCat(ctx context.Context, catIDs []uint, required []string) (map[uint][]string, error)
+}
+
+type service struct {
+	repo    Repository
+	catRepo cat.Repository
+	clock   clock.Clock
+}
+
+// NewService creates a new skill service with the given repositories.
+func NewService(r Repository, cRepo cat.Repository, clk clock.Clock) Service {
+	return &service{
+		repo:    r,
+		catRepo: cRepo,
+		clock:   clk,
+	}
+}
+
+// CreateSkill adds a skill to the catalog.
+func (s *service) CreateSkill(ctx context.Context, name, description string) (*Skill, error) {
+	ctx, span := tracing.Start(ctx, "skill.Service.CreateSkill")
+	defer span.End()
+
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return nil, ErrNameRequired
+	}
+	if _, err := s.repo.FindSkillByName(ctx, name); err == nil {
+		return nil, ErrAlreadyExists
+	}
+
+	sk := &Skill{Name: name, Description: description}
+	if err := s.repo.CreateSkill(ctx, sk); err != nil {
+		return nil, err
+	}
+	return sk, nil
+}
+
+// ListSkills returns the skill catalog.
+func (s *service) ListSkills(ctx context.Context) ([]Skill, error) {
+	ctx, span := tracing.Start(ctx, "skill.Service.ListSkills")
+	defer span.End()
+
+	return s.repo.ListSkills(ctx)
+}
+
+// GetSkill retrieves a catalog skill by its ID.
+func (s *service) GetSkill(ctx context.Context, id uint) (*Skill, error) {
+	ctx, span := tracing.Start(ctx, "skill.Service.GetSkill")
+	defer span.End()
+
+	sk, err := s.repo.FindSkillByID(ctx, id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	return sk, nil
+}
+
+// UpdateSkill renames or re-describes a catalog skill. Missions and templates
+// requiring the skill follow a rename.
+func (s *service) UpdateSkill(ctx context.Context, id uint, name, description string) (*Skill, error) {
+	ctx, span := tracing.Start(ctx, "skill.Service.UpdateSkill")
+	defer span.End()
+
+	sk, err := s.repo.FindSkillByID(ctx, id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return nil, ErrNameRequired
+	}
+	if existing, err := s.repo.FindSkillByName(ctx, name); err == nil && existing.ID != id {
+		return nil, ErrAlreadyExists
+	}
+
+	previous := sk.Name
+	sk.Name = name
+	sk.Description = description
+	if name != previous {
+		err = s.repo.RenameSkill(ctx, sk, previous)
+	} else {
+		err = s.repo.UpdateSkill(ctx, sk)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sk, nil
+}
+
+// DeleteSkill removes a skill from the catalog and from every cat. Skills that
+// missions or templates require cannot be deleted.
+func (s *service) DeleteSkill(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "skill.Service.DeleteSkill")
+	defer span.End()
+
+	if _, err := s.repo.FindSkillByID(ctx, id); err != nil {
+		return ErrNotFound
+	}
+	return s.repo.DeleteSkill(ctx, id)
+}
+
+// SetCatSkill sets a cat's proficiency level (1–5) in a skill.
+func (s *service) SetCatSkill(ctx context.Context, catID, skillID uint, level int) (*CatSkill, error) {
+	ctx, span := tracing.Start(ctx, "skill.Service.SetCatSkill")
+	defer span.End()
+
+	if _, err := s.catRepo.FindByID(ctx, catID); err != nil {
+		return nil, cat.ErrNotFound
+	}
+	sk, err := s.repo.FindSkillByID(ctx, skillID)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	if level < 1 || level > 5 {
+		return nil, ErrInvalidLevel
+	}
+
+	if err := s.repo.UpsertCatSkill(ctx, &CatSkill{CatID: catID, SkillID: skillID, Level: level}); err != nil {
+		return nil, err
+	}
+	// Reload: when the cat already had the skill, the existing row was updated.
+	cs, err := s.repo.FindCatSkill(ctx, catID, skillID)
+	if err != nil {
+		return nil, err
+	}
+	cs.Skill = *sk
+	return cs, nil
+}
+
+// GetCatSkill returns a cat's proficiency in one skill.
+func (s *service) GetCatSkill(ctx context.Context, catID, skillID uint) (*CatSkill, error) {
+	ctx, span := tracing.Start(ctx, "skill.Service.GetCatSkill")
+	defer span.End()
+
+	cs, err := s.repo.FindCatSkill(ctx, catID, skillID)
+	if err != nil {
+		return nil, ErrCatSkillNotFound
+	}
+	return cs, nil
+}
+
+// ListCatSkills returns a cat's skills.
+func (s *service) ListCatSkills(ctx context.Context, catID uint) ([]CatSkill, error) {
+	ctx, span := tracing.Start(ctx, "skill.Service.ListCatSkills")
+	defer span.End()
+
+	if _, err := s.catRepo.FindByID(ctx, catID); err != nil {
+		return nil, cat.ErrNotFound
+	}
+	return s.repo.ListCatSkills(ctx, catID)
+}
+
+// RemoveCatSkill removes a skill from a cat.
+func (s *service) RemoveCatSkill(ctx context.Context, catID, skillID uint) error {
+	ctx, span := tracing.Start(ctx, "skill.Service.RemoveCatSkill")
+	defer span.End()
+
+	if _, err := s.catRepo.FindByID(ctx, catID); err != nil {
+		return cat.ErrNotFound
+	}
+	err := s.repo.DeleteCatSkill(ctx, catID, skillID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrCatSkillNotFound
+	}
+	return err
+}
+
+// AddCertification records a certification for a cat.
+func (s *service) AddCertification(ctx context.Context, catID uint, skillID *uint, name string, issuedAt time.Time, expiresAt *time.Time) (*Certification, error) {
+	ctx, span := tracing.Start(ctx, "skill.Service.AddCertification")
+	defer span.End()
+
+	if _, err := s.catRepo.FindByID(ctx, catID); err != nil {
+		return nil, cat.ErrNotFound
+	}
+	if skillID != nil {
+		if _, err := s.repo.FindSkillByID(ctx, *skillID); err != nil {
+			return nil, ErrNotFound
+		}
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrCertificationName
+	}
+	if issuedAt.IsZero() {
+		issuedAt = s.clock.Now()
+	}
+	if expiresAt != nil && !expiresAt.After(issuedAt) {
+		return nil, ErrCertificationDates
+	}
+
+	cert := &Certification{
+		CatID:     catID,
+		SkillID:   skillID,
+		Name:      name,
+		IssuedAt:  issuedAt,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.repo.CreateCertification(ctx, cert); err != nil {
+		return nil, err
+	}
+	cert.Expired = s.expired(cert)
+	return cert, nil
+}
+
+// ListCertifications returns a cat's certifications, flagging expired ones.
+func (s *service) ListCertifications(ctx context.Context, catID uint) ([]Certification, error) {
+	ctx, span := tracing.Start(ctx, "skill.Service.ListCertifications")
+	defer span.End()
+
+	if _, err := s.catRepo.FindByID(ctx, catID); err != nil {
+		return nil, cat.ErrNotFound
+	}
+
+	certs, err := s.repo.ListCertifications(ctx, catID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range certs {
+		certs[i].Expired = s.expired(&certs[i])
+	}
+	return certs, nil
+}
+
+// GetCertification retrieves a certification by its ID.
+func (s *service) GetCertification(ctx context.Context, id uint) (*Certification, error) {
+	ctx, span := tracing.Start(ctx, "skill.Service.GetCertification")
+	defer span.End()
+
+	cert, err := s.repo.FindCertification(ctx, id)
+	if err != nil {
+		return nil, ErrCertificationNotFound
+	}
+	return cert, nil
+}
+
+// DeleteCertification removes a certification.
+func (s *service) DeleteCertification(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "skill.Service.DeleteCertification")
+	defer span.End()
+
+	err := s.repo.DeleteCertification(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrCertificationNotFound
+	}
+	return err
+}
+
+// NormalizeNames lowercases skill requirements and ensures each skill is in the
+// catalog. A level of 1 is left out, since any proficiency meets it.
+func (s *service) NormalizeNames(ctx context.Context, names []string) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "skill.Service.NormalizeNames")
+	defer span.End()
+
+	var normalized []string
+	for _, req := range names {
+		name, level, err := parseRequirement(req)
+		if err != nil {
+			return nil, err
+		}
+		if name == "" {
+			continue
+		}
+		if _, err := s.repo.FindSkillByName(ctx, name); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownSkill, name)
+		}
+		if level > 1 {
+			name += ":" + strconv.Itoa(level)
+		}
+		normalized = append(normalized, name)
+	}
+	return normalized, nil
+}
+
+// MissingSkills returns the requirements the cat does not meet: it lacks the
+// skill, its level is below the one required, or every certification it holds
+// in the skill has expired.
+func (s *service) MissingSkills(ctx context.Context, catID uint, required []string) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "skill.Service.MissingSkills")
+	defer span.End()
+
+	missing, err := s.MissingSkillsByCat(ctx, []uint{catID}, required)
+	if err != nil {
+		return nil, err
+	}
+	return missing[catID], nil
+}
+
+// MissingSkillsByCat checks the requirements like MissingSkills for every cat,
+// loading the skills and certifications of all of them at once.
+func (s *service) MissingSkillsByCat(ctx context.Context, catIDs []uint, required []string) (map[uint][]string, error) {
+	ctx, span := tracing.Start(ctx, "skill.Service.MissingSkillsByCat")
+	defer span.End()
+
+	if len(required) == 0 || len(catIDs) == 0 {
+		return nil, nil
+	}
+
+	skills, err := s.repo.ListCatSkillsByCatIDs(ctx, catIDs)
+	if err != nil {
+		return nil, err
+	}
+	certs, err := s.repo.ListCertificationsByCatIDs(ctx, catIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// A cat certified in a skill only keeps it while a certification is valid.
+	type catSkill struct{ catID, skillID uint }
+	certified := make(map[catSkill]bool)
+	for i := range certs {
+		if id := certs[i].SkillID; id != nil {
+			key := catSkill{certs[i].CatID, *id}
+			certified[key] = certified[key] || !s.expired(&certs[i])
+		}
+	}
+	levels := make(map[uint]map[string]int, len(catIDs))
+	for _, cs := range skills {
+		if valid, ok := certified[catSkill{cs.CatID, cs.SkillID}]; ok && !valid {
+			continue
+		}
+		if levels[cs.CatID] == nil {
+			levels[cs.CatID] = make(map[string]int)
+		}
+		levels[cs.CatID][cs.Skill.Name] = cs.Level
+	}
+
+	missing := make(map[uint][]string)
+	for _, req := range required {
+		name, level, err := parseRequirement(req)
+		if err != nil {
+			return nil, err
+		}
+		for _, catID := range catIDs {
+			if levels[catID][name] < level {
+				missing[catID] = append(missing[catID], req)
+			}
+		}
+	}
+	return missing, nil
+}
+
+// parseRequirement splits a requirement such as "lockpicking:3" into the
+// lowercased skill name and the minimum level, 1 if none is given.
+func parseRequirement(req string) (string, int, error) {
+	name, levelText, hasLevel := strings.Cut(strings.ToLower(strings.TrimSpace(req)), ":")
+	name = strings.TrimSpace(name)
+	if !hasLevel {
+		return name, 1, nil
+	}
+	level, err := strconv.Atoi(strings.TrimSpace(levelText))
+	if err != nil || level < 1 || level > 5 {
+		return "", 0, fmt.Errorf("%w: %q, the level must be between 1 and 5", ErrInvalidRequirement, req)
+	}
+	return name, level, nil
+}
+
+// expired reports whether the certification has lapsed.
+func (s *service) expired(c *Certification) bool {
+	return c.ExpiresAt != nil && !c.ExpiresAt.After(s.clock.Now())
+}
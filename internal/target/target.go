@@ -1,16 +1,24 @@
 package target
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // Target now includes Country and Notes to match the requirement
 type Target struct {
-	ID          uint `gorm:"primaryKey"`
-	MissionID   uint `gorm:"index"` // belongs to a particular mission
+	ID          uint  `gorm:"primaryKey"`
+	MissionID   uint  `gorm:"index"` // belongs to a particular mission
+	DossierID   *uint `gorm:"index"` // optional link to a cross-mission dossier
 	Name        string
 	Country     string
 	Notes       string
-	Status      string // "ONGOING" or "COMPLETED"
+	Status      string     // "ONGOING" or "COMPLETED"
+	DueAt       *time.Time // optional per-target deadline
 	CompletedAt *time.Time
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	Version     uint           `gorm:"not null;default:1"`
+	DeletedAt   gorm.DeletedAt `gorm:"index"` // soft delete, cascades to the target's notes
 }
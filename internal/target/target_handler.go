@@ -1,10 +1,13 @@
 package target
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/etag"
 )
 
 // Handler handles HTTP requests for the "target" domain.
@@ -17,14 +20,49 @@ func NewHandler(s Service) *Handler {
 }
 
 // RegisterRoutes sets up the target-related endpoints.
-func (h *Handler) RegisterRoutes(r *gin.Engine) {
+func (h *Handler) RegisterRoutes(r gin.IRouter) {
 	// POST /missions/:missionId/targets to add a target to a specific mission
 
+	// GET /targets/:id to fetch a target and its ETag
+	r.GET("/targets/:id", h.getTarget)
+
 	// DELETE /targets/:id to remove a target by its ID
-	r.DELETE("/targets/:id", h.removeTarget)
+	r.DELETE("/targets/:id", etag.IfMatch(h.targetVersion), h.removeTarget)
+
+	// POST /targets/:id/restore to undo a deletion
+	r.POST("/targets/:id/restore", h.restoreTarget)
+}
+
+// targetVersion looks up the version of the target addressed by :id for If-Match checks.
+func (h *Handler) targetVersion(c *gin.Context) (interface{}, uint, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, 0, false
+	}
+	t, err := h.service.GetTarget(c.Request.Context(), uint(id))
+	if err != nil {
+		return nil, 0, false
+	}
+	return t, t.Version, true
 }
 
-// addTarget handles POST /missions/:missionId/targets
+// getTarget handles GET /targets/:id
+func (h *Handler) getTarget(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target ID"})
+		return
+	}
+
+	t, err := h.service.GetTarget(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	etag.Set(c, t.Version)
+	c.JSON(http.StatusOK, t)
+}
 
 // removeTarget handles DELETE /targets/:id
 func (h *Handler) removeTarget(c *gin.Context) {
@@ -35,9 +73,9 @@ func (h *Handler) removeTarget(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.RemoveTarget(uint(id)); err != nil {
+	if err := h.service.RemoveTarget(c.Request.Context(), uint(id)); err != nil {
 		// If the error is "cannot delete a completed target", we return a 403 Forbidden
-		if err.Error() == "cannot delete a completed target" {
+		if errors.Is(err, ErrCompleted) {
 			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 			return
 		}
@@ -48,3 +86,25 @@ func (h *Handler) removeTarget(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Target removed successfully"})
 }
+
+// restoreTarget handles POST /targets/:id/restore
+func (h *Handler) restoreTarget(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target ID"})
+		return
+	}
+
+	t, err := h.service.RestoreTarget(c.Request.Context(), uint(id))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	etag.Set(c, t.Version)
+	c.JSON(http.StatusOK, t)
+}
@@ -1,15 +1,25 @@
 package target
 
 import (
+	"context"
+
 	"gorm.io/gorm"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/optimistic"
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
 )
 
 type Repository interface {
-	Create(t *Target) error
-	FindByID(id uint) (*Target, error)
-	FindByMissionID(missionID uint) ([]Target, error)
-	Update(t *Target) error
-	Delete(id uint) error
+	Create(ctx context.Context, t *Target) error
+	FindByID(ctx context.Context, id uint) (*Target, error)
+	// FindWithDeleted retrieves a Target whether or not it is soft-deleted.
+	FindWithDeleted(ctx context.Context, id uint) (*Target, error)
+	FindByIDs(ctx context.Context, ids []uint) ([]Target, error)
+	FindByMissionID(ctx context.Context, missionID uint) ([]Target, error)
+	FindByMissionIDs(ctx context.Context, missionIDs []uint) ([]Target, error)
+	Update(ctx context.Context, t *Target) error
+	Delete(ctx context.Context, id uint) error
+	Restore(ctx context.Context, id uint) (*Target, error)
 }
 
 type repository struct {
@@ -20,30 +30,131 @@ func NewRepository(db *gorm.DB) Repository {
 	return &repository{db: db}
 }
 
-func (r *repository) Create(t *Target) error {
-	return r.db.Create(t).Error
+func (r *repository) Create(ctx context.Context, t *Target) error {
+	ctx, span := tracing.Start(ctx, "target.Repository.Create")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Create(t).Error
 }
 
-func (r *repository) FindByID(id uint) (*Target, error) {
+func (r *repository) FindByID(ctx context.Context, id uint) (*Target, error) {
+	ctx, span := tracing.Start(ctx, "target.Repository.FindByID")
+	defer span.End()
+
+	var tgt Target
+	if err := r.db.WithContext(ctx).First(&tgt, id).Error; err != nil {
+		return nil, err
+	}
+	return &tgt, nil
+}
+
+func (r *repository) FindWithDeleted(ctx context.Context, id uint) (*Target, error) {
+	ctx, span := tracing.Start(ctx, "target.Repository.FindWithDeleted")
+	defer span.End()
+
 	var tgt Target
-	if err := r.db.First(&tgt, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).Unscoped().First(&tgt, id).Error; err != nil {
 		return nil, err
 	}
 	return &tgt, nil
 }
 
-func (r *repository) FindByMissionID(missionID uint) ([]Target, error) {
+func (r *repository) FindByMissionID(ctx context.Context, missionID uint) ([]Target, error) {
+	ctx, span := tracing.Start(ctx, "target.Repository.FindByMissionID")
+	defer span.End()
+
 	var targets []Target
-	if err := r.db.Where("mission_id = ?", missionID).Find(&targets).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("mission_id = ?", missionID).Find(&targets).Error; err != nil {
 		return nil, err
 	}
 	return targets, nil
 }
 
-func (r *repository) Update(t *Target) error {
-	return r.db.Save(t).Error
+func (r *repository) FindByIDs(ctx context.Context, ids []uint) ([]Target, error) {
+	ctx, span := tracing.Start(ctx, "target.Repository.FindByIDs")
+	defer span.End()
+
+	var targets []Target
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&targets).Error; err != nil {
+		return nil, err
+	}
+	return targets, nil
 }
 
-func (r *repository) Delete(id uint) error {
-	return r.db.Delete(&Target{}, id).Error
+func (r *repository) FindByMissionIDs(ctx context.Context, missionIDs []uint) ([]Target, error) {
+	ctx, span := tracing.Start(ctx, "target.Repository.FindByMissionIDs")
+	defer span.End()
+
+	var targets []Target
+	if err := r.db.WithContext(ctx).Where("mission_id IN ?", missionIDs).Order("id").Find(&targets).Error; err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+// Update fails with optimistic.ErrConflict if the target was modified since it was read.
+func (r *repository) Update(ctx context.Context, t *Target) error {
+	ctx, span := tracing.Start(ctx, "target.Repository.Update")
+	defer span.End()
+
+	return optimistic.Update(r.db.WithContext(ctx), t, &t.Version)
+}
+
+// Delete soft-deletes a target together with its notes. Both get the same
+// deletion time so that Restore can tell them apart from notes deleted earlier.
+func (r *repository) Delete(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "target.Repository.Delete")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := tx.NowFunc()
+		if err := tx.Table("notes").
+			Where("target_id = ? AND deleted_at IS NULL", id).
+			Update("deleted_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Target{}).Where("id = ?", id).Update("deleted_at", now).Error
+	})
+}
+
+// Restore undoes Delete for a target whose mission still exists, bringing back
+// the notes deleted along with it.
+func (r *repository) Restore(ctx context.Context, id uint) (*Target, error) {
+	ctx, span := tracing.Start(ctx, "target.Repository.Restore")
+	defer span.End()
+
+	var tgt Target
+	if err := r.db.WithContext(ctx).Unscoped().First(&tgt, id).Error; err != nil {
+		return nil, err
+	}
+	if !tgt.DeletedAt.Valid {
+		return nil, ErrNotDeleted
+	}
+
+	var live int64
+	if err := r.db.WithContext(ctx).Table("missions").
+		Where("id = ? AND deleted_at IS NULL", tgt.MissionID).
+		Count(&live).Error; err != nil {
+		return nil, err
+	}
+	if live == 0 {
+		return nil, ErrMissionDeleted
+	}
+
+	deletedAt := tgt.DeletedAt.Time
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		restored := map[string]interface{}{"deleted_at": nil, "version": optimistic.Bump}
+		if err := tx.Table("notes").
+			Where("target_id = ? AND deleted_at = ?", id, deletedAt).
+			Updates(restored).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&Target{}).Where("id = ?", id).Updates(restored).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	tgt.DeletedAt = gorm.DeletedAt{}
+	tgt.Version++
+	return &tgt, nil
 }
@@ -1,44 +1,122 @@
 package target
 
 import (
+	"context"
+	"errors"
 	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/errcode"
+	"github.com/genryusaishigikuni/spy_cats/pkg/rules"
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
+)
+
+// Errors returned by the target service.
+var (
+	ErrNotFound       = errcode.New("target_not_found", "target not found")
+	ErrCompleted      = errcode.New("target_completed", "cannot delete a completed target")
+	ErrTargetLimit    = errcode.New("target_limit_reached", "mission already has the maximum number of targets")
+	ErrNotDeleted     = errcode.New("target_not_deleted", "target is not deleted")
+	ErrMissionDeleted = errcode.New("mission_deleted", "the target's mission is deleted, restore the mission instead")
 )
 
 // Service defines business operations for the target domain.
 type Service interface {
-	RemoveTarget(id uint) error
+	GetTarget(ctx context.Context, id uint) (*Target, error)
+	// GetTargets and ListTargetsByMissions load targets in batches; unknown IDs are left out.
+	GetTargets(ctx context.Context, ids []uint) ([]Target, error)
+	ListTargetsByMissions(ctx context.Context, missionIDs []uint) ([]Target, error)
+	RemoveTarget(ctx context.Context, id uint) error
+	RestoreTarget(ctx context.Context, id uint) (*Target, error)
 }
 
 type service struct {
-	repo Repository
+	repo  Repository
+	rules rules.Rules
+}
+
+// NewService constructs a new target service with the required repository and
+// the agency rules restored targets must satisfy.
+func NewService(r Repository, rules rules.Rules) Service {
+	return &service{repo: r, rules: rules}
 }
 
-// NewService constructs a new target service with the required repository.
-func NewService(r Repository) Service {
-	return &service{repo: r}
+// GetTarget retrieves a target by its ID.
+func (s *service) GetTarget(ctx context.Context, id uint) (*Target, error) {
+	ctx, span := tracing.Start(ctx, "target.Service.GetTarget")
+	defer span.End()
+
+	t, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	return t, nil
+}
+
+// GetTargets retrieves the targets with the given IDs.
+func (s *service) GetTargets(ctx context.Context, ids []uint) ([]Target, error) {
+	ctx, span := tracing.Start(ctx, "target.Service.GetTargets")
+	defer span.End()
+
+	return s.repo.FindByIDs(ctx, ids)
 }
 
-// AddTarget adds a new target to the given mission, ensuring the mission
-// does not exceed 3 targets.
+// ListTargetsByMissions retrieves the targets of all the given missions.
+func (s *service) ListTargetsByMissions(ctx context.Context, missionIDs []uint) ([]Target, error) {
+	ctx, span := tracing.Start(ctx, "target.Service.ListTargetsByMissions")
+	defer span.End()
+
+	return s.repo.FindByMissionIDs(ctx, missionIDs)
+}
+
+// RemoveTarget soft-deletes a target and its notes by the target's ID.
+func (s *service) RemoveTarget(ctx context.Context, id uint) error {
+	ctx, span := tracing.Start(ctx, "target.Service.RemoveTarget")
+	defer span.End()
 
-// RemoveTarget removes a target by its ID.
-// RemoveTarget removes a target by its ID.
-func (s *service) RemoveTarget(id uint) error {
 	// 1) Check existence
-	t, err := s.repo.FindByID(id)
+	t, err := s.repo.FindByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
 	// 2) Validate if the target is completed
 	if t.Status == "COMPLETED" {
-		return fmt.Errorf("cannot delete a completed target")
+		return ErrCompleted
 	}
 
 	// 3) Remove the target using the repository's Delete method
-	if err := s.repo.Delete(t.ID); err != nil {
+	if err := s.repo.Delete(ctx, t.ID); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// RestoreTarget brings back a soft-deleted target and the notes deleted with it,
+// unless its mission has taken on the most targets the rules allow since.
+func (s *service) RestoreTarget(ctx context.Context, id uint) (*Target, error) {
+	ctx, span := tracing.Start(ctx, "target.Service.RestoreTarget")
+	defer span.End()
+
+	t, err := s.repo.FindWithDeleted(ctx, id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	if t.DeletedAt.Valid {
+		siblings, err := s.repo.FindByMissionID(ctx, t.MissionID)
+		if err != nil {
+			return nil, err
+		}
+		if len(siblings) >= s.rules.MaxTargetsPerMission {
+			return nil, fmt.Errorf("%w: at most %d", ErrTargetLimit, s.rules.MaxTargetsPerMission)
+		}
+	}
+
+	t, err = s.repo.Restore(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return t, err
+}
@@ -0,0 +1,254 @@
+package transfer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/genryusaishigikuni/spy_cats/internal/cat"
+	"github.com/genryusaishigikuni/spy_cats/pkg/errcode"
+)
+
+// Errors reading an import file.
+var (
+	ErrEmptyFile     = errcode.New("invalid_csv", "the CSV file is empty")
+	ErrMissingColumn = errcode.New("invalid_csv", "the CSV header is missing columns")
+)
+
+// Import modes. An atomic import stores every row or none; a best-effort import
+// stores the valid rows and reports the rest.
+const (
+	ModeAtomic     = "atomic"
+	ModeBestEffort = "best_effort"
+)
+
+// Row outcomes in an import report.
+const (
+	RowImported   = "imported"
+	RowFailed     = "failed"
+	RowRolledBack = "rolled_back" // valid, but discarded because another row of an atomic import failed
+)
+
+// Entities that can be exported.
+const (
+	EntityCats     = "cats"
+	EntityMissions = "missions"
+	EntityTargets  = "targets"
+	EntityNotes    = "notes"
+)
+
+// CatRow is one cat in an import file.
+type CatRow struct {
+	Name              string  `json:"name"`
+	Breed             string  `json:"breed"`
+	YearsOfExperience int     `json:"years_of_experience"`
+	Salary            float64 `json:"salary"`
+}
+
+// RowResult is the outcome of importing one row. Rows are numbered from 1,
+// not counting the CSV header.
+type RowResult struct {
+	Row    int             `json:"row"`
+	Status string          `json:"status"`
+	ID     uint            `json:"id,omitempty"`
+	Error  string          `json:"error,omitempty"`
+	Fields cat.FieldErrors `json:"fields,omitempty"`
+}
+
+// Report summarises an import.
+type Report struct {
+	Mode     string      `json:"mode"`
+	Total    int         `json:"total"`
+	Imported int         `json:"imported"`
+	Failed   int         `json:"failed"`
+	Rows     []RowResult `json:"rows"`
+}
+
+// CatReader reads import rows one at a time. Read returns io.EOF after the last
+// row and cat.FieldErrors for a row that cannot be parsed; reading may continue
+// after such an error. Any other error aborts the import.
+type CatReader interface {
+	Read() (CatRow, error)
+}
+
+// catColumns are the columns of a cat import CSV, in any order.
+var catColumns = []string{"name", "breed", "years_of_experience", "salary"}
+
+type csvCatReader struct {
+	r       *csv.Reader
+	columns map[string]int
+	width   int
+}
+
+// NewCSVCatReader returns a reader for CSV with a header row naming the columns.
+func NewCSVCatReader(r io.Reader) (CatReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, ErrEmptyFile
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		columns[name] = i
+	}
+	var missing []string
+	for _, name := range catColumns {
+		if _, ok := columns[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrMissingColumn, strings.Join(missing, ", "))
+	}
+	return &csvCatReader{r: cr, columns: columns, width: len(header)}, nil
+}
+
+func (r *csvCatReader) Read() (CatRow, error) {
+	record, err := r.r.Read()
+	if err != nil {
+		return CatRow{}, err
+	}
+	if len(record) != r.width {
+		return CatRow{}, cat.FieldErrors{"row": fmt.Sprintf("has %d fields, expected %d", len(record), r.width)}
+	}
+
+	field := func(name string) string { return strings.TrimSpace(record[r.columns[name]]) }
+	row := CatRow{Name: field("name"), Breed: field("breed")}
+	errs := cat.FieldErrors{}
+	if v := field("years_of_experience"); v != "" {
+		if row.YearsOfExperience, err = strconv.Atoi(v); err != nil {
+			errs["years_of_experience"] = "must be a whole number"
+		}
+	}
+	if v := field("salary"); v != "" {
+		if row.Salary, err = strconv.ParseFloat(v, 64); err != nil {
+			errs["salary"] = "must be a number"
+		}
+	}
+	if len(errs) > 0 {
+		return CatRow{}, errs
+	}
+	return row, nil
+}
+
+// maxLineSize bounds a single NDJSON line.
+const maxLineSize = 1 << 20
+
+type ndjsonCatReader struct {
+	s *bufio.Scanner
+}
+
+// NewNDJSONCatReader returns a reader for one JSON object per line. Blank lines
+// are ignored.
+func NewNDJSONCatReader(r io.Reader) CatReader {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), maxLineSize)
+	return &ndjsonCatReader{s: s}
+}
+
+func (r *ndjsonCatReader) Read() (CatRow, error) {
+	for r.s.Scan() {
+		line := strings.TrimSpace(r.s.Text())
+		if line == "" {
+			continue
+		}
+
+		var row CatRow
+		dec := json.NewDecoder(strings.NewReader(line))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&row); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) && typeErr.Field != "" {
+				return CatRow{}, cat.FieldErrors{typeErr.Field: "has the wrong type"}
+			}
+			return CatRow{}, cat.FieldErrors{"row": "is not a valid cat object: " + err.Error()}
+		}
+		return row, nil
+	}
+	if err := r.s.Err(); err != nil {
+		return CatRow{}, err
+	}
+	return CatRow{}, io.EOF
+}
+
+// RowWriter writes exported records. Header is called once before any row.
+type RowWriter interface {
+	Header(columns []string) error
+	Row(record interface{}, fields []string) error
+	Flush() error
+}
+
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+// NewCSVRowWriter writes each record's fields as a CSV line.
+func NewCSVRowWriter(w io.Writer) RowWriter {
+	return &csvRowWriter{w: csv.NewWriter(w)}
+}
+
+func (w *csvRowWriter) Header(columns []string) error { return w.w.Write(columns) }
+
+func (w *csvRowWriter) Row(_ interface{}, fields []string) error { return w.w.Write(fields) }
+
+func (w *csvRowWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+type ndjsonRowWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+// NewNDJSONRowWriter writes each record as a JSON object on its own line, in the
+// same shape the API returns it.
+func NewNDJSONRowWriter(w io.Writer) RowWriter {
+	bw := bufio.NewWriter(w)
+	return &ndjsonRowWriter{w: bw, enc: json.NewEncoder(bw)}
+}
+
+func (w *ndjsonRowWriter) Header([]string) error { return nil }
+
+func (w *ndjsonRowWriter) Row(record interface{}, _ []string) error { return w.enc.Encode(record) }
+
+func (w *ndjsonRowWriter) Flush() error { return w.w.Flush() }
+
+// Column formatting helpers for CSV exports.
+
+func formatUint(v uint) string { return strconv.FormatUint(uint64(v), 10) }
+
+func formatUintPtr(v *uint) string {
+	if v == nil {
+		return ""
+	}
+	return formatUint(*v)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatTimePtr(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return formatTime(*t)
+}
@@ -0,0 +1,107 @@
+package transfer
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/logging"
+)
+
+// Handler handles HTTP requests for bulk imports and exports.
+type Handler struct {
+	service Service
+}
+
+// NewHandler creates a new transfer Handler.
+func NewHandler(s Service) *Handler {
+	return &Handler{service: s}
+}
+
+// RegisterRoutes sets up the import and export endpoints.
+func (h *Handler) RegisterRoutes(r gin.IRouter) {
+	r.POST("/import/cats", h.importCats)   // POST /import/cats?mode=atomic|best_effort
+	r.GET("/export/:entity", h.exportRows) // GET /export/{cats,missions,targets,notes}?format=csv|ndjson
+}
+
+// importCats handles POST /import/cats. The body is CSV (text/csv) or JSON Lines
+// (application/x-ndjson), one cat per row.
+func (h *Handler) importCats(c *gin.Context) {
+	var rows CatReader
+	switch c.ContentType() {
+	case "text/csv":
+		var err error
+		if rows, err = NewCSVCatReader(c.Request.Body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	case "application/x-ndjson", "application/jsonl":
+		rows = NewNDJSONCatReader(c.Request.Body)
+	default:
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "import expects text/csv or application/x-ndjson"})
+		return
+	}
+
+	report, err := h.service.ImportCats(c.Request.Context(), rows, c.DefaultQuery("mode", ModeAtomic))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch {
+	case report.Failed == 0:
+		c.JSON(http.StatusCreated, report)
+	case report.Mode == ModeAtomic:
+		c.JSON(http.StatusUnprocessableEntity, report)
+	default:
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// exportRows handles GET /export/:entity?format=csv|ndjson&include_deleted=true
+func (h *Handler) exportRows(c *gin.Context) {
+	entity := c.Param("entity")
+	if !Exportable(entity) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "nothing to export for " + entity})
+		return
+	}
+
+	var w RowWriter
+	format := c.DefaultQuery("format", "ndjson")
+	switch format {
+	case "csv":
+		c.Header("Content-Type", "text/csv")
+		w = NewCSVRowWriter(c.Writer)
+	case "ndjson":
+		c.Header("Content-Type", "application/x-ndjson")
+		w = NewNDJSONRowWriter(c.Writer)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or ndjson"})
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename="+entity+"."+format)
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	// Once rows are on the wire the status cannot change, so a failure can only
+	// cut the export short.
+	includeDeleted := c.Query("include_deleted") == "true"
+	if err := h.service.Export(c.Request.Context(), entity, includeDeleted, &flushingRowWriter{RowWriter: w, w: c.Writer}); err != nil {
+		ctx := c.Request.Context()
+		logging.FromContext(ctx).ErrorContext(ctx, "export failed", "entity", entity, "error", err)
+	}
+}
+
+// flushingRowWriter pushes every flushed batch of rows out to the client.
+type flushingRowWriter struct {
+	RowWriter
+	w http.Flusher
+}
+
+func (f *flushingRowWriter) Flush() error {
+	if err := f.RowWriter.Flush(); err != nil {
+		return err
+	}
+	f.w.Flush()
+	return nil
+}
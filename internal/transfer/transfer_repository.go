@@ -0,0 +1,134 @@
+package transfer
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/genryusaishigikuni/spy_cats/internal/cat"
+	"github.com/genryusaishigikuni/spy_cats/internal/mission"
+	"github.com/genryusaishigikuni/spy_cats/internal/note"
+	"github.com/genryusaishigikuni/spy_cats/internal/target"
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
+)
+
+// Repository reads tables row by row for exports and runs imports in
+// transactions.
+type Repository interface {
+	// The Stream methods call fn for each row in ID order without loading the
+	// whole table. Stopping early is done by returning an error from fn.
+	StreamCats(ctx context.Context, includeRetired bool, fn func(*cat.Cat) error) error
+	StreamMissions(ctx context.Context, includeDeleted bool, fn func(*mission.Mission) error) error
+	StreamTargets(ctx context.Context, includeDeleted bool, fn func(*target.Target) error) error
+	StreamNotes(ctx context.Context, includeDeleted bool, fn func(*note.Note) error) error
+
+	// Transaction runs fn with a cat service bound to a database transaction,
+	// rolled back if fn fails.
+	Transaction(ctx context.Context, fn func(tx Tx) error) error
+}
+
+// CatServiceFactory builds a cat service on a transaction's database handle, so
+// that imported cats and their salary history are written in the import's
+// transaction.
+type CatServiceFactory func(db *gorm.DB) cat.Service
+
+// Tx is an import's transaction: the cat service writing through it and
+// savepoints to undo part of it.
+type Tx struct {
+	Cats cat.Service
+	db   *gorm.DB
+}
+
+// SavePoint marks a point the transaction can be rolled back to.
+func (tx Tx) SavePoint(name string) error {
+	return tx.db.SavePoint(name).Error
+}
+
+// RollbackTo undoes everything written since the named savepoint.
+func (tx Tx) RollbackTo(name string) error {
+	return tx.db.RollbackTo(name).Error
+}
+
+type repository struct {
+	db   *gorm.DB
+	cats CatServiceFactory
+}
+
+// NewRepository creates a new transfer repository with the given GORM DB
+// instance and the factory of its transactions' cat services.
+func NewRepository(db *gorm.DB, cats CatServiceFactory) Repository {
+	return &repository{db: db, cats: cats}
+}
+
+// StreamCats streams cats, leaving out retired ones unless includeRetired is set.
+func (r *repository) StreamCats(ctx context.Context, includeRetired bool, fn func(*cat.Cat) error) error {
+	ctx, span := tracing.Start(ctx, "transfer.Repository.StreamCats")
+	defer span.End()
+
+	query := r.db.WithContext(ctx).Model(&cat.Cat{})
+	if !includeRetired {
+		query = query.Where("status <> ?", cat.StatusRetired)
+	}
+	return stream(r.db.WithContext(ctx), query, fn)
+}
+
+// StreamMissions streams missions, including soft-deleted ones if asked to.
+func (r *repository) StreamMissions(ctx context.Context, includeDeleted bool, fn func(*mission.Mission) error) error {
+	ctx, span := tracing.Start(ctx, "transfer.Repository.StreamMissions")
+	defer span.End()
+
+	return stream(r.db.WithContext(ctx), scoped(r.db.WithContext(ctx), includeDeleted).Model(&mission.Mission{}), fn)
+}
+
+// StreamTargets streams targets, including soft-deleted ones if asked to.
+func (r *repository) StreamTargets(ctx context.Context, includeDeleted bool, fn func(*target.Target) error) error {
+	ctx, span := tracing.Start(ctx, "transfer.Repository.StreamTargets")
+	defer span.End()
+
+	return stream(r.db.WithContext(ctx), scoped(r.db.WithContext(ctx), includeDeleted).Model(&target.Target{}), fn)
+}
+
+// StreamNotes streams notes, including soft-deleted ones if asked to.
+func (r *repository) StreamNotes(ctx context.Context, includeDeleted bool, fn func(*note.Note) error) error {
+	ctx, span := tracing.Start(ctx, "transfer.Repository.StreamNotes")
+	defer span.End()
+
+	return stream(r.db.WithContext(ctx), scoped(r.db.WithContext(ctx), includeDeleted).Model(&note.Note{}), fn)
+}
+
+// Transaction runs fn in a database transaction.
+func (r *repository) Transaction(ctx context.Context, fn func(tx Tx) error) error {
+	ctx, span := tracing.Start(ctx, "transfer.Repository.Transaction")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		return fn(Tx{Cats: r.cats(db), db: db})
+	})
+}
+
+func scoped(db *gorm.DB, includeDeleted bool) *gorm.DB {
+	if includeDeleted {
+		return db.Unscoped()
+	}
+	return db
+}
+
+// stream iterates a query with a database cursor, scanning one row at a time.
+func stream[T any](db, query *gorm.DB, fn func(*T) error) error {
+	rows, err := query.Order("id").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v T
+		if err := db.ScanRows(rows, &v); err != nil {
+			return err
+		}
+		if err := fn(&v); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
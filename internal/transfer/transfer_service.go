@@ -0,0 +1,254 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/genryusaishigikuni/spy_cats/internal/cat"
+	"github.com/genryusaishigikuni/spy_cats/internal/mission"
+	"github.com/genryusaishigikuni/spy_cats/internal/note"
+	"github.com/genryusaishigikuni/spy_cats/internal/target"
+	"github.com/genryusaishigikuni/spy_cats/pkg/errcode"
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
+)
+
+// exportFlushEvery is how many rows are written between flushes of an export.
+const exportFlushEvery = 100
+
+// Errors returned by the transfer service.
+var (
+	ErrInvalidMode   = errcode.New("invalid_import_mode", "mode must be atomic or best_effort")
+	ErrUnknownExport = errcode.New("unknown_export", "unknown export")
+)
+
+// errImportFailed rolls back an atomic import in which a row failed.
+var errImportFailed = errors.New("import failed")
+
+// Service defines bulk import and export operations.
+type Service interface {
+	// ImportCats creates a cat per row through the cat service, so imported cats
+	// are validated exactly like ones created through the API.
+	ImportCats(ctx context.Context, rows CatReader, mode string) (*Report, error)
+	// Export writes every row of an entity, streaming it from the database.
+	Export(ctx context.Context, entity string, includeDeleted bool, w RowWriter) error
+}
+
+type service struct {
+	repo Repository
+}
+
+// NewService creates a new transfer service with the given repository.
+func NewService(r Repository) Service {
+	return &service{repo: r}
+}
+
+// ImportCats imports cats in atomic or best-effort mode.
+func (s *service) ImportCats(ctx context.Context, rows CatReader, mode string) (*Report, error) {
+	ctx, span := tracing.Start(ctx, "transfer.Service.ImportCats")
+	defer span.End()
+
+	switch mode {
+	case ModeAtomic:
+		return s.importAtomic(ctx, rows)
+	case ModeBestEffort:
+		return s.importBestEffort(ctx, rows)
+	default:
+		return nil, ErrInvalidMode
+	}
+}
+
+// importAtomic imports every row in one transaction. Each row runs under a
+// savepoint, so a failing row does not stop the remaining rows from being
+// checked; if any row failed, the whole transaction is rolled back.
+func (s *service) importAtomic(ctx context.Context, rows CatReader) (*Report, error) {
+	report := &Report{Mode: ModeAtomic}
+	err := s.repo.Transaction(ctx, func(tx Tx) error {
+		return eachRow(rows, report, func(row CatRow) (uint, error) {
+			if err := tx.SavePoint("import_row"); err != nil {
+				return 0, err
+			}
+			c, err := tx.Cats.CreateCat(ctx, row.Name, row.Breed, row.YearsOfExperience, row.Salary)
+			if err != nil {
+				if rbErr := tx.RollbackTo("import_row"); rbErr != nil {
+					return 0, rbErr
+				}
+				return 0, err
+			}
+			return c.ID, nil
+		}, func() error {
+			if report.Failed > 0 {
+				return errImportFailed
+			}
+			return nil
+		})
+	})
+	if err != nil && !errors.Is(err, errImportFailed) {
+		return nil, err
+	}
+
+	if report.Failed > 0 {
+		for i := range report.Rows {
+			if report.Rows[i].Status == RowImported {
+				report.Rows[i].Status = RowRolledBack
+				report.Rows[i].ID = 0
+			}
+		}
+		report.Imported = 0
+	}
+	return report, nil
+}
+
+// importBestEffort imports each row in its own transaction and keeps the ones
+// that succeed.
+func (s *service) importBestEffort(ctx context.Context, rows CatReader) (*Report, error) {
+	report := &Report{Mode: ModeBestEffort}
+	err := eachRow(rows, report, func(row CatRow) (uint, error) {
+		var id uint
+		err := s.repo.Transaction(ctx, func(tx Tx) error {
+			c, err := tx.Cats.CreateCat(ctx, row.Name, row.Breed, row.YearsOfExperience, row.Salary)
+			if err != nil {
+				return err
+			}
+			id = c.ID
+			return nil
+		})
+		return id, err
+	}, func() error { return nil })
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// eachRow reads every row, imports it and records the outcome in the report.
+// Parse and validation errors fail only their row; errors reading the input
+// abort the import. done runs after the last row.
+func eachRow(rows CatReader, report *Report, importRow func(CatRow) (uint, error), done func() error) error {
+	for n := 1; ; n++ {
+		row, err := rows.Read()
+		if err == io.EOF {
+			break
+		}
+
+		var fields cat.FieldErrors
+		if err != nil && !errors.As(err, &fields) {
+			return err
+		}
+		report.Total++
+
+		var id uint
+		if err == nil {
+			id, err = importRow(row)
+		}
+		if err != nil {
+			result := RowResult{Row: n, Status: RowFailed, Error: err.Error()}
+			if errors.As(err, &fields) {
+				result.Error = "validation failed"
+				result.Fields = fields
+			}
+			report.Rows = append(report.Rows, result)
+			report.Failed++
+			continue
+		}
+		report.Rows = append(report.Rows, RowResult{Row: n, Status: RowImported, ID: id})
+		report.Imported++
+	}
+	return done()
+}
+
+// exports lists the CSV columns of each exportable entity.
+var exports = map[string][]string{
+	EntityCats: {
+		"id", "name", "breed", "years_of_experience", "salary", "status", "retired_at",
+		"created_at", "updated_at", "version",
+	},
+	EntityMissions: {
+		"id", "cat_id", "status", "priority", "difficulty", "required_skills", "start_at", "due_at",
+		"overdue_at", "completed_at", "created_at", "updated_at", "version", "deleted_at",
+	},
+	EntityTargets: {
+		"id", "mission_id", "dossier_id", "name", "country", "notes", "status", "due_at",
+		"completed_at", "created_at", "updated_at", "version", "deleted_at",
+	},
+	EntityNotes: {
+		"id", "target_id", "content", "created_at", "updated_at", "version", "deleted_at",
+	},
+}
+
+// Exportable reports whether an entity can be exported.
+func Exportable(entity string) bool {
+	_, ok := exports[entity]
+	return ok
+}
+
+// Export writes an entity's rows to w, flushing it regularly so that large
+// tables are sent as they are read.
+func (s *service) Export(ctx context.Context, entity string, includeDeleted bool, w RowWriter) error {
+	ctx, span := tracing.Start(ctx, "transfer.Service.Export")
+	defer span.End()
+
+	columns, ok := exports[entity]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownExport, entity)
+	}
+	if err := w.Header(columns); err != nil {
+		return err
+	}
+
+	written := 0
+	write := func(record interface{}, fields []string) error {
+		if err := w.Row(record, fields); err != nil {
+			return err
+		}
+		written++
+		if written%exportFlushEvery == 0 {
+			return w.Flush()
+		}
+		return nil
+	}
+
+	var err error
+	switch entity {
+	case EntityCats:
+		err = s.repo.StreamCats(ctx, includeDeleted, func(c *cat.Cat) error {
+			return write(c, []string{
+				formatUint(c.ID), c.Name, c.Breed, strconv.Itoa(c.YearsOfExperience),
+				strconv.FormatFloat(c.Salary, 'f', 2, 64), c.Status, formatTimePtr(c.RetiredAt),
+				formatTime(c.CreatedAt), formatTime(c.UpdatedAt), formatUint(c.Version),
+			})
+		})
+	case EntityMissions:
+		err = s.repo.StreamMissions(ctx, includeDeleted, func(m *mission.Mission) error {
+			return write(m, []string{
+				formatUint(m.ID), formatUintPtr(m.CatID), m.Status, m.Priority, strconv.Itoa(m.Difficulty),
+				strings.Join(m.RequiredSkills, ";"), formatTimePtr(m.StartAt), formatTimePtr(m.DueAt),
+				formatTimePtr(m.OverdueAt), formatTimePtr(m.CompletedAt), formatTime(m.CreatedAt),
+				formatTime(m.UpdatedAt), formatUint(m.Version), formatTime(m.DeletedAt.Time),
+			})
+		})
+	case EntityTargets:
+		err = s.repo.StreamTargets(ctx, includeDeleted, func(t *target.Target) error {
+			return write(t, []string{
+				formatUint(t.ID), formatUint(t.MissionID), formatUintPtr(t.DossierID), t.Name, t.Country,
+				t.Notes, t.Status, formatTimePtr(t.DueAt), formatTimePtr(t.CompletedAt),
+				formatTime(t.CreatedAt), formatTime(t.UpdatedAt), formatUint(t.Version),
+				formatTime(t.DeletedAt.Time),
+			})
+		})
+	case EntityNotes:
+		err = s.repo.StreamNotes(ctx, includeDeleted, func(n *note.Note) error {
+			return write(n, []string{
+				formatUint(n.ID), formatUint(n.TargetID), n.Content, formatTime(n.CreatedAt),
+				formatTime(n.UpdatedAt), formatUint(n.Version), formatTime(n.DeletedAt.Time),
+			})
+		})
+	}
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -0,0 +1,136 @@
+// Package api mounts the domain handlers under versioned path prefixes. Every
+// handler serves /v1. A handler whose resources change shape in v2 implements
+// RoutesV2: its v2 routes are served under /v2 and its v1 routes are marked
+// deprecated; handlers without changes serve the same routes under /v2. For a
+// transition period, unversioned paths alias or redirect to /v1.
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/genryusaishigikuni/spy_cats/config"
+)
+
+// Version prefixes.
+const (
+	V1 = "/v1"
+	V2 = "/v2"
+)
+
+// UnversionedDeprecatedSince is when the unversioned paths were deprecated in
+// favour of /v1.
+var UnversionedDeprecatedSince = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// Routes is implemented by the domain handlers.
+type Routes interface {
+	RegisterRoutes(r gin.IRouter)
+}
+
+// RoutesFunc adapts a function to Routes, for routes without a handler type.
+type RoutesFunc func(r gin.IRouter)
+
+func (f RoutesFunc) RegisterRoutes(r gin.IRouter) {
+	f(r)
+}
+
+// RoutesV2 is implemented by handlers whose resources changed shape in v2.
+type RoutesV2 interface {
+	Routes
+	// RegisterRoutesV2 registers the v2 routes, which replace all of the v1 routes.
+	RegisterRoutesV2(r gin.IRouter)
+	// V1Deprecation returns when the v1 routes were deprecated and when they
+	// will be removed.
+	V1Deprecation() (deprecated, sunset time.Time)
+}
+
+// Mount registers the handlers under /v1 and, if any of them has v2 routes,
+// under /v2. Unversioned paths are aliases of /v1, redirects to it or not
+// served, as cfg.UnversionedRoutes says.
+func Mount(r *gin.Engine, cfg config.APIConfig, handlers ...Routes) {
+	v1 := r.Group(V1)
+	for _, h := range handlers {
+		if h2, ok := h.(RoutesV2); ok {
+			deprecated, sunset := h2.V1Deprecation()
+			h.RegisterRoutes(v1.Group("", Deprecated(deprecated, sunset, V1, V2)))
+			continue
+		}
+		h.RegisterRoutes(v1)
+	}
+
+	if hasV2(handlers) {
+		v2 := r.Group(V2)
+		for _, h := range handlers {
+			if h2, ok := h.(RoutesV2); ok {
+				h2.RegisterRoutesV2(v2)
+				continue
+			}
+			h.RegisterRoutes(v2)
+		}
+	}
+
+	var unversioned gin.IRouter
+	switch cfg.UnversionedRoutes {
+	case "alias":
+		unversioned = r.Group("", Deprecated(UnversionedDeprecatedSince, cfg.UnversionedSunset, "", V1))
+	case "redirect":
+		unversioned = r.Group("", redirect(V1))
+	default:
+		return
+	}
+	for _, h := range handlers {
+		h.RegisterRoutes(unversioned)
+	}
+}
+
+func hasV2(handlers []Routes) bool {
+	for _, h := range handlers {
+		if _, ok := h.(RoutesV2); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Deprecated marks the responses of deprecated routes with the Deprecation
+// (RFC 9745) and Sunset (RFC 8594) headers, and links to the same path under
+// the successor prefix. A zero sunset is left out.
+func Deprecated(deprecated, sunset time.Time, prefix, successor string) gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(deprecated.Unix(), 10)
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecation)
+		if !sunset.IsZero() {
+			c.Header("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		path := successor + strings.TrimPrefix(c.Request.URL.Path, prefix)
+		c.Header("Link", "<"+path+`>; rel="successor-version"`)
+		c.Next()
+	}
+}
+
+// redirect permanently redirects to the same path and query under prefix. 308
+// keeps the method and body, so writes are redirected too.
+func redirect(prefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		u := *c.Request.URL
+		u.Path = prefix + u.Path
+		u.RawPath = ""
+		c.Redirect(http.StatusPermanentRedirect, u.RequestURI())
+		c.Abort()
+	}
+}
+
+// Unversioned strips the version prefix from a route template, so that
+// "/v1/missions" and "/missions" can share settings keyed by route.
+func Unversioned(route string) string {
+	for _, prefix := range []string{V1, V2} {
+		if rest, ok := strings.CutPrefix(route, prefix); ok && (rest == "" || rest[0] == '/') {
+			return rest
+		}
+	}
+	return route
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/genryusaishigikuni/spy_cats/config"
+	"github.com/genryusaishigikuni/spy_cats/pkg/clock"
+	"github.com/genryusaishigikuni/spy_cats/pkg/idempotency"
+)
+
+// memoryKeys is an in-memory idempotency key store.
+type memoryKeys struct {
+	records map[string]*idempotency.Record
+	nextID  uint
+}
+
+func (m *memoryKeys) Reserve(_ context.Context, rec *idempotency.Record, _ time.Time) (*idempotency.Record, error) {
+	if existing, ok := m.records[rec.Principal+"\x00"+rec.Key]; ok {
+		cp := *existing
+		return &cp, nil
+	}
+	m.nextID++
+	rec.ID = m.nextID
+	cp := *rec
+	m.records[rec.Principal+"\x00"+rec.Key] = &cp
+	return nil, nil
+}
+
+func (m *memoryKeys) Complete(_ context.Context, rec *idempotency.Record) error {
+	cp := *rec
+	m.records[rec.Principal+"\x00"+rec.Key] = &cp
+	return nil
+}
+
+func (m *memoryKeys) Release(_ context.Context, rec *idempotency.Record) error {
+	delete(m.records, rec.Principal+"\x00"+rec.Key)
+	return nil
+}
+
+func (m *memoryKeys) DeleteExpired(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestRedirectedPostKeepsIdempotencyKeyUsable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(idempotency.Middleware(&memoryKeys{records: make(map[string]*idempotency.Record)}, time.Hour, clock.System()))
+
+	created := 0
+	Mount(r, config.APIConfig{UnversionedRoutes: "redirect"}, RoutesFunc(func(r gin.IRouter) {
+		r.POST("/missions", func(c *gin.Context) {
+			created++
+			c.JSON(http.StatusCreated, gin.H{"id": created})
+		})
+	}))
+
+	post := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"priority":"HIGH"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(idempotency.Header, "key-1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("/missions")
+	if w.Code != http.StatusPermanentRedirect || w.Header().Get("Location") != "/v1/missions" {
+		t.Fatalf("unversioned POST = %d to %q, want 308 to /v1/missions", w.Code, w.Header().Get("Location"))
+	}
+
+	w = post("/v1/missions")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("redirected POST = %d %s, want 201", w.Code, w.Body)
+	}
+
+	w = post("/v1/missions")
+	if w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("retried POST = %d (replayed %q), want replayed 201", w.Code, w.Header().Get("Idempotent-Replayed"))
+	}
+	if created != 1 {
+		t.Errorf("handler ran %d times, want 1", created)
+	}
+}
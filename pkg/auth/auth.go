@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Roles known to the agency.
+const (
+	RoleHandler    = "handler"
+	RoleSupervisor = "supervisor"
+)
+
+// principalKey is the gin context key under which the authenticated Principal is stored.
+const principalKey = "auth.principal"
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Name string
+	Role string
+}
+
+// ParseTokens parses a comma-separated list of "token:name:role" entries.
+func ParseTokens(spec string) (map[string]Principal, error) {
+	tokens := make(map[string]Principal)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid auth token entry %q, expected token:name:role", entry)
+		}
+		role := strings.ToLower(parts[2])
+		if role != RoleHandler && role != RoleSupervisor {
+			return nil, fmt.Errorf("unknown role %q for %s", parts[2], parts[1])
+		}
+		tokens[parts[0]] = Principal{Name: parts[1], Role: role}
+	}
+	return tokens, nil
+}
+
+// Middleware resolves the bearer token of each request to a Principal.
+// Requests without a token pass through anonymously; unknown tokens are rejected.
+func Middleware(tokens map[string]Principal) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			c.Next()
+			return
+		}
+
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		p, known := tokens[strings.TrimSpace(token)]
+		if !ok || !known {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+			return
+		}
+
+		c.Set(principalKey, p)
+		c.Next()
+	}
+}
+
+// RequireRole aborts requests whose principal does not hold the given role.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := FromContext(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			return
+		}
+		if p.Role != role {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "requires " + role + " role"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// FromContext returns the authenticated principal of the request, if any.
+func FromContext(c *gin.Context) (Principal, bool) {
+	v, ok := c.Get(principalKey)
+	if !ok {
+		return Principal{}, false
+	}
+	p, ok := v.(Principal)
+	return p, ok
+}
@@ -0,0 +1,33 @@
+package bodylimit
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/api"
+)
+
+// Middleware caps request bodies at maxBytes, or at the limit given in routes
+// for a "METHOD /route" key without the version prefix, such as larger uploads to an import endpoint.
+// Requests that declare a larger Content-Length are rejected with 413 before
+// the body is read; for others, reading past the limit fails, and the handler
+// answers 400.
+func Middleware(maxBytes int64, routes map[string]int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := maxBytes
+		if l, ok := routes[c.Request.Method+" "+api.Unversioned(c.FullPath())]; ok {
+			limit = l
+		}
+
+		if c.Request.ContentLength > limit {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": "request body is too large, at most " + strconv.FormatInt(limit, 10) + " bytes",
+			})
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
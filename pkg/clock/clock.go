@@ -0,0 +1,26 @@
+package clock
+
+import "time"
+
+// Clock supplies the current time so that time-dependent logic can be tested.
+type Clock interface {
+	Now() time.Time
+}
+
+// Func adapts an ordinary function into a Clock.
+type Func func() time.Time
+
+// Now calls f.
+func (f Func) Now() time.Time {
+	return f()
+}
+
+// System returns a Clock backed by time.Now.
+func System() Clock {
+	return Func(time.Now)
+}
+
+// Fixed returns a Clock that always reports t.
+func Fixed(t time.Time) Clock {
+	return Func(func() time.Time { return t })
+}
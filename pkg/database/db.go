@@ -1,23 +1,38 @@
 package database
 
 import (
+	"context"
 	"fmt"
-	"github.com/genryusaishigikuni/spy_cats/internal/target"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"gorm.io/driver/postgres" // or whichever driver you use
 	"gorm.io/gorm"
 
 	"github.com/genryusaishigikuni/spy_cats/config"
+	"github.com/genryusaishigikuni/spy_cats/internal/budget"
 	"github.com/genryusaishigikuni/spy_cats/internal/cat"
+	"github.com/genryusaishigikuni/spy_cats/internal/dossier"
+	"github.com/genryusaishigikuni/spy_cats/internal/history"
 	"github.com/genryusaishigikuni/spy_cats/internal/mission"
 	"github.com/genryusaishigikuni/spy_cats/internal/note"
+	"github.com/genryusaishigikuni/spy_cats/internal/payroll"
+	"github.com/genryusaishigikuni/spy_cats/internal/skill"
+	"github.com/genryusaishigikuni/spy_cats/internal/target"
+	"github.com/genryusaishigikuni/spy_cats/pkg/idempotency"
+	"github.com/genryusaishigikuni/spy_cats/pkg/logging"
+	"github.com/genryusaishigikuni/spy_cats/pkg/metrics"
+	"github.com/genryusaishigikuni/spy_cats/pkg/ratelimit"
+	"github.com/genryusaishigikuni/spy_cats/pkg/scheduler"
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
 )
 
 // Connect opens a GORM DB connection based on the provided config.DBConfig.
-func Connect(dbCfg config.DBConfig) (*gorm.DB, error) {
+// SQL statements are logged as configured by logCfg.
+func Connect(dbCfg config.DBConfig, logCfg config.LogConfig) (*gorm.DB, error) {
 	// Example DSN for Postgres; adjust for MySQL, SQLite, etc.
 	dsn := fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
@@ -28,23 +43,61 @@ func Connect(dbCfg config.DBConfig) (*gorm.DB, error) {
 		dbCfg.Name,
 	)
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger: logging.GormLogger{SampleRatio: logCfg.SQLSampleRatio, SlowThreshold: logCfg.SQLSlowThreshold},
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect database: %w", err)
 	}
+	if err := db.Use(metrics.GormPlugin{}); err != nil {
+		return nil, fmt.Errorf("failed to install query metrics: %w", err)
+	}
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		return nil, fmt.Errorf("failed to install query tracing: %w", err)
+	}
+
+	// Size the connection pool; database/sql defaults to unlimited open connections.
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(dbCfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(dbCfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(dbCfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(dbCfg.ConnMaxIdleTime)
 
 	return db, nil
 }
 
+// Close closes the database connection pool.
+func Close(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
 // RunMigrations can do any (or both) of these:
 // 1) GORM AutoMigrate
 // 2) Raw SQL Migrations
 func RunMigrations(db *gorm.DB) error {
+	// Data migrations that must happen before the schema changes, such as
+	// repairing rows that would block the foreign keys created below
+	if err := runOneOffMigrations(db); err != nil {
+		return err
+	}
+
 	// (1) GORM AutoMigrate
 	if err := autoMigrate(db); err != nil {
 		return err
 	}
 
+	// Seed reference data
+	if err := seedSkills(db); err != nil {
+		return err
+	}
+
 	// (2) Run any raw SQL files in "pkg/database/migrations/"
 	if err := runSQLMigrations(db, "pkg/database/migrations"); err != nil {
 		return err
@@ -53,14 +106,169 @@ func RunMigrations(db *gorm.DB) error {
 	return nil
 }
 
+// models are the tables managed by AutoMigrate.
+var models = []interface{}{
+	&cat.Cat{},
+	&cat.Leave{},
+	&mission.Mission{},
+	&mission.Template{},
+	&target.Target{},
+	&note.Note{},
+	&dossier.Dossier{},
+	&history.Entry{},
+	&skill.Skill{},
+	&skill.CatSkill{},
+	&skill.Certification{},
+	&payroll.SalaryChange{},
+	&payroll.Run{},
+	&payroll.Payslip{},
+	&budget.Budget{},
+	&budget.Expense{},
+	&idempotency.Record{},
+	&ratelimit.Bucket{},
+	&scheduler.LastRun{},
+	&Migration{},
+}
+
 // autoMigrate uses GORM's AutoMigrate to create/modify DB tables
 func autoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(
-		&cat.Cat{},
-		&mission.Mission{},
-		&target.Target{},
-		&note.Note{},
-	)
+	return db.AutoMigrate(models...)
+}
+
+// MigrationsApplied reports an error naming the first model whose table is missing.
+func MigrationsApplied(ctx context.Context, db *gorm.DB) error {
+	m := db.WithContext(ctx).Migrator()
+	for _, model := range models {
+		if !m.HasTable(model) {
+			return fmt.Errorf("table for %T is missing", model)
+		}
+	}
+	return nil
+}
+
+// Migration records a one-off data migration applied to the database.
+type Migration struct {
+	Name      string `gorm:"primaryKey"`
+	AppliedAt time.Time
+}
+
+// TableName keeps the table name readable.
+func (Migration) TableName() string {
+	return "schema_migrations"
+}
+
+// oneOffMigrations run in order, each at most once per database.
+var oneOffMigrations = []struct {
+	name string
+	run  func(tx *gorm.DB) error
+}{
+	{"repair_orphans", repairOrphans},
+}
+
+// runOneOffMigrations applies the one-off migrations not recorded yet. Each runs
+// in a transaction together with its record, so a failed one is retried on the
+// next start.
+func runOneOffMigrations(db *gorm.DB) error {
+	if err := db.AutoMigrate(&Migration{}); err != nil {
+		return err
+	}
+	for _, mig := range oneOffMigrations {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var applied int64
+			if err := tx.Model(&Migration{}).Where("name = ?", mig.name).Count(&applied).Error; err != nil {
+				return err
+			}
+			if applied > 0 {
+				return nil
+			}
+			slog.Info("applying one-off migration", "name", mig.name)
+			if err := mig.run(tx); err != nil {
+				return err
+			}
+			return tx.Create(&Migration{Name: mig.name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s failed: %w", mig.name, err)
+		}
+	}
+	return nil
+}
+
+// repairOrphans prepares a database written by versions without foreign keys,
+// whose hard deletes left references dangling. Optional references are
+// cleared; unassigned missions used to store cat_id 0 and now store NULL.
+// Targets and notes whose parent is gone are only reported: soft-deleting them
+// would not satisfy the foreign keys, and deleting them would lose data, so the
+// migration fails until an operator has archived or removed them.
+func repairOrphans(tx *gorm.DB) error {
+	m := tx.Migrator()
+	present := func(tables ...string) bool {
+		for _, table := range tables {
+			if !m.HasTable(table) {
+				return false
+			}
+		}
+		return true
+	}
+
+	clears := []struct {
+		tables []string
+		sql    string
+	}{
+		{[]string{"missions", "cats"}, "UPDATE missions SET cat_id = NULL WHERE cat_id NOT IN (SELECT id FROM cats)"},
+		{[]string{"targets", "dossiers"}, "UPDATE targets SET dossier_id = NULL WHERE dossier_id NOT IN (SELECT id FROM dossiers)"},
+	}
+	for _, c := range clears {
+		if !present(c.tables...) {
+			continue
+		}
+		res := tx.Exec(c.sql)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected > 0 {
+			slog.Info("cleared dangling references", "rows", res.RowsAffected, "sql", c.sql)
+		}
+	}
+
+	orphans := []struct {
+		tables []string
+		what   string
+		sql    string
+	}{
+		{[]string{"targets", "missions"}, "targets whose mission no longer exists",
+			"SELECT COUNT(*) FROM targets WHERE mission_id NOT IN (SELECT id FROM missions)"},
+		{[]string{"notes", "targets"}, "notes whose target no longer exists",
+			"SELECT COUNT(*) FROM notes WHERE target_id NOT IN (SELECT id FROM targets)"},
+	}
+	var found []string
+	for _, o := range orphans {
+		if !present(o.tables...) {
+			continue
+		}
+		var count int64
+		if err := tx.Raw(o.sql).Scan(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			slog.Warn("found orphaned rows", "rows", count, "sql", o.sql)
+			found = append(found, fmt.Sprintf("%d %s", count, o.what))
+		}
+	}
+	if len(found) > 0 {
+		return fmt.Errorf("found %s; archive or delete them, then restart", strings.Join(found, " and "))
+	}
+	return nil
+}
+
+// seedSkills makes sure the default skill catalog exists.
+func seedSkills(db *gorm.DB) error {
+	for _, s := range skill.DefaultCatalog {
+		if err := db.Where(skill.Skill{Name: s.Name}).FirstOrCreate(&s).Error; err != nil {
+			return fmt.Errorf("failed to seed skill %s: %w", s.Name, err)
+		}
+	}
+	return nil
 }
 
 // runSQLMigrations reads *.sql files from a given folder and executes them in order
@@ -71,7 +279,7 @@ func runSQLMigrations(db *gorm.DB, migrationsDir string) error {
 	}
 
 	for _, file := range files {
-		log.Printf("Applying migration: %s", file)
+		slog.Info("applying migration", "file", file)
 		contents, err := os.ReadFile(file)
 		if err != nil {
 			return fmt.Errorf("failed to read file %s: %w", file, err)
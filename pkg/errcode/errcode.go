@@ -0,0 +1,36 @@
+// Package errcode gives domain errors stable codes, so that logs and alerts
+// keep grouping an error the same way when its message is reworded.
+package errcode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// codes maps the message of every domain error to its code.
+var codes = make(map[string]string)
+
+// New returns a domain error with the given message and registers its code. It
+// is meant for package-level sentinels; errors with details wrap them as
+// fmt.Errorf("%w: details", err), keeping the sentinel's message before the
+// first colon. Sentinels of different packages may share a message, e.g.
+// "mission not found", but then they must share the code too.
+func New(code, message string) error {
+	if existing, ok := codes[message]; ok && existing != code {
+		panic(fmt.Sprintf("errcode: %q is registered as %s and %s", message, existing, code))
+	}
+	codes[message] = code
+	return errors.New(message)
+}
+
+// Lookup returns the code of the domain error an error message comes from,
+// with any details after the first colon left out, or "" if the message is not
+// a domain error's.
+func Lookup(message string) string {
+	if code, ok := codes[message]; ok {
+		return code
+	}
+	message, _, _ = strings.Cut(message, ":")
+	return codes[message]
+}
@@ -0,0 +1,39 @@
+package errcode
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	errGone := New("widget_gone", "widget not found")
+	errBusy := New("widget_busy", "widget is busy")
+	wrapped := fmt.Errorf("cannot ship the order: %w", errBusy)
+	New("order_blocked", "cannot ship the order")
+
+	for _, tc := range []struct {
+		message, want string
+	}{
+		{errGone.Error(), "widget_gone"},
+		{fmt.Errorf("%w: id 7", errGone).Error(), "widget_gone"},
+		{wrapped.Error(), "order_blocked"},
+		{"Invalid widget ID", ""},
+		{"", ""},
+	} {
+		if got := Lookup(tc.message); got != tc.want {
+			t.Errorf("Lookup(%q) = %q, want %q", tc.message, got, tc.want)
+		}
+	}
+}
+
+func TestNewRejectsConflictingCodes(t *testing.T) {
+	New("gadget_missing", "gadget not found")
+	New("gadget_missing", "gadget not found")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a message under a second code did not panic")
+		}
+	}()
+	New("gadget_gone", "gadget not found")
+}
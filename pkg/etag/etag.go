@@ -0,0 +1,195 @@
+package etag
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/optimistic"
+)
+
+// Lookup returns the record a request is about to modify and its current
+// version. found is false when the record does not exist, in which case the
+// handler decides what happens (usually 404, or creation for upserts).
+type Lookup func(c *gin.Context) (record interface{}, version uint, found bool)
+
+// Tag formats the entity tag of a record version.
+func Tag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// Set writes the ETag header for a record version.
+func Set(c *gin.Context, version uint) {
+	c.Header("ETag", Tag(version))
+}
+
+// SetDerived tags a representation that embeds a versioned record together with
+// data owned by other records, such as a dossier with its linked targets. The tag
+// changes whenever the payload does, while If-Match only compares the version.
+func SetDerived(c *gin.Context, version uint, payload interface{}) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		Set(c, version)
+		return
+	}
+	c.Header("ETag", fmt.Sprintf(`"%d.%s"`, version, digest(body)))
+}
+
+// IfMatch guards PUT, PATCH and DELETE routes against lost updates. The request
+// must carry an If-Match header (428 otherwise) naming the record's current
+// version (412 otherwise). The version is passed on in the request context, so
+// that optimistic.Update writes the record only if it is still that version.
+func IfMatch(lookup Lookup) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		record, version, found := lookup(c)
+		if !found {
+			c.Next()
+			return
+		}
+
+		header := c.GetHeader("If-Match")
+		if header == "" {
+			c.AbortWithStatusJSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header is required"})
+			return
+		}
+		if !matchesVersion(header, version) {
+			Set(c, version)
+			c.AbortWithStatusJSON(http.StatusPreconditionFailed, gin.H{"error": "resource has been modified, reload it and try again"})
+			return
+		}
+		if strings.TrimSpace(header) != "*" {
+			c.Request = c.Request.WithContext(optimistic.Expect(c.Request.Context(), record, version))
+		}
+		c.Next()
+	}
+}
+
+// Conflict reports a concurrent modification detected while writing as 412,
+// the same status IfMatch uses. It returns false for any other error.
+func Conflict(c *gin.Context, err error) bool {
+	if !errors.Is(err, optimistic.ErrConflict) {
+		return false
+	}
+	c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+	return true
+}
+
+// matchesVersion compares an If-Match header with a version. If-Match uses the
+// strong comparison, so weak tags never match.
+func matchesVersion(header string, version uint) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		tag = strings.Trim(tag, `"`)
+		if i := strings.IndexByte(tag, '.'); i >= 0 {
+			tag = tag[:i]
+		}
+		v, err := strconv.ParseUint(tag, 10, 64)
+		if err == nil && uint(v) == version {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware tags every successful GET response and answers a matching
+// If-None-Match with 304 Not Modified, so clients can poll cheaply. Handlers of
+// versioned records set their own tag; other responses are tagged with a digest
+// of their body. Handlers that flush, such as streaming exports, are passed
+// through untagged.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		w := &bufferedWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if w.streaming {
+			return
+		}
+		if w.Status() == http.StatusOK {
+			tag := w.Header().Get("ETag")
+			if tag == "" {
+				tag = `W/"` + digest(w.body.Bytes()) + `"`
+				w.Header().Set("ETag", tag)
+			}
+			if noneMatch(c.GetHeader("If-None-Match"), tag) {
+				w.ResponseWriter.WriteHeader(http.StatusNotModified)
+				w.ResponseWriter.WriteHeaderNow()
+				return
+			}
+		}
+		_, _ = w.ResponseWriter.Write(w.body.Bytes())
+	}
+}
+
+// noneMatch reports whether an If-None-Match header matches tag, using the weak
+// comparison.
+func noneMatch(header, tag string) bool {
+	if header == "" {
+		return false
+	}
+	tag = strings.TrimPrefix(tag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func digest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:16])
+}
+
+// bufferedWriter holds a GET response back until its tag is known.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	streaming bool
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	if w.streaming {
+		return w.ResponseWriter.Write(b)
+	}
+	return w.body.Write(b)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	if w.streaming {
+		return w.ResponseWriter.WriteString(s)
+	}
+	return w.body.WriteString(s)
+}
+
+// Flush gives up on tagging: whatever was buffered is sent and the rest of the
+// response goes straight through.
+func (w *bufferedWriter) Flush() {
+	if !w.streaming {
+		w.streaming = true
+		_, _ = w.ResponseWriter.Write(w.body.Bytes())
+		w.body.Reset()
+	}
+	w.ResponseWriter.Flush()
+}
@@ -0,0 +1,170 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/clock"
+)
+
+// Version is the build version, set at link time with
+// -ldflags "-X github.com/genryusaishigikuni/spy_cats/pkg/health.Version=v1.2.3".
+// Without it the VCS revision recorded by the Go toolchain is reported.
+var Version = ""
+
+// Check probes one dependency within its own timeout.
+type Check struct {
+	Name    string
+	Timeout time.Duration
+	Probe   func(ctx context.Context) error
+}
+
+// Result is the outcome of a Check.
+type Result struct {
+	Name     string `json:"name"`
+	OK       bool   `json:"ok"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Run runs the checks concurrently and reports whether all of them passed.
+func Run(ctx context.Context, checks []Check) ([]Result, bool) {
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	healthy := true
+	for _, r := range results {
+		healthy = healthy && r.OK
+	}
+	return results, healthy
+}
+
+func run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, check.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Probe(ctx)
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	r := Result{Name: check.Name, OK: err == nil, Duration: time.Since(start).Round(time.Millisecond).String()}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// Handler serves the liveness, readiness and status endpoints.
+type Handler struct {
+	db        *sql.DB
+	clock     clock.Clock
+	started   time.Time
+	readiness []Check
+	// dependencies are reported by /status but do not affect readiness.
+	dependencies []Check
+}
+
+// NewHandler creates a health Handler. Readiness checks gate /readyz;
+// dependency checks are only reported by /status.
+func NewHandler(db *sql.DB, clk clock.Clock, readiness, dependencies []Check) *Handler {
+	return &Handler{
+		db:           db,
+		clock:        clk,
+		started:      clk.Now(),
+		readiness:    readiness,
+		dependencies: dependencies,
+	}
+}
+
+// RegisterRoutes sets up the health endpoints.
+func (h *Handler) RegisterRoutes(r *gin.Engine) {
+	r.GET("/healthz", h.live)  // GET /healthz
+	r.GET("/readyz", h.ready)  // GET /readyz
+	r.GET("/status", h.status) // GET /status
+}
+
+// live handles GET /healthz. The process is alive if it can answer.
+func (h *Handler) live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// ready handles GET /readyz: 200 when every readiness check passes, 503 otherwise.
+func (h *Handler) ready(c *gin.Context) {
+	results, ready := Run(c.Request.Context(), h.readiness)
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "checks": results})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready", "checks": results})
+}
+
+// status handles GET /status with build, uptime, pool and dependency details.
+func (h *Handler) status(c *gin.Context) {
+	checks := append(append([]Check{}, h.readiness...), h.dependencies...)
+	results, healthy := Run(c.Request.Context(), checks)
+
+	stats := h.db.Stats()
+	status := "ok"
+	if !healthy {
+		status = "degraded"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":     status,
+		"version":    version(),
+		"started_at": h.started,
+		"uptime":     h.clock.Now().Sub(h.started).Round(time.Second).String(),
+		"db_pool": gin.H{
+			"max_open_connections": stats.MaxOpenConnections,
+			"open_connections":     stats.OpenConnections,
+			"in_use":               stats.InUse,
+			"idle":                 stats.Idle,
+			"wait_count":           stats.WaitCount,
+			"wait_duration":        stats.WaitDuration.String(),
+			"max_idle_closed":      stats.MaxIdleClosed,
+			"max_lifetime_closed":  stats.MaxLifetimeClosed,
+		},
+		"checks": results,
+	})
+}
+
+// version returns Version, or the VCS revision the binary was built from.
+func version() string {
+	if Version != "" {
+		return Version
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	revision, modified := "", false
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+	if revision == "" {
+		return "dev"
+	}
+	if modified {
+		revision += "-dirty"
+	}
+	return revision
+}
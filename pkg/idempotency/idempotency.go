@@ -0,0 +1,163 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/auth"
+	"github.com/genryusaishigikuni/spy_cats/pkg/clock"
+	"github.com/genryusaishigikuni/spy_cats/pkg/logging"
+)
+
+// Header is the request header carrying the client-chosen idempotency key.
+const Header = "Idempotency-Key"
+
+// maxKeyLength bounds the keys clients may send.
+const maxKeyLength = 255
+
+// Record remembers a POST request made with an idempotency key and, once it has
+// finished, the response to replay for retries. Keys are scoped per principal.
+type Record struct {
+	ID          uint   `gorm:"primaryKey"`
+	Principal   string `gorm:"uniqueIndex:idx_idempotency_key"`
+	Key         string `gorm:"uniqueIndex:idx_idempotency_key"`
+	Fingerprint string // hash of method, path and body
+	Completed   bool   // false while the first request is still being handled
+	Status      int
+	ContentType string
+	Body        []byte
+	ExpiresAt   time.Time `gorm:"index"`
+	CreatedAt   time.Time
+}
+
+// TableName keeps the table name readable.
+func (Record) TableName() string {
+	return "idempotency_keys"
+}
+
+// Middleware makes POST requests that carry an Idempotency-Key safe to retry.
+// The first request is handled normally and its response stored for ttl; a retry
+// with the same key and body gets the stored response replayed, and a retry with
+// a different body is rejected with 409. Server errors are not stored, so they
+// can be retried for real, and neither are redirects, so the request can be
+// repeated with the same key where it was redirected to.
+func Middleware(repo Repository, ttl time.Duration, clk clock.Clock) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(Header)
+		if c.Request.Method != http.MethodPost || key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "could not read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		p, _ := auth.FromContext(c)
+		rec := &Record{
+			Principal:   p.Name,
+			Key:         key,
+			Fingerprint: fingerprint(c.Request.Method, c.Request.URL.Path, body),
+			ExpiresAt:   clk.Now().Add(ttl),
+		}
+
+		existing, err := repo.Reserve(c.Request.Context(), rec, clk.Now())
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if existing != nil {
+			replay(c, existing, rec.Fingerprint)
+			return
+		}
+
+		// The key is stored or released even if the client has gone away.
+		ctx := context.WithoutCancel(c.Request.Context())
+		release := func() {
+			if err := repo.Release(ctx, rec); err != nil {
+				logging.FromContext(ctx).ErrorContext(ctx, "failed to release idempotency key", "key", key, "error", err)
+			}
+		}
+
+		w := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		defer func() {
+			c.Writer = w.ResponseWriter
+			// A panicking handler is answered with 500 by the recovery
+			// middleware upstream, so its key is released like for any
+			// server error before the panic goes on.
+			if r := recover(); r != nil {
+				release()
+				panic(r)
+			}
+		}()
+		c.Next()
+
+		if w.Status() >= http.StatusInternalServerError || isRedirect(w.Status()) {
+			release()
+			return
+		}
+		rec.Completed = true
+		rec.Status = w.Status()
+		rec.ContentType = w.Header().Get("Content-Type")
+		rec.Body = w.body.Bytes()
+		if err := repo.Complete(ctx, rec); err != nil {
+			logging.FromContext(ctx).ErrorContext(ctx, "failed to store idempotent response", "key", key, "error", err)
+		}
+	}
+}
+
+// replay answers a retried request from the stored record.
+func replay(c *gin.Context, rec *Record, fp string) {
+	switch {
+	case rec.Fingerprint != fp:
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Idempotency-Key was already used for a different request"})
+	case !rec.Completed:
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "a request with this Idempotency-Key is still in progress"})
+	default:
+		c.Header("Idempotent-Replayed", "true")
+		c.Data(rec.Status, rec.ContentType, rec.Body)
+		c.Abort()
+	}
+}
+
+func isRedirect(status int) bool {
+	return status >= http.StatusMultipleChoices && status < http.StatusBadRequest && status != http.StatusNotModified
+}
+
+func fingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recordingWriter keeps a copy of the response body as it is written.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
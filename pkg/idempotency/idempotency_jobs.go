@@ -0,0 +1,30 @@
+package idempotency
+
+import (
+	"context"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/clock"
+	"github.com/genryusaishigikuni/spy_cats/pkg/logging"
+	"github.com/genryusaishigikuni/spy_cats/pkg/scheduler"
+)
+
+// cleanupLockKey is the advisory lock key for the expired key cleanup job.
+const cleanupLockKey int64 = 0x5ca7_0003
+
+// NewCleanupJob returns a scheduler job that deletes idempotency keys whose replay window has passed.
+func NewCleanupJob(repo Repository, clk clock.Clock) scheduler.Job {
+	return scheduler.Job{
+		Name:    "idempotency-cleanup",
+		LockKey: cleanupLockKey,
+		Run: func(ctx context.Context) error {
+			deleted, err := repo.DeleteExpired(ctx, clk.Now())
+			if err != nil {
+				return err
+			}
+			if deleted > 0 {
+				logging.FromContext(ctx).InfoContext(ctx, "deleted expired idempotency keys", "count", deleted)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,80 @@
+package idempotency
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
+)
+
+type Repository interface {
+	// Reserve claims rec's key. If the key is already taken by an unexpired
+	// record, nothing is written and that record is returned instead.
+	Reserve(ctx context.Context, rec *Record, now time.Time) (*Record, error)
+	// Complete stores the response of a reserved request.
+	Complete(ctx context.Context, rec *Record) error
+	// Release gives up a reservation so the request can be retried.
+	Release(ctx context.Context, rec *Record) error
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new idempotency key repository with the given GORM DB instance.
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Reserve(ctx context.Context, rec *Record, now time.Time) (*Record, error) {
+	ctx, span := tracing.Start(ctx, "idempotency.Repository.Reserve")
+	defer span.End()
+
+	// Make room if the key's previous use has expired but not been cleaned up yet.
+	if err := r.db.WithContext(ctx).
+		Where("principal = ? AND key = ? AND expires_at <= ?", rec.Principal, rec.Key, now).
+		Delete(&Record{}).Error; err != nil {
+		return nil, err
+	}
+
+	res := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(rec)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 1 {
+		return nil, nil
+	}
+
+	var existing Record
+	if err := r.db.WithContext(ctx).Where("principal = ? AND key = ?", rec.Principal, rec.Key).First(&existing).Error; err != nil {
+		return nil, err
+	}
+	return &existing, nil
+}
+
+func (r *repository) Complete(ctx context.Context, rec *Record) error {
+	ctx, span := tracing.Start(ctx, "idempotency.Repository.Complete")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Model(rec).Select("completed", "status", "content_type", "body").Updates(rec).Error
+}
+
+func (r *repository) Release(ctx context.Context, rec *Record) error {
+	ctx, span := tracing.Start(ctx, "idempotency.Repository.Release")
+	defer span.End()
+
+	return r.db.WithContext(ctx).Delete(&Record{}, rec.ID).Error
+}
+
+// DeleteExpired removes records whose replay window has passed.
+func (r *repository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	ctx, span := tracing.Start(ctx, "idempotency.Repository.DeleteExpired")
+	defer span.End()
+
+	res := r.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&Record{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,218 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/auth"
+	"github.com/genryusaishigikuni/spy_cats/pkg/clock"
+)
+
+// memoryRepo keeps idempotency records in memory, as the database does.
+type memoryRepo struct {
+	records map[string]Record
+}
+
+func newMemoryRepo() *memoryRepo {
+	return &memoryRepo{records: make(map[string]Record)}
+}
+
+func (m *memoryRepo) Reserve(_ context.Context, rec *Record, now time.Time) (*Record, error) {
+	if existing, ok := m.records[rec.Principal+"\x00"+rec.Key]; ok && existing.ExpiresAt.After(now) {
+		return &existing, nil
+	}
+	m.records[rec.Principal+"\x00"+rec.Key] = *rec
+	return nil, nil
+}
+
+func (m *memoryRepo) Complete(_ context.Context, rec *Record) error {
+	m.records[rec.Principal+"\x00"+rec.Key] = *rec
+	return nil
+}
+
+func (m *memoryRepo) Release(_ context.Context, rec *Record) error {
+	delete(m.records, rec.Principal+"\x00"+rec.Key)
+	return nil
+}
+
+func (m *memoryRepo) DeleteExpired(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+// testServer counts the requests that reach POST /missions, which answers with
+// the status in the "status" query parameter, 201 by default.
+type testServer struct {
+	router *gin.Engine
+	repo   *memoryRepo
+	now    time.Time
+	calls  int
+}
+
+func newTestServer() *testServer {
+	gin.SetMode(gin.TestMode)
+	s := &testServer{
+		router: gin.New(),
+		repo:   newMemoryRepo(),
+		now:    time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+	s.router.Use(gin.CustomRecovery(func(c *gin.Context, _ interface{}) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	s.router.Use(auth.Middleware(map[string]auth.Principal{
+		"tom-token":   {Name: "tom", Role: auth.RoleHandler},
+		"felix-token": {Name: "felix", Role: auth.RoleHandler},
+	}))
+	s.router.Use(Middleware(s.repo, time.Hour, clock.Func(func() time.Time { return s.now })))
+	s.router.POST("/missions", func(c *gin.Context) {
+		s.calls++
+		switch c.Query("status") {
+		case "500":
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database is down"})
+		case "panic":
+			panic("handler bug")
+		default:
+			c.JSON(http.StatusCreated, gin.H{"id": s.calls})
+		}
+	})
+	return s
+}
+
+func (s *testServer) post(token, key, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	if key != "" {
+		req.Header.Set(Header, key)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestReplay(t *testing.T) {
+	s := newTestServer()
+
+	first := s.post("tom-token", "key-1", "/missions", `{"priority":"HIGH"}`)
+	if first.Code != http.StatusCreated || first.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("first POST = %d (replayed %q), want a fresh 201", first.Code, first.Header().Get("Idempotent-Replayed"))
+	}
+
+	retry := s.post("tom-token", "key-1", "/missions", `{"priority":"HIGH"}`)
+	if retry.Code != http.StatusCreated || retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry = %d (replayed %q), want a replayed 201", retry.Code, retry.Header().Get("Idempotent-Replayed"))
+	}
+	if retry.Body.String() != first.Body.String() || retry.Header().Get("Content-Type") != first.Header().Get("Content-Type") {
+		t.Errorf("retry answered %q as %q, want %q as %q", retry.Body, retry.Header().Get("Content-Type"),
+			first.Body, first.Header().Get("Content-Type"))
+	}
+	if s.calls != 1 {
+		t.Errorf("handler ran %d times, want 1", s.calls)
+	}
+}
+
+func TestReplayAfterClientError(t *testing.T) {
+	s := newTestServer()
+	s.router.POST("/cats", func(c *gin.Context) {
+		s.calls++
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+	})
+
+	s.post("tom-token", "key-1", "/cats", `{}`)
+	if w := s.post("tom-token", "key-1", "/cats", `{}`); w.Code != http.StatusBadRequest || w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry = %d (replayed %q), want a replayed 400", w.Code, w.Header().Get("Idempotent-Replayed"))
+	}
+	if s.calls != 1 {
+		t.Errorf("handler ran %d times, want 1", s.calls)
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	s := newTestServer()
+	s.post("tom-token", "key-1", "/missions", `{"priority":"HIGH"}`)
+
+	if w := s.post("tom-token", "key-1", "/missions", `{"priority":"LOW"}`); w.Code != http.StatusConflict {
+		t.Errorf("same key with another body = %d, want 409", w.Code)
+	}
+	// The query string is not part of the fingerprint.
+	if w := s.post("tom-token", "key-1", "/missions?status=201", `{"priority":"HIGH"}`); w.Code != http.StatusCreated {
+		t.Errorf("same key and body with a query = %d, want the replayed 201", w.Code)
+	}
+
+	// The first request with key-2 is still being handled.
+	s.repo.records["tom\x00key-2"] = Record{
+		Principal:   "tom",
+		Key:         "key-2",
+		Fingerprint: fingerprint(http.MethodPost, "/missions", []byte(`{}`)),
+		ExpiresAt:   s.now.Add(time.Hour),
+	}
+	if w := s.post("tom-token", "key-2", "/missions", `{}`); w.Code != http.StatusConflict {
+		t.Errorf("retry while in progress = %d, want 409", w.Code)
+	}
+	if s.calls != 1 {
+		t.Errorf("handler ran %d times, want 1", s.calls)
+	}
+}
+
+func TestKeysArePerPrincipal(t *testing.T) {
+	s := newTestServer()
+	s.post("tom-token", "key-1", "/missions", `{}`)
+	if w := s.post("felix-token", "key-1", "/missions", `{}`); w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("another principal's POST = %d (replayed %q), want a fresh 201", w.Code, w.Header().Get("Idempotent-Replayed"))
+	}
+	if s.calls != 2 {
+		t.Errorf("handler ran %d times, want 2", s.calls)
+	}
+}
+
+func TestKeysAreReleased(t *testing.T) {
+	for _, tc := range []struct {
+		name, path string
+		want       int
+	}{
+		{"server error", "/missions?status=500", http.StatusInternalServerError},
+		{"panic", "/missions?status=panic", http.StatusInternalServerError},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newTestServer()
+			if w := s.post("tom-token", "key-1", tc.path, `{}`); w.Code != tc.want {
+				t.Fatalf("first POST = %d, want %d", w.Code, tc.want)
+			}
+			if w := s.post("tom-token", "key-1", "/missions", `{}`); w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" {
+				t.Errorf("retry = %d (replayed %q), want a fresh 201", w.Code, w.Header().Get("Idempotent-Replayed"))
+			}
+			if s.calls != 2 {
+				t.Errorf("handler ran %d times, want 2", s.calls)
+			}
+		})
+	}
+}
+
+func TestKeysExpire(t *testing.T) {
+	s := newTestServer()
+	s.post("tom-token", "key-1", "/missions", `{}`)
+	s.now = s.now.Add(time.Hour)
+	if w := s.post("tom-token", "key-1", "/missions", `{}`); w.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("an expired key was replayed")
+	}
+	if s.calls != 2 {
+		t.Errorf("handler ran %d times, want 2", s.calls)
+	}
+}
+
+func TestRequestsWithoutKey(t *testing.T) {
+	s := newTestServer()
+	s.post("tom-token", "", "/missions", `{}`)
+	s.post("tom-token", "", "/missions", `{}`)
+	if s.calls != 2 {
+		t.Errorf("handler ran %d times, want 2", s.calls)
+	}
+	if w := s.post("tom-token", strings.Repeat("k", maxKeyLength+1), "/missions", `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("overlong key = %d, want 400", w.Code)
+	}
+}
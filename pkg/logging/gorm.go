@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"regexp"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GormLogger logs SQL statements through the logger of the statement's
+// context. Bound values are never logged. Failed and slow statements are
+// always logged; others only for the sampled fraction.
+type GormLogger struct {
+	SampleRatio   float64
+	SlowThreshold time.Duration
+}
+
+var _ gormlogger.Interface = GormLogger{}
+
+// placeholder matches Postgres bind parameters.
+var placeholder = regexp.MustCompile(`\$\d+`)
+
+// ParamsFilter implements gorm.ParamsFilter by dropping the bound values.
+// The placeholders are rewritten to "?" so that GORM does not substitute them.
+func (l GormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return placeholder.ReplaceAllString(sql, "?"), nil
+}
+
+// LogMode implements gormlogger.Interface; the level comes from the slog logger.
+func (l GormLogger) LogMode(gormlogger.LogLevel) gormlogger.Interface {
+	return l
+}
+
+func (l GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	FromContext(ctx).InfoContext(ctx, msg, "args", args)
+}
+
+func (l GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	FromContext(ctx).WarnContext(ctx, msg, "args", args)
+}
+
+func (l GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	FromContext(ctx).ErrorContext(ctx, msg, "args", args)
+}
+
+// Trace logs one executed statement.
+func (l GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	elapsed := time.Since(begin)
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+	slow := l.SlowThreshold > 0 && elapsed > l.SlowThreshold
+	if !failed && !slow && (l.SampleRatio <= 0 || rand.Float64() >= l.SampleRatio) {
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []slog.Attr{
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Float64("duration_ms", milliseconds(elapsed)),
+	}
+	logger := FromContext(ctx)
+	switch {
+	case failed:
+		logger.LogAttrs(ctx, slog.LevelError, "sql failed", append(attrs, slog.String("error", err.Error()))...)
+	case slow:
+		logger.LogAttrs(ctx, slog.LevelWarn, "slow sql", attrs...)
+	default:
+		logger.LogAttrs(ctx, slog.LevelInfo, "sql", attrs...)
+	}
+}
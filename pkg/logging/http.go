@@ -0,0 +1,165 @@
+package logging
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/auth"
+	"github.com/genryusaishigikuni/spy_cats/pkg/errcode"
+)
+
+// RequestIDHeader carries the request ID in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client-supplied request IDs.
+const maxRequestIDLength = 128
+
+// maxErrorBody is how much of an error response is kept to log its message.
+const maxErrorBody = 4 << 10
+
+// Middleware assigns each request an ID, taken from X-Request-ID when the
+// client sends a usable one and generated otherwise, and echoes it in the
+// response. The request's context carries a logger with the ID, method and
+// route; after the request, one access line is logged with the status,
+// duration and, for failed requests, the error message and, for domain errors,
+// their code (see errcode).
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Header(RequestIDHeader, id)
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		logger := slog.Default().With("request_id", id, "method", c.Request.Method, "route", route)
+		c.Request = c.Request.WithContext(WithLogger(c.Request.Context(), logger))
+
+		w := &errorCapture{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		status := c.Writer.Status()
+		attrs := []slog.Attr{
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("duration_ms", milliseconds(time.Since(start))),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if msg := w.message(); msg != "" {
+			attrs = append(attrs, slog.String("error", msg))
+			if code := errcode.Lookup(msg); code != "" {
+				attrs = append(attrs, slog.String("error_code", code))
+			}
+		}
+
+		lvl := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			lvl = slog.LevelError
+		case status >= http.StatusBadRequest:
+			lvl = slog.LevelWarn
+		}
+		// Read the logger back: later middleware adds the principal to it.
+		ctx := c.Request.Context()
+		FromContext(ctx).LogAttrs(ctx, lvl, "request", attrs...)
+	}
+}
+
+// Principal adds the authenticated principal to the request's logger. It must
+// run after auth.Middleware.
+func Principal() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if p, ok := auth.FromContext(c); ok {
+			ctx := c.Request.Context()
+			logger := FromContext(ctx).With("principal", p.Name, "role", p.Role)
+			c.Request = c.Request.WithContext(WithLogger(ctx, logger))
+		}
+		c.Next()
+	}
+}
+
+// Recovery turns panics into 500 responses and logs them with their stack.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err interface{}) {
+		ctx := c.Request.Context()
+		FromContext(ctx).ErrorContext(ctx, "panic while serving request", "panic", err, "stack", string(debug.Stack()))
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	})
+}
+
+// validRequestID accepts short IDs of printable ASCII, so that client input
+// cannot forge log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// errorCapture keeps the start of error responses so that their message can
+// be logged.
+type errorCapture struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *errorCapture) Write(b []byte) (int, error) {
+	w.capture(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *errorCapture) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *errorCapture) capture(b []byte) {
+	if w.Status() < http.StatusBadRequest || w.body.Len() >= maxErrorBody {
+		return
+	}
+	if room := maxErrorBody - w.body.Len(); len(b) > room {
+		b = b[:room]
+	}
+	w.body.Write(b)
+}
+
+// message returns the "error" field of a captured JSON error response.
+func (w *errorCapture) message() string {
+	if w.body.Len() == 0 {
+		return ""
+	}
+	var resp struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(w.body.Bytes(), &resp) != nil {
+		return ""
+	}
+	return resp.Error
+}
@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every application metric.
+const namespace = "spy_cats"
+
+// Registry holds the application's metrics, plus the Go runtime and process collectors.
+var Registry = prometheus.NewRegistry()
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// RegisterDBStats exports the connection pool statistics of db as gauges and counters.
+func RegisterDBStats(db *sql.DB) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, namespace))
+}
+
+// Handler serves GET /metrics in the Prometheus exposition format. A collector
+// that fails is reported but the other metrics are still served.
+func Handler() gin.HandlerFunc {
+	h := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry, ErrorHandling: promhttp.ContinueOnError})
+	return gin.WrapH(h)
+}
+
+// Client instruments an outbound HTTP client with request counters and latencies
+// labelled by the name of the remote service. Requests that fail before a
+// response arrives, such as timeouts, are counted with code "error".
+func Client(client *http.Client, service string) *http.Client {
+	next := client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	client.Transport = &instrumentedTransport{next: next, service: service}
+	return client
+}
+
+type instrumentedTransport struct {
+	next    http.RoundTripper
+	service string
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	clientRequests.WithLabelValues(t.service, req.Method, code).Inc()
+	clientDuration.WithLabelValues(t.service, req.Method, code).Observe(time.Since(start).Seconds())
+	return resp, err
+}
+
+var (
+	clientRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "client_requests_total",
+		Help:      "Outbound HTTP requests by remote service, method and status code.",
+	}, []string{"service", "method", "code"})
+
+	clientDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "client_request_duration_seconds",
+		Help:      "Latency of outbound HTTP requests by remote service, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"service", "method", "code"})
+)
+
+func init() {
+	Registry.MustRegister(clientRequests, clientDuration, clientCircuitOpen)
+}
+
+var clientCircuitOpen = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "client_circuit_open",
+	Help:      "1 while the circuit breaker of a remote service is open, 0 otherwise.",
+}, []string{"service"})
+
+// SetCircuitOpen reports the state of a remote service's circuit breaker.
+func SetCircuitOpen(service string, open bool) {
+	v := 0.0
+	if open {
+		v = 1
+	}
+	clientCircuitOpen.WithLabelValues(service).Set(v)
+}
@@ -0,0 +1,108 @@
+package optimistic
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrConflict is returned when a record was modified by someone else between
+// being read and being written back.
+var ErrConflict = errors.New("record was modified concurrently, reload it and try again")
+
+// Update writes every column of model, which must have its primary key set, but
+// only if the stored row still carries *version. On success *version is
+// incremented; if another writer got there first nothing is written and
+// ErrConflict is returned. Associations are never saved.
+//
+// When the context names model as the record the client expects to change (see
+// Expect), the client's version is required instead of *version, so that a
+// change made between the client's read and the service's read is not lost.
+func Update(db *gorm.DB, model interface{}, version *uint) error {
+	current := *version
+	e := expectationFor(db, model)
+	if e != nil {
+		current = e.version
+	}
+	*version = current + 1
+
+	res := db.Model(model).
+		Where("version = ?", current).
+		Select("*").
+		Omit(clause.Associations).
+		Updates(model)
+	if res.Error != nil {
+		*version = current
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		*version = current
+		return ErrConflict
+	}
+	if e != nil {
+		// Later writes of the record in the same request build on this one.
+		e.version = *version
+	}
+	return nil
+}
+
+// Bump is the column assignment that increments a record's version in bulk
+// updates, e.g. Updates(map[string]interface{}{"dossier_id": nil, "version": optimistic.Bump}).
+var Bump = gorm.Expr("version + 1")
+
+// expectation is the record a request was checked against and its version.
+type expectation struct {
+	record  interface{}
+	version uint
+}
+
+type expectationKey struct{}
+
+// Expect returns a context noting that the client last saw record at version,
+// typically the version named by an If-Match header. record is the model as
+// loaded, used only to tell its table and primary key.
+func Expect(ctx context.Context, record interface{}, version uint) context.Context {
+	return context.WithValue(ctx, expectationKey{}, &expectation{record: record, version: version})
+}
+
+// expectationFor returns the expectation for model carried by db's context, or
+// nil if there is none or it is about another record.
+func expectationFor(db *gorm.DB, model interface{}) *expectation {
+	ctx := db.Statement.Context
+	if ctx == nil {
+		return nil
+	}
+	e, ok := ctx.Value(expectationKey{}).(*expectation)
+	if !ok {
+		return nil
+	}
+	table, id, ok := identify(db, model)
+	if !ok {
+		return nil
+	}
+	eTable, eID, ok := identify(db, e.record)
+	if !ok || table != eTable || id != eID {
+		return nil
+	}
+	return e
+}
+
+// identify returns the table and primary key of a model.
+func identify(db *gorm.DB, model interface{}) (string, interface{}, bool) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil || stmt.Schema.PrioritizedPrimaryField == nil {
+		return "", nil, false
+	}
+	v := reflect.Indirect(reflect.ValueOf(model))
+	if v.Kind() != reflect.Struct {
+		return "", nil, false
+	}
+	id, zero := stmt.Schema.PrioritizedPrimaryField.ValueOf(db.Statement.Context, v)
+	if zero {
+		return "", nil, false
+	}
+	return stmt.Schema.Table, id, true
+}
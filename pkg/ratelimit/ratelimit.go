@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/api"
+	"github.com/genryusaishigikuni/spy_cats/pkg/auth"
+	"github.com/genryusaishigikuni/spy_cats/pkg/logging"
+)
+
+// Budget is a token bucket: it holds up to Burst requests and refills at
+// PerMinute requests a minute.
+type Budget struct {
+	PerMinute int
+	Burst     int
+}
+
+// rate is the refill rate in tokens per second.
+func (b Budget) rate() float64 {
+	return float64(b.PerMinute) / 60
+}
+
+// RefillTime returns how long the slowest of the budgets takes to refill from empty.
+func RefillTime(budgets ...Budget) time.Duration {
+	var longest time.Duration
+	for _, b := range budgets {
+		if d := time.Duration(float64(b.Burst) / b.rate() * float64(time.Second)); d > longest {
+			longest = d
+		}
+	}
+	return longest
+}
+
+// Store keeps the token buckets.
+type Store interface {
+	// Take removes a token from the bucket under key and returns the tokens
+	// left. If the bucket is empty, nothing is taken and ok is false.
+	Take(ctx context.Context, key string, b Budget) (remaining float64, ok bool, err error)
+}
+
+// ParseBudgets parses a comma-separated list of "METHOD /route=per_minute:burst"
+// entries, such as "POST /missions=30:10". Routes are Gin route templates
+// without the version prefix.
+func ParseBudgets(spec string) (map[string]Budget, error) {
+	budgets := make(map[string]Budget)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		route, limits, ok := strings.Cut(entry, "=")
+		method, path, hasPath := strings.Cut(strings.TrimSpace(route), " ")
+		perMinute, burst, hasBurst := strings.Cut(limits, ":")
+		if !ok || !hasPath || !hasBurst || !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("invalid rate limit entry %q, expected METHOD /route=per_minute:burst", entry)
+		}
+		b := Budget{}
+		var errPM, errB error
+		b.PerMinute, errPM = strconv.Atoi(strings.TrimSpace(perMinute))
+		b.Burst, errB = strconv.Atoi(strings.TrimSpace(burst))
+		if errPM != nil || errB != nil || b.PerMinute < 1 || b.Burst < 1 {
+			return nil, fmt.Errorf("invalid rate limit entry %q, per_minute and burst must be positive whole numbers", entry)
+		}
+		budgets[strings.ToUpper(method)+" "+strings.TrimSpace(path)] = b
+	}
+	return budgets, nil
+}
+
+// Middleware limits each client, the authenticated principal or else the
+// client IP, to def across the routes without a budget of their own; routes
+// listed in routes get a separate bucket each, shared by all API versions. Every limited response carries
+// X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset (seconds
+// until the bucket is full); rejected requests get 429 with Retry-After.
+// The exempt paths, such as health probes, are not limited. If the store
+// fails, requests are let through. It must run after auth.Middleware.
+func Middleware(store Store, def Budget, routes map[string]Budget, exempt ...string) gin.HandlerFunc {
+	skip := make(map[string]bool, len(exempt))
+	for _, path := range exempt {
+		skip[path] = true
+	}
+	return func(c *gin.Context) {
+		if skip[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		client := "ip:" + c.ClientIP()
+		if p, ok := auth.FromContext(c); ok {
+			client = "principal:" + p.Name
+		}
+		budget, scope := def, "default"
+		if route := c.Request.Method + " " + api.Unversioned(c.FullPath()); c.FullPath() != "" {
+			if b, ok := routes[route]; ok {
+				budget, scope = b, route
+			}
+		}
+
+		ctx := c.Request.Context()
+		remaining, ok, err := store.Take(ctx, scope+"|"+client, budget)
+		if err != nil {
+			logging.FromContext(ctx).ErrorContext(ctx, "rate limit store failed, request let through", "error", err)
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(budget.Burst))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(int(math.Floor(remaining))))
+		c.Header("X-RateLimit-Reset", strconv.Itoa(seconds((float64(budget.Burst)-remaining)/budget.rate())))
+		if !ok {
+			c.Header("Retry-After", strconv.Itoa(seconds((1-remaining)/budget.rate())))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// seconds rounds s up to whole seconds.
+func seconds(s float64) int {
+	return int(math.Ceil(s))
+}
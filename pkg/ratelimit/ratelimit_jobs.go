@@ -0,0 +1,32 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/logging"
+	"github.com/genryusaishigikuni/spy_cats/pkg/scheduler"
+)
+
+// cleanupLockKey is the advisory lock key for the idle bucket cleanup job.
+const cleanupLockKey int64 = 0x5ca7_0004
+
+// NewCleanupJob returns a scheduler job that deletes buckets untouched for
+// longer than idle. It should be at least the time the largest budget takes to
+// refill, after which a deleted bucket and a new one are the same.
+func NewCleanupJob(repo Repository, idle time.Duration) scheduler.Job {
+	return scheduler.Job{
+		Name:    "rate-limit-cleanup",
+		LockKey: cleanupLockKey,
+		Run: func(ctx context.Context) error {
+			deleted, err := repo.DeleteIdle(ctx, idle)
+			if err != nil {
+				return err
+			}
+			if deleted > 0 {
+				logging.FromContext(ctx).InfoContext(ctx, "deleted idle rate limit buckets", "count", deleted)
+			}
+			return nil
+		},
+	}
+}
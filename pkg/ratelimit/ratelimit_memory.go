@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/clock"
+)
+
+// pruneInterval is how often the memory store drops full buckets.
+const pruneInterval = time.Minute
+
+// memoryStore keeps the buckets of a single instance.
+type memoryStore struct {
+	clk clock.Clock
+
+	mu         sync.Mutex
+	buckets    map[string]*bucket
+	lastPruned time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time // when the bucket will be full again
+}
+
+// NewMemoryStore creates a Store local to this process. With several
+// replicas, each enforces the budgets on its own.
+func NewMemoryStore(clk clock.Clock) Store {
+	return &memoryStore{clk: clk, buckets: make(map[string]*bucket)}
+}
+
+func (s *memoryStore) Take(ctx context.Context, key string, b Budget) (float64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clk.Now()
+	s.prune(now)
+
+	bk, found := s.buckets[key]
+	if !found {
+		bk = &bucket{tokens: float64(b.Burst), updated: now}
+		s.buckets[key] = bk
+	}
+	bk.tokens = math.Min(float64(b.Burst), bk.tokens+now.Sub(bk.updated).Seconds()*b.rate())
+	bk.updated = now
+
+	ok := bk.tokens >= 1
+	if ok {
+		bk.tokens--
+	}
+	bk.full = now.Add(time.Duration((float64(b.Burst) - bk.tokens) / b.rate() * float64(time.Second)))
+	return bk.tokens, ok, nil
+}
+
+// prune drops the buckets that have refilled, since a new bucket starts full
+// anyway. It does the work at most once per pruneInterval.
+func (s *memoryStore) prune(now time.Time) {
+	if now.Sub(s.lastPruned) < pruneInterval {
+		return
+	}
+	s.lastPruned = now
+	for key, bk := range s.buckets {
+		if !now.Before(bk.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
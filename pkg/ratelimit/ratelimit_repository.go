@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
+)
+
+// Bucket is a token bucket shared by every replica. Refills are computed
+// with the database clock, so replicas need not agree on the time.
+type Bucket struct {
+	Key       string    `gorm:"primaryKey"`
+	Tokens    float64   `gorm:"type:double precision"`
+	Allowed   bool      // whether the last request was let through
+	UpdatedAt time.Time `gorm:"index"`
+}
+
+// TableName keeps the table name readable.
+func (Bucket) TableName() string {
+	return "rate_limit_buckets"
+}
+
+// Repository is a Store backed by Postgres, for deployments with several replicas.
+type Repository interface {
+	Store
+	// DeleteIdle deletes the buckets untouched for longer than idle.
+	DeleteIdle(ctx context.Context, idle time.Duration) (int64, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new rate limit bucket repository with the given GORM DB instance.
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// takeSQL refills the bucket and takes a token in a single statement, so that
+// concurrent requests cannot both take the last token. The SET expressions
+// all see the row as it was before the update.
+const takeSQL = `
+INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at)
+VALUES (@key, @burst - 1, true, now())
+ON CONFLICT (key) DO UPDATE SET
+	tokens = LEAST(@burst, b.tokens + EXTRACT(EPOCH FROM now() - b.updated_at) * @rate)
+		- CASE WHEN LEAST(@burst, b.tokens + EXTRACT(EPOCH FROM now() - b.updated_at) * @rate) >= 1 THEN 1 ELSE 0 END,
+	allowed = LEAST(@burst, b.tokens + EXTRACT(EPOCH FROM now() - b.updated_at) * @rate) >= 1,
+	updated_at = now()
+RETURNING tokens, allowed`
+
+func (r *repository) Take(ctx context.Context, key string, b Budget) (float64, bool, error) {
+	ctx, span := tracing.Start(ctx, "ratelimit.Repository.Take")
+	defer span.End()
+
+	var row Bucket
+	err := r.db.WithContext(ctx).Raw(takeSQL, map[string]interface{}{
+		"key":   key,
+		"burst": float64(b.Burst),
+		"rate":  b.rate(),
+	}).Scan(&row).Error
+	if err != nil {
+		return 0, false, err
+	}
+	return row.Tokens, row.Allowed, nil
+}
+
+func (r *repository) DeleteIdle(ctx context.Context, idle time.Duration) (int64, error) {
+	ctx, span := tracing.Start(ctx, "ratelimit.Repository.DeleteIdle")
+	defer span.End()
+
+	res := r.db.WithContext(ctx).
+		Where("updated_at < now() - make_interval(secs => ?)", idle.Seconds()).
+		Delete(&Bucket{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,149 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/clock"
+)
+
+func TestMemoryStoreRefills(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore(clock.Func(func() time.Time { return now }))
+	ctx := context.Background()
+	b := Budget{PerMinute: 60, Burst: 2} // one token a second
+
+	for i, want := range []struct {
+		remaining float64
+		ok        bool
+	}{{1, true}, {0, true}, {0, false}} {
+		remaining, ok, err := store.Take(ctx, "k", b)
+		if err != nil || remaining != want.remaining || ok != want.ok {
+			t.Fatalf("take %d = %v, %v, %v, want %v, %v", i+1, remaining, ok, err, want.remaining, want.ok)
+		}
+	}
+
+	if _, ok, _ := store.Take(ctx, "other", b); !ok {
+		t.Error("another key shares the empty bucket")
+	}
+
+	now = now.Add(1500 * time.Millisecond)
+	if remaining, ok, _ := store.Take(ctx, "k", b); !ok || remaining != 0.5 {
+		t.Errorf("after 1.5s = %v, %v, want 0.5 left and let through", remaining, ok)
+	}
+
+	// A bucket never holds more than Burst, however long it was idle.
+	now = now.Add(time.Hour)
+	if remaining, _, _ := store.Take(ctx, "k", b); remaining != 1 {
+		t.Errorf("after an hour = %v left, want 1", remaining)
+	}
+}
+
+func TestMemoryStorePrunesFullBuckets(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	s := NewMemoryStore(clock.Func(func() time.Time { return now })).(*memoryStore)
+	ctx := context.Background()
+	_, _, _ = s.Take(ctx, "idle", Budget{PerMinute: 60, Burst: 5})
+
+	now = now.Add(pruneInterval)
+	_, _, _ = s.Take(ctx, "busy", Budget{PerMinute: 60, Burst: 5})
+	if _, ok := s.buckets["idle"]; ok {
+		t.Error("the refilled bucket was kept")
+	}
+	if _, ok := s.buckets["busy"]; !ok {
+		t.Error("the bucket just used was dropped")
+	}
+}
+
+func TestParseBudgets(t *testing.T) {
+	budgets, err := ParseBudgets(" post /missions=30:10, GET /cats/:id=120:20 ,")
+	if err != nil {
+		t.Fatalf("ParseBudgets: %v", err)
+	}
+	if len(budgets) != 2 || budgets["POST /missions"] != (Budget{30, 10}) || budgets["GET /cats/:id"] != (Budget{120, 20}) {
+		t.Errorf("budgets = %v", budgets)
+	}
+
+	for _, spec := range []string{"POST /missions", "POST missions=1:1", "/missions=1:1", "POST /missions=10", "POST /missions=0:1", "POST /missions=a:1"} {
+		if _, err := ParseBudgets(spec); err == nil {
+			t.Errorf("ParseBudgets(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestRefillTime(t *testing.T) {
+	if got := RefillTime(Budget{PerMinute: 60, Burst: 10}, Budget{PerMinute: 30, Burst: 10}); got != 20*time.Second {
+		t.Errorf("RefillTime = %v, want 20s", got)
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Budget) (float64, bool, error) {
+	return 0, false, errors.New("database is down")
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	r := gin.New()
+	r.Use(Middleware(NewMemoryStore(clock.Fixed(now)),
+		Budget{PerMinute: 60, Burst: 1},
+		map[string]Budget{"POST /missions": {PerMinute: 60, Burst: 2}},
+		"/healthz"))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/healthz", ok)
+	r.GET("/v1/cats", ok)
+	r.POST("/v1/missions", ok)
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	w := do(http.MethodGet, "/v1/cats")
+	if w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "1" ||
+		w.Header().Get("X-RateLimit-Remaining") != "0" || w.Header().Get("X-RateLimit-Reset") != "1" {
+		t.Errorf("first GET = %d with %v", w.Code, w.Header())
+	}
+	w = do(http.MethodGet, "/v1/cats")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("second GET = %d, Retry-After %q, want 429 after 1s", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// The route's own budget is separate from the exhausted default one.
+	for i := 0; i < 2; i++ {
+		if w := do(http.MethodPost, "/v1/missions"); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "2" {
+			t.Errorf("POST %d = %d, limit %q", i+1, w.Code, w.Header().Get("X-RateLimit-Limit"))
+		}
+	}
+	if w := do(http.MethodPost, "/v1/missions"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("third POST = %d, want 429", w.Code)
+	}
+
+	for i := 0; i < 3; i++ {
+		if w := do(http.MethodGet, "/healthz"); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "" {
+			t.Errorf("exempt GET = %d, limit %q", w.Code, w.Header().Get("X-RateLimit-Limit"))
+		}
+	}
+}
+
+func TestMiddlewareLetsRequestsThroughWhenStoreFails(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware(failingStore{}, Budget{PerMinute: 1, Burst: 1}, nil))
+	r.GET("/cats", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/cats", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", w.Code)
+	}
+}
@@ -1,43 +1,203 @@
 package router
 
 import (
+	"context"
+
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"gorm.io/gorm"
 
+	"github.com/genryusaishigikuni/spy_cats/config"
+	"github.com/genryusaishigikuni/spy_cats/internal/budget"
 	"github.com/genryusaishigikuni/spy_cats/internal/cat"
+	"github.com/genryusaishigikuni/spy_cats/internal/dossier"
+	"github.com/genryusaishigikuni/spy_cats/internal/graph"
+	"github.com/genryusaishigikuni/spy_cats/internal/history"
+	"github.com/genryusaishigikuni/spy_cats/internal/kpi"
 	"github.com/genryusaishigikuni/spy_cats/internal/mission"
 	"github.com/genryusaishigikuni/spy_cats/internal/note"
+	"github.com/genryusaishigikuni/spy_cats/internal/payroll"
+	"github.com/genryusaishigikuni/spy_cats/internal/skill"
 	"github.com/genryusaishigikuni/spy_cats/internal/target"
+	"github.com/genryusaishigikuni/spy_cats/internal/transfer"
+	"github.com/genryusaishigikuni/spy_cats/pkg/api"
+	"github.com/genryusaishigikuni/spy_cats/pkg/auth"
+	"github.com/genryusaishigikuni/spy_cats/pkg/bodylimit"
+	"github.com/genryusaishigikuni/spy_cats/pkg/clock"
+	"github.com/genryusaishigikuni/spy_cats/pkg/database"
+	"github.com/genryusaishigikuni/spy_cats/pkg/etag"
+	"github.com/genryusaishigikuni/spy_cats/pkg/health"
+	"github.com/genryusaishigikuni/spy_cats/pkg/idempotency"
+	"github.com/genryusaishigikuni/spy_cats/pkg/logging"
+	"github.com/genryusaishigikuni/spy_cats/pkg/metrics"
+	"github.com/genryusaishigikuni/spy_cats/pkg/ratelimit"
+	"github.com/genryusaishigikuni/spy_cats/pkg/rules"
+	"github.com/genryusaishigikuni/spy_cats/pkg/scheduler"
+	"github.com/genryusaishigikuni/spy_cats/pkg/thecatapi"
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
 )
 
-func SetupRouter(db *gorm.DB) *gin.Engine {
-	r := gin.Default()
+func SetupRouter(db *gorm.DB, cfg *config.Config, sched *scheduler.Scheduler) (*gin.Engine, error) {
+	r := gin.New()
+
+	// A server span per request; handlers pass its context down to services and repositories
+	r.Use(otelgin.Middleware(tracing.ServiceName))
+
+	// Request IDs, a logger per request and one structured access line per request
+	r.Use(logging.Middleware(), logging.Recovery())
+
+	// Request counts and latencies by route template, recorded first so they see the final status
+	r.Use(metrics.Middleware())
+
+	// Request bodies are capped; bulk imports get a larger limit
+	r.Use(bodylimit.Middleware(int64(cfg.HTTP.MaxBodyBytes), map[string]int64{
+		"POST /import/cats": int64(cfg.HTTP.MaxImportBytes),
+	}))
+
+	// 0) Authentication: resolve bearer tokens to principals
+	tokens, err := auth.ParseTokens(cfg.AuthTokens)
+	if err != nil {
+		return nil, err
+	}
+	r.Use(auth.Middleware(tokens), logging.Principal())
+
+	// Rate limits per principal, or per IP for anonymous clients; probes and scrapes are exempt
+	defaultBudget := ratelimit.Budget{PerMinute: cfg.RateLimit.PerMinute, Burst: cfg.RateLimit.Burst}
+	routeBudgets, err := ratelimit.ParseBudgets(cfg.RateLimit.Routes)
+	if err != nil {
+		return nil, err
+	}
+	var rateLimitRepo ratelimit.Repository
+	switch cfg.RateLimit.Store {
+	case "memory":
+		r.Use(ratelimit.Middleware(ratelimit.NewMemoryStore(clock.System()), defaultBudget, routeBudgets, "/healthz", "/readyz", "/metrics"))
+	case "postgres":
+		rateLimitRepo = ratelimit.NewRepository(db)
+		r.Use(ratelimit.Middleware(rateLimitRepo, defaultBudget, routeBudgets, "/healthz", "/readyz", "/metrics"))
+	}
+
+	// Conditional GETs: ETag on every response, 304 for a matching If-None-Match
+	r.Use(etag.Middleware())
+
+	// Safe retries: POSTs with an Idempotency-Key replay their first response
+	idempotencyRepo := idempotency.NewRepository(db)
+	r.Use(idempotency.Middleware(idempotencyRepo, cfg.Idempotency.TTL, clock.System()))
+
+	// Business rules, shared by the services and served to clients
+	agencyRules, err := rules.Load(cfg.Rules.File, cfg.Rules.Agency)
+	if err != nil {
+		return nil, err
+	}
 
 	// 1) Repositories
-	catRepo := cat.NewRepository(db)
+	// A cat and its salary history are written in one transaction, so the cat
+	// repository needs a payroll service bound to it
+	salariesOn := func(tx *gorm.DB) cat.SalaryRecorder {
+		return payroll.NewService(
+			payroll.NewRepository(tx), cat.NewRepository(tx, nil), mission.NewRepository(tx),
+			clock.System(), cfg.Payroll.MissionBonus,
+		)
+	}
+	catRepo := cat.NewRepository(db, salariesOn)
 	missionRepo := mission.NewRepository(db)
 	targetRepo := target.NewRepository(db)
 	noteRepo := note.NewRepository(db)
+	dossierRepo := dossier.NewRepository(db)
+	historyRepo := history.NewRepository(db)
+	skillRepo := skill.NewRepository(db)
+	payrollRepo := payroll.NewRepository(db)
+	budgetRepo := budget.NewRepository(db)
 
 	// 2) Services
-	catService := cat.NewService(catRepo)
+	breedCatalog := cat.NewBreedCatalog(thecatapi.New(cfg.CatAPI), clock.System(), cfg.CatAPI.BreedCacheTTL, cfg.CatAPI.StaleBreedsMaxAge)
+	payrollService := payroll.NewService(payrollRepo, catRepo, missionRepo, clock.System(), cfg.Payroll.MissionBonus)
+	catService := cat.NewService(catRepo, breedCatalog, clock.System())
+	dossierService := dossier.NewService(dossierRepo, targetRepo)
+	skillService := skill.NewService(skillRepo, catRepo, clock.System())
 	// Pass *all* required repos to mission.NewService
-	missionService := mission.NewService(missionRepo, catRepo, targetRepo)
-	targetService := target.NewService(targetRepo)
-	// Pass the note repo + target repo to note.NewService
-	noteService := note.NewService(noteRepo, targetRepo)
+	missionService := mission.NewService(
+		missionRepo, catRepo, targetRepo, historyRepo, dossierService, skillService, breedCatalog,
+		clock.System(), mission.NewLogNotifier(), cfg.Scheduler.OverdueEscalation, agencyRules,
+	)
+	targetService := target.NewService(targetRepo, agencyRules)
+	// Pass the note repo + target and mission repos to note.NewService
+	noteService := note.NewService(noteRepo, targetRepo, missionRepo, agencyRules, cfg.Notes.MaxLength)
+	historyService := history.NewService(historyRepo)
+	budgetService := budget.NewService(budgetRepo, missionRepo, targetRepo, catRepo, agencyRules)
+	// Imports create cats inside their own transaction, so they need a cat service bound to it
+	transferRepo := transfer.NewRepository(db, func(tx *gorm.DB) cat.Service {
+		return cat.NewService(cat.NewRepository(tx, salariesOn), breedCatalog, clock.System())
+	})
+	transferService := transfer.NewService(transferRepo)
 
 	// 3) Handlers
 	catHandler := cat.NewHandler(catService)
 	missionHandler := mission.NewHandler(missionService)
 	targetHandler := target.NewHandler(targetService)
 	noteHandler := note.NewHandler(noteService)
+	dossierHandler := dossier.NewHandler(dossierService)
+	historyHandler := history.NewHandler(historyService)
+	skillHandler := skill.NewHandler(skillService)
+	payrollHandler := payroll.NewHandler(payrollService)
+	budgetHandler := budget.NewHandler(budgetService)
+	transferHandler := transfer.NewHandler(transferService)
+	graphHandler, err := graph.NewHandler(catService, missionService, targetService, noteService, graph.Limits{
+		MaxComplexity: cfg.GraphQL.MaxComplexity,
+		MaxDepth:      cfg.GraphQL.MaxDepth,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 4) Register routes under /v1 (and /v2 for changed resources), with unversioned aliases
+	api.Mount(r, cfg.API,
+		catHandler, missionHandler, targetHandler, noteHandler, dossierHandler, historyHandler,
+		skillHandler, payrollHandler, budgetHandler, transferHandler,
+		api.RoutesFunc(func(r gin.IRouter) {
+			r.GET("/config/rules", rules.Handler(agencyRules))
+		}),
+	)
+
+	// GraphQL evolves its schema in place instead of by version, so it is served at the root
+	graphHandler.RegisterRoutes(r)
+
+	// Liveness, readiness and dependency status
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	readiness := []health.Check{
+		{Name: "database", Timeout: cfg.Health.CheckTimeout, Probe: sqlDB.PingContext},
+		{Name: "migrations", Timeout: cfg.Health.CheckTimeout, Probe: func(ctx context.Context) error {
+			return database.MigrationsApplied(ctx, db)
+		}},
+		{Name: "breed_catalog", Timeout: cfg.CatAPI.Timeout, Probe: breedCatalog.Preload},
+	}
+	dependencies := []health.Check{
+		{Name: "thecatapi", Timeout: cfg.CatAPI.Timeout, Probe: breedCatalog.Ping},
+	}
+	health.NewHandler(sqlDB, clock.System(), readiness, dependencies).RegisterRoutes(r)
+
+	// Prometheus metrics: HTTP, queries, the connection pool, TheCatAPI and business KPIs
+	if err := metrics.RegisterDBStats(sqlDB); err != nil {
+		return nil, err
+	}
+	if err := metrics.Registry.Register(kpi.NewCollector(kpi.NewRepository(db), clock.System())); err != nil {
+		return nil, err
+	}
+	r.GET("/metrics", metrics.Handler())
 
-	// 4) Register routes
-	catHandler.RegisterRoutes(r)
-	missionHandler.RegisterRoutes(r)
-	targetHandler.RegisterRoutes(r)
-	noteHandler.RegisterRoutes(r)
+	// 5) Background jobs
+	sched.Add(mission.NewOverdueJob(missionService))
+	sched.Add(payroll.NewSalaryJob(payrollService))
+	sched.Add(idempotency.NewCleanupJob(idempotencyRepo, clock.System()))
+	if rateLimitRepo != nil {
+		budgets := []ratelimit.Budget{defaultBudget}
+		for _, b := range routeBudgets {
+			budgets = append(budgets, b)
+		}
+		sched.Add(ratelimit.NewCleanupJob(rateLimitRepo, ratelimit.RefillTime(budgets...)))
+	}
 
-	return r
+	return r, nil
 }
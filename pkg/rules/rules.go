@@ -0,0 +1,118 @@
+package rules
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// Rules are the business limits services enforce. They are served to clients by
+// Handler so that forms can validate the same way.
+type Rules struct {
+	MinTargetsPerMission        int  `yaml:"min_targets_per_mission" json:"min_targets_per_mission"`
+	MaxTargetsPerMission        int  `yaml:"max_targets_per_mission" json:"max_targets_per_mission"`
+	MaxConcurrentMissionsPerCat int  `yaml:"max_concurrent_missions_per_cat" json:"max_concurrent_missions_per_cat"`
+	FreezeNotesOnCompletion     bool `yaml:"freeze_notes_on_completion" json:"freeze_notes_on_completion"`
+	FreezeExpensesOnCompletion  bool `yaml:"freeze_expenses_on_completion" json:"freeze_expenses_on_completion"`
+}
+
+// Default returns the agency's standing rules: 1–3 targets per mission, one
+// ongoing mission per cat, notes frozen once their target or mission is done
+// and expense ledgers frozen once their mission is done.
+func Default() Rules {
+	return Rules{
+		MinTargetsPerMission:        1,
+		MaxTargetsPerMission:        3,
+		MaxConcurrentMissionsPerCat: 1,
+		FreezeNotesOnCompletion:     true,
+		FreezeExpensesOnCompletion:  true,
+	}
+}
+
+// file is the layout of a rules file. Agency sections only need to list the
+// rules they change.
+type file struct {
+	Defaults yaml.Node            `yaml:"defaults"`
+	Agencies map[string]yaml.Node `yaml:"agencies"`
+}
+
+// Load reads rules from a YAML (or JSON) file, starting from Default, applying
+// the file's defaults and then the named agency's overrides. An empty path
+// returns Default.
+func Load(path, agency string) (Rules, error) {
+	r := Default()
+	if path == "" {
+		if agency != "" {
+			return r, fmt.Errorf("agency %q given without a rules file", agency)
+		}
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return r, fmt.Errorf("read rules: %w", err)
+	}
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return r, fmt.Errorf("parse rules %s: %w", path, err)
+	}
+
+	if !f.Defaults.IsZero() {
+		if err := decodeStrict(&f.Defaults, &r); err != nil {
+			return r, fmt.Errorf("parse rules %s: defaults: %w", path, err)
+		}
+	}
+	if agency != "" {
+		overrides, ok := f.Agencies[agency]
+		if !ok {
+			return r, fmt.Errorf("rules %s have no section for agency %q", path, agency)
+		}
+		if err := decodeStrict(&overrides, &r); err != nil {
+			return r, fmt.Errorf("parse rules %s: agency %s: %w", path, agency, err)
+		}
+	}
+	return r, r.Validate()
+}
+
+// decodeStrict decodes a section onto r, rejecting keys that are not rules so
+// that a misspelt rule does not silently keep its default.
+func decodeStrict(section *yaml.Node, r *Rules) error {
+	data, err := yaml.Marshal(section)
+	if err != nil {
+		return err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	return dec.Decode(r)
+}
+
+// Validate reports every rule that is out of range.
+func (r Rules) Validate() error {
+	var problems []string
+	if r.MinTargetsPerMission < 1 {
+		problems = append(problems, "min_targets_per_mission must be at least 1")
+	}
+	if r.MaxTargetsPerMission < r.MinTargetsPerMission {
+		problems = append(problems, "max_targets_per_mission must not be below min_targets_per_mission")
+	}
+	if r.MaxConcurrentMissionsPerCat < 1 {
+		problems = append(problems, "max_concurrent_missions_per_cat must be at least 1")
+	}
+	if len(problems) > 0 {
+		return errors.New("invalid rules: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Handler serves the rules at GET /config/rules.
+func Handler(r Rules) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, r)
+	}
+}
@@ -0,0 +1,132 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/logging"
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
+)
+
+// Job is a unit of periodic background work.
+type Job struct {
+	Name string
+	// LockKey identifies the Postgres advisory lock guarding the job, so that
+	// only one replica runs it at a time.
+	LockKey int64
+	Run     func(ctx context.Context) error
+}
+
+// LastRun records when a job last started, so that replicas whose tickers are
+// offset from each other do not run it again within the same interval.
+type LastRun struct {
+	Job       string `gorm:"primaryKey"`
+	StartedAt time.Time
+}
+
+// TableName keeps the table name readable.
+func (LastRun) TableName() string {
+	return "scheduled_job_runs"
+}
+
+// Scheduler runs registered jobs on a fixed interval.
+type Scheduler struct {
+	db       *gorm.DB
+	interval time.Duration
+	jobs     []Job
+}
+
+// New creates a Scheduler that ticks every interval.
+func New(db *gorm.DB, interval time.Duration) *Scheduler {
+	return &Scheduler{db: db, interval: interval}
+}
+
+// Add registers a job. It must be called before Run.
+func (s *Scheduler) Add(job Job) {
+	s.jobs = append(s.jobs, job)
+}
+
+// Run executes all jobs on every tick until ctx is cancelled. Cancelling ctx
+// stops new jobs from starting but lets a running job finish, so Run may return
+// a little after ctx is done; callers wait for it before closing the database.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	// Jobs are not interrupted halfway by shutdown.
+	jobCtx := context.WithoutCancel(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, job := range s.jobs {
+				if ctx.Err() != nil {
+					return
+				}
+				// Jobs log through the context so their lines name the job.
+				logger := slog.Default().With("job", job.Name)
+				if err := s.runLocked(logging.WithLogger(jobCtx, logger), job); err != nil {
+					logger.Error("scheduled job failed", "error", err)
+				}
+			}
+		}
+	}
+}
+
+// claimSQL records that the job starts now unless it started less than
+// min_gap seconds ago, returning a row only when the claim succeeds. Times come
+// from the database so that replica clocks need not agree.
+const claimSQL = `
+INSERT INTO scheduled_job_runs AS r (job, started_at)
+VALUES (@job, now())
+ON CONFLICT (job) DO UPDATE SET started_at = now()
+	WHERE r.started_at <= now() - make_interval(secs => @min_gap)
+RETURNING job, started_at`
+
+// minGap is the shortest time allowed between two runs of a job. It is a
+// little under the interval so that the leader's own ticks, which drift by a
+// few milliseconds, are not mistaken for a second run.
+func (s *Scheduler) minGap() time.Duration {
+	return s.interval - s.interval/10
+}
+
+// runLocked runs job only if this process wins its advisory lock and the job
+// has not run within the interval on any replica. The lock is taken and
+// released on a single pinned connection since advisory locks are scoped to
+// the database session.
+func (s *Scheduler) runLocked(ctx context.Context, job Job) error {
+	ctx, span := tracing.Start(ctx, "job "+job.Name)
+	defer span.End()
+
+	return s.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		var acquired bool
+		if err := conn.Raw("SELECT pg_try_advisory_lock(?)", job.LockKey).Scan(&acquired).Error; err != nil {
+			return fmt.Errorf("failed to acquire advisory lock: %w", err)
+		}
+		if !acquired {
+			// Another replica is running the job.
+			return nil
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", job.LockKey)
+
+		var run LastRun
+		res := conn.Raw(claimSQL, map[string]interface{}{
+			"job":     job.Name,
+			"min_gap": s.minGap().Seconds(),
+		}).Scan(&run)
+		if res.Error != nil {
+			return fmt.Errorf("failed to record job run: %w", res.Error)
+		}
+		if res.RowsAffected == 0 {
+			// Another replica already ran the job this interval.
+			return nil
+		}
+
+		return job.Run(ctx)
+	})
+}
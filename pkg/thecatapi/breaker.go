@@ -0,0 +1,79 @@
+package thecatapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/genryusaishigikuni/spy_cats/pkg/logging"
+	"github.com/genryusaishigikuni/spy_cats/pkg/metrics"
+)
+
+// breaker is a circuit breaker. After threshold consecutive calls fail with
+// ErrUnavailable it opens, and calls fail at once for cooldown. Then a single
+// trial call is let through: it closes the circuit if it succeeds and reopens
+// it otherwise.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time // zero while closed
+	trial    bool      // a trial call is in flight
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	metrics.SetCircuitOpen(service, false)
+	return &breaker{threshold: threshold, cooldown: cooldown}
+}
+
+// allow returns an error wrapping ErrUnavailable if the call must not be made.
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.openedAt.IsZero() {
+		return nil
+	}
+	if wait := b.cooldown - time.Since(b.openedAt); wait > 0 || b.trial {
+		return fmt.Errorf("%w: circuit open after %d failed calls", ErrUnavailable, b.threshold)
+	}
+	b.trial = true
+	return nil
+}
+
+// record counts the outcome of an allowed call. Calls abandoned by the caller
+// and errors other than ErrUnavailable, such as a rejected API key, say
+// nothing about TheCatAPI's health; they only end a trial.
+func (b *breaker) record(ctx context.Context, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wasTrial := b.trial
+	b.trial = false
+	switch {
+	case err == nil:
+		if !b.openedAt.IsZero() {
+			logging.FromContext(ctx).InfoContext(ctx, "circuit closed", "service", service)
+			metrics.SetCircuitOpen(service, false)
+		}
+		b.failures = 0
+		b.openedAt = time.Time{}
+	case !errors.Is(err, ErrUnavailable) || ctx.Err() != nil:
+	default:
+		b.failures++
+		if wasTrial || (b.openedAt.IsZero() && b.failures >= b.threshold) {
+			if b.openedAt.IsZero() {
+				metrics.SetCircuitOpen(service, true)
+			}
+			b.openedAt = time.Now()
+			logging.FromContext(ctx).LogAttrs(ctx, slog.LevelWarn, "circuit open",
+				slog.String("service", service), slog.Int("failures", b.failures),
+				slog.Duration("cooldown", b.cooldown), slog.String("error", err.Error()))
+		}
+	}
+}
@@ -0,0 +1,174 @@
+// Package thecatapi is the client of TheCatAPI (https://thecatapi.com). Calls
+// send the API key, are retried with jittered backoff when the upstream fails
+// transiently, and go through a circuit breaker so that an outage fails fast
+// instead of holding up every request for the full timeout.
+package thecatapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/genryusaishigikuni/spy_cats/config"
+	"github.com/genryusaishigikuni/spy_cats/pkg/metrics"
+	"github.com/genryusaishigikuni/spy_cats/pkg/tracing"
+)
+
+// service labels the client's metrics and logs.
+const service = "thecatapi"
+
+// maxBackoff caps the delay between two attempts.
+const maxBackoff = 5 * time.Second
+
+// ErrUnavailable is returned when TheCatAPI cannot be reached, answers with a
+// server error or rate limits us after every retry, or when the circuit is open.
+var ErrUnavailable = errors.New("thecatapi is unavailable")
+
+// Breed describes a breed from TheCatAPI catalog, including the trait scores
+// (1 to 5) used to rank cats for missions.
+type Breed struct {
+	Name         string `json:"name"`
+	Temperament  string `json:"temperament"`
+	Intelligence int    `json:"intelligence"`
+	Adaptability int    `json:"adaptability"`
+	EnergyLevel  int    `json:"energy_level"`
+}
+
+// Client calls TheCatAPI. It is safe for concurrent use.
+type Client struct {
+	http       *http.Client
+	breedsURL  string
+	apiKey     string
+	maxRetries int
+	backoff    time.Duration
+	breaker    *breaker
+}
+
+// New creates a Client from the catapi settings.
+func New(cfg config.CatAPIConfig) *Client {
+	return &Client{
+		http:       metrics.Client(tracing.Client(&http.Client{Timeout: cfg.Timeout}), service),
+		breedsURL:  cfg.BreedsURL,
+		apiKey:     cfg.APIKey,
+		maxRetries: cfg.MaxRetries,
+		backoff:    cfg.RetryBackoff,
+		breaker:    newBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown),
+	}
+}
+
+// Breeds downloads the whole breed catalog.
+func (c *Client) Breeds(ctx context.Context) ([]Breed, error) {
+	ctx, span := tracing.Start(ctx, "thecatapi.Client.Breeds")
+	defer span.End()
+
+	var breeds []Breed
+	if err := c.get(ctx, c.breedsURL, nil, c.maxRetries, &breeds); err != nil {
+		return nil, err
+	}
+	return breeds, nil
+}
+
+// Ping asks for a single breed to check that TheCatAPI answers. It is not
+// retried, and fails at once while the circuit is open.
+func (c *Client) Ping(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "thecatapi.Client.Ping")
+	defer span.End()
+
+	var breeds []Breed
+	return c.get(ctx, c.breedsURL, url.Values{"limit": {"1"}}, 0, &breeds)
+}
+
+// get fetches rawURL with the query added and decodes the JSON response into
+// out, retrying transient failures up to retries times.
+func (c *Client) get(ctx context.Context, rawURL string, query url.Values, retries int, out interface{}) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("could not create request to thecatapi: %w", err)
+	}
+	if len(query) > 0 {
+		q := u.Query()
+		for k, v := range query {
+			q[k] = v
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	if err := c.breaker.allow(); err != nil {
+		return err
+	}
+	for attempt := 0; ; attempt++ {
+		err = c.do(ctx, u.String(), out)
+		if err == nil || !errors.Is(err, ErrUnavailable) || ctx.Err() != nil {
+			break
+		}
+		if attempt == retries {
+			break
+		}
+		if sleep(ctx, c.delay(attempt)) != nil {
+			break
+		}
+	}
+	c.breaker.record(ctx, err)
+	return err
+}
+
+// do makes one attempt. Failures worth retrying wrap ErrUnavailable.
+func (c *Client) do(ctx context.Context, u string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("could not create request to thecatapi: %w", err)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("x-api-key", c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError:
+		return fmt.Errorf("%w: responded with status %d", ErrUnavailable, resp.StatusCode)
+	default:
+		return fmt.Errorf("thecatapi responded with status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("could not decode thecatapi response: %w", err)
+	}
+	return nil
+}
+
+// delay returns the wait before the retry following attempt: a random
+// duration up to backoff doubled per attempt ("full jitter"), so that
+// instances retrying the same outage spread out.
+func (c *Client) delay(attempt int) time.Duration {
+	ceiling := c.backoff << attempt
+	if ceiling <= 0 || ceiling > maxBackoff {
+		ceiling = maxBackoff
+	}
+	return rand.N(ceiling) + 1
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}